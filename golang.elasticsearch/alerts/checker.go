@@ -0,0 +1,131 @@
+package alerts
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+)
+
+// queueSize bounds the movements waiting to be checked. Movements arriving
+// while it is full are dropped and counted rather than slowing down the
+// request: alerting must never hold back stock writes.
+const queueSize = 256
+
+// workers is how many movements are checked at once, so that one slow
+// notifier does not hold up the others.
+const workers = 4
+
+// checkTimeout bounds the check of one movement, notification included.
+const checkTimeout = 30 * time.Second
+
+var levelRank = map[string]int{
+	models.StockOK:       0,
+	models.StockLow:      1,
+	models.StockCritical: 2,
+}
+
+// Checker compares the stock level before and after each movement and raises
+// an alert when a product falls to a worse level.
+type Checker struct {
+	products repository.ProductRepository
+	notifier Notifier
+	queue    chan models.StockMovement
+	dropped  atomic.Int64
+}
+
+func NewChecker(products repository.ProductRepository, notifier Notifier) *Checker {
+	return &Checker{
+		products: products,
+		notifier: notifier,
+		queue:    make(chan models.StockMovement, queueSize),
+	}
+}
+
+// Watch wraps stock so every movement it posts is queued for checking.
+func (c *Checker) Watch(stock repository.StockRepository) repository.StockRepository {
+	return &watchedStock{StockRepository: stock, checker: c}
+}
+
+// Observe queues a posted movement without blocking.
+func (c *Checker) Observe(movement models.StockMovement) {
+	select {
+	case c.queue <- movement:
+	default:
+		n := c.dropped.Add(1)
+		log.Printf("Stock alert queue is full, skipping movement of product %s (%d skipped so far)", movement.ProductID, n)
+	}
+}
+
+// Dropped is how many movements were skipped because the queue was full.
+func (c *Checker) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// Run checks queued movements until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case movement := <-c.queue:
+					c.checkWithTimeout(ctx, movement)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *Checker) checkWithTimeout(ctx context.Context, movement models.StockMovement) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if err := c.check(ctx, movement); err != nil {
+		log.Printf("Error checking stock of product %s: %s", movement.ProductID, err)
+	}
+}
+
+func (c *Checker) check(ctx context.Context, movement models.StockMovement) error {
+	product, err := c.products.Get(ctx, movement.ProductID)
+	if err != nil {
+		return err
+	}
+
+	before := product.StockLevel(movement.Balance - movement.Delta())
+	after := product.StockLevel(movement.Balance)
+	if levelRank[after] <= levelRank[before] {
+		return nil
+	}
+
+	return c.notifier.Notify(ctx, Alert{
+		ProductID:      product.ID,
+		Descriptions:   product.Descriptions,
+		Level:          after,
+		Qty:            movement.Balance,
+		Alertstocks:    product.Alertstocks,
+		Criticalstocks: product.Criticalstocks,
+		At:             movement.CreatedAt,
+	})
+}
+
+type watchedStock struct {
+	repository.StockRepository
+	checker *Checker
+}
+
+func (w *watchedStock) Move(ctx context.Context, movement *models.StockMovement) error {
+	if err := w.StockRepository.Move(ctx, movement); err != nil {
+		return err
+	}
+	w.checker.Observe(*movement)
+	return nil
+}
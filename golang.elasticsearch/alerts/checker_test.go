@@ -0,0 +1,124 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+)
+
+// stuckNotifier blocks every alert until its context is done or it is
+// released.
+type stuckNotifier struct {
+	started chan Alert
+	release chan struct{}
+}
+
+func (n *stuckNotifier) Notify(ctx context.Context, alert Alert) error {
+	n.started <- alert
+	select {
+	case <-n.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func lowStockProduct(t *testing.T, products repository.ProductRepository) string {
+	t.Helper()
+	id, err := products.Create(context.Background(), &models.Product{Descriptions: "Widget", Alertstocks: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// issue is a movement taking a product from 10 down to 3, past its alert level.
+func issue(productID string) models.StockMovement {
+	return models.StockMovement{ProductID: productID, Type: models.MovementIssue, Quantity: 7, Balance: 3}
+}
+
+func TestObserveNeverBlocks(t *testing.T) {
+	products := repository.NewMemoryProductRepository()
+	notifier := &stuckNotifier{started: make(chan Alert, workers), release: make(chan struct{})}
+	checker := NewChecker(products, notifier)
+	id := lowStockProduct(t, products)
+
+	// Nothing drains the queue, as when every worker is stuck on a notifier
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < queueSize+3; i++ {
+			checker.Observe(issue(id))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Observe blocked on a full queue")
+	}
+	if got := checker.Dropped(); got != 3 {
+		t.Errorf("dropped %d movements, want 3", got)
+	}
+}
+
+func TestRunChecksConcurrently(t *testing.T) {
+	products := repository.NewMemoryProductRepository()
+	notifier := &stuckNotifier{started: make(chan Alert, workers), release: make(chan struct{})}
+	checker := NewChecker(products, notifier)
+	id := lowStockProduct(t, products)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		checker.Run(ctx)
+		close(stopped)
+	}()
+
+	for i := 0; i < workers; i++ {
+		checker.Observe(issue(id))
+	}
+	// Every worker gets an alert although none of them has finished
+	for i := 0; i < workers; i++ {
+		select {
+		case alert := <-notifier.started:
+			if alert.Level != models.StockLow || alert.ProductID != id {
+				t.Errorf("alert = %+v, want product %s low", alert, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d of %d alerts started", i, workers)
+		}
+	}
+	close(notifier.release)
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop with its context")
+	}
+}
+
+func TestCheckSkipsUnchangedLevel(t *testing.T) {
+	products := repository.NewMemoryProductRepository()
+	notifier := &stuckNotifier{started: make(chan Alert, 1), release: make(chan struct{})}
+	close(notifier.release)
+	checker := NewChecker(products, notifier)
+	id := lowStockProduct(t, products)
+
+	// Already low before the movement
+	movement := models.StockMovement{ProductID: id, Type: models.MovementIssue, Quantity: 1, Balance: 2}
+	if err := checker.check(context.Background(), movement); err != nil {
+		t.Fatal(err)
+	}
+	// Back up above the alert level
+	movement = models.StockMovement{ProductID: id, Type: models.MovementReceipt, Quantity: 10, Balance: 12}
+	if err := checker.check(context.Background(), movement); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.started) != 0 {
+		t.Errorf("%d alerts raised, want none", len(notifier.started))
+	}
+}
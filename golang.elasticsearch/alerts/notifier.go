@@ -0,0 +1,117 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.elasticsearch/mailer"
+)
+
+// Alert is raised when a stock movement takes a product down to a worse
+// stock level.
+type Alert struct {
+	ProductID      string    `json:"product_id"`
+	Descriptions   string    `json:"descriptions"`
+	Level          string    `json:"level"`
+	Qty            float64   `json:"qty"`
+	Alertstocks    float64   `json:"alertstocks"`
+	Criticalstocks float64   `json:"criticalstocks"`
+	At             time.Time `json:"at"`
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("%s (id %s) is %s on stock: %g left, alert level %g, critical level %g",
+		a.Descriptions, a.ProductID, a.Level, a.Qty, a.Alertstocks, a.Criticalstocks)
+}
+
+// Notifier delivers stock alerts.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alerts to the standard logger.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, alert Alert) error {
+	log.Printf("Stock alert: %s", alert)
+	return nil
+}
+
+type mailNotifier struct {
+	mail mailer.Mailer
+	to   []string
+}
+
+// NewMailNotifier emails every alert to each address in to.
+func NewMailNotifier(mail mailer.Mailer, to []string) Notifier {
+	return &mailNotifier{mail: mail, to: to}
+}
+
+func (n *mailNotifier) Notify(ctx context.Context, alert Alert) error {
+	msg := mailer.Message{
+		Subject: fmt.Sprintf("Stock alert: %s is %s", alert.Descriptions, alert.Level),
+		Body:    alert.String() + ".\n",
+	}
+
+	var errs []error
+	for _, to := range n.to {
+		msg.To = to
+		errs = append(errs, n.mail.Send(ctx, msg))
+	}
+	return errors.Join(errs...)
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier posts every alert as JSON to url.
+func NewWebhookNotifier(url string) Notifier {
+	return &webhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
+
+type multiNotifier []Notifier
+
+// Multi sends each alert through every notifier, even when one of them fails.
+func Multi(notifiers ...Notifier) Notifier {
+	return multiNotifier(notifiers)
+}
+
+func (m multiNotifier) Notify(ctx context.Context, alert Alert) error {
+	var errs []error
+	for _, n := range m {
+		errs = append(errs, n.Notify(ctx, alert))
+	}
+	return errors.Join(errs...)
+}
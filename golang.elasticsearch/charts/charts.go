@@ -0,0 +1,350 @@
+package charts
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	TypeBar     = "bar"
+	TypeLine    = "line"
+	TypePie     = "pie"
+	TypeStacked = "stacked"
+
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// The logo sits in a band of logoHeight pixels above the chart.
+const (
+	logoHeight = 60
+	logoGap    = 10
+)
+
+// labelWidth is the room, in pixels, given to each axis label. Labels that
+// would not fit are skipped.
+const labelWidth = 90
+
+// ErrNoData is returned when every value is zero, which go-chart cannot draw.
+var ErrNoData = errors.New("nothing to chart")
+
+// Series is one row of values, one per label of the chart Data.
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+// Data is what gets plotted. Bar, line and pie charts use the first series,
+// a stacked chart stacks every series on each label.
+type Data struct {
+	Labels []string
+	Series []Series
+}
+
+// Options choose how a chart is drawn. Zero values fall back to a bar chart
+// of 1280x512 pixels in PNG.
+type Options struct {
+	Type   string
+	Format string
+	Title  string
+	Width  int
+	Height int
+	// Logo puts the company logo above the chart.
+	Logo bool
+}
+
+// ContentType is the media type of a chart rendered in format.
+func ContentType(format string) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Service renders the sales charts. It is safe for concurrent use.
+type Service struct {
+	logo    image.Image
+	logoPNG []byte
+}
+
+// NewService loads the logo once. Charts are drawn without it when the file
+// cannot be read.
+func NewService(logoPath string) *Service {
+	s := &Service{}
+	data, err := os.ReadFile(logoPath)
+	if err == nil {
+		s.logo, err = png.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		log.Printf("Chart logo %s not loaded, charts are drawn without it: %s", logoPath, err)
+		return s
+	}
+	s.logoPNG = data
+	return s
+}
+
+type renderable interface {
+	Render(rp chart.RendererProvider, w io.Writer) error
+}
+
+// Render draws data as described by opts and writes the image to w.
+func (s *Service) Render(w io.Writer, data Data, opts Options) error {
+	if opts.Width == 0 {
+		opts.Width = 1280
+	}
+	if opts.Height == 0 {
+		opts.Height = 512
+	}
+
+	if empty(data) {
+		return ErrNoData
+	}
+
+	var graph renderable
+	switch opts.Type {
+	case TypeLine:
+		graph = lineChart(data, opts)
+	case TypePie:
+		graph = pieChart(data, opts)
+	case TypeStacked:
+		graph = stackedChart(data, opts)
+	default:
+		graph = barChart(data, opts)
+	}
+
+	provider := chart.PNG
+	if opts.Format == FormatSVG {
+		provider = chart.SVG
+	}
+	var buf bytes.Buffer
+	if err := graph.Render(provider, &buf); err != nil {
+		return err
+	}
+
+	var err error
+	switch {
+	case !opts.Logo || s.logo == nil:
+		_, err = buf.WriteTo(w)
+	case opts.Format == FormatSVG:
+		err = s.svgWithLogo(w, buf.String(), opts)
+	default:
+		err = s.pngWithLogo(w, &buf, opts)
+	}
+	return err
+}
+
+func empty(data Data) bool {
+	for _, series := range data.Series {
+		for _, v := range series.Values {
+			if v > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// labelStep is how many points go by between two labels on an axis of
+// the given width.
+func labelStep(points int, width int) int {
+	fit := max(width/labelWidth, 1)
+	return (points + fit - 1) / fit
+}
+
+func firstSeries(data Data) []float64 {
+	if len(data.Series) == 0 {
+		return nil
+	}
+	return data.Series[0].Values
+}
+
+func barChart(data Data, opts Options) chart.BarChart {
+	values := firstSeries(data)
+	step := labelStep(len(data.Labels), opts.Width)
+	bars := make([]chart.Value, len(data.Labels))
+	for i, label := range data.Labels {
+		if i%step != 0 {
+			label = ""
+		}
+		bars[i] = chart.Value{Label: label, Value: values[i]}
+	}
+
+	// Keep the bars readable however many intervals there are
+	width := opts.Width / (len(bars) + 1) * 2 / 3
+	return chart.BarChart{
+		Title: opts.Title,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40, Bottom: 20},
+		},
+		Width:    opts.Width,
+		Height:   opts.Height,
+		BarWidth: min(max(width, 4), 60),
+		Bars:     bars,
+		YAxis: chart.YAxis{
+			ValueFormatter: commaFormatter,
+		},
+	}
+}
+
+func lineChart(data Data, opts Options) chart.Chart {
+	values := firstSeries(data)
+	step := labelStep(len(data.Labels), opts.Width)
+	xs := make([]float64, len(data.Labels))
+	var ticks []chart.Tick
+	for i, label := range data.Labels {
+		xs[i] = float64(i)
+		if i%step == 0 {
+			ticks = append(ticks, chart.Tick{Value: float64(i), Label: label})
+		}
+	}
+
+	name := ""
+	if len(data.Series) > 0 {
+		name = data.Series[0].Name
+	}
+	return chart.Chart{
+		Title: opts.Title,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40, Left: 20, Right: 20, Bottom: 20},
+		},
+		Width:  opts.Width,
+		Height: opts.Height,
+		XAxis:  chart.XAxis{Ticks: ticks},
+		YAxis: chart.YAxis{
+			ValueFormatter: commaFormatter,
+		},
+		Series: []chart.Series{
+			chart.ContinuousSeries{
+				Name:    name,
+				XValues: xs,
+				YValues: values,
+				Style: chart.Style{
+					StrokeWidth: 3,
+					DotWidth:    4,
+				},
+			},
+		},
+	}
+}
+
+func pieChart(data Data, opts Options) chart.PieChart {
+	values := firstSeries(data)
+	var slices []chart.Value
+	for i, label := range data.Labels {
+		if values[i] > 0 {
+			slices = append(slices, chart.Value{
+				Label: fmt.Sprintf("%s: %s", label, humanize.Commaf(values[i])),
+				Value: values[i],
+			})
+		}
+	}
+	return chart.PieChart{
+		Title:  opts.Title,
+		Width:  opts.Width,
+		Height: opts.Height,
+		Values: slices,
+		SliceStyle: chart.Style{
+			FontColor:   drawing.ColorWhite,
+			FontSize:    10.0,
+			StrokeWidth: 2,
+		},
+	}
+}
+
+// stackedChart draws one bar per label, split by series. go-chart scales
+// every bar to full height, so the chart shows each series' share; empty
+// labels are left out and each series keeps its color across bars.
+func stackedChart(data Data, opts Options) chart.StackedBarChart {
+	palette := chart.DefaultColorPalette
+	var bars []chart.StackedBar
+	for i, label := range data.Labels {
+		var parts []chart.Value
+		for j, series := range data.Series {
+			if series.Values[i] <= 0 {
+				continue
+			}
+			c := palette.GetSeriesColor(j)
+			parts = append(parts, chart.Value{
+				Label: series.Name,
+				Value: series.Values[i],
+				Style: chart.Style{FillColor: c, StrokeColor: c, FontColor: drawing.ColorWhite},
+			})
+		}
+		if len(parts) > 0 {
+			bars = append(bars, chart.StackedBar{Name: label, Values: parts})
+		}
+	}
+
+	// Spread the bars over the width left of the percentage axis
+	slot := (opts.Width - 100) / max(len(bars), 1)
+	barWidth := min(max(slot*2/3, 4), 80)
+	for i := range bars {
+		bars[i].Width = barWidth
+	}
+	return chart.StackedBarChart{
+		Title: opts.Title,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40},
+		},
+		Width:      opts.Width,
+		Height:     opts.Height,
+		BarSpacing: max(slot-barWidth, 1),
+		Bars:       bars,
+	}
+}
+
+// pngWithLogo draws the logo, centered, above the rendered chart.
+func (s *Service) pngWithLogo(w io.Writer, chartPNG io.Reader, opts Options) error {
+	chartImg, err := png.Decode(chartPNG)
+	if err != nil {
+		return err
+	}
+
+	height := logoHeight + logoGap + opts.Height
+	canvas := image.NewRGBA(image.Rect(0, 0, opts.Width, height))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+
+	logoX := (opts.Width - s.logo.Bounds().Dx()) / 2
+	draw.Draw(canvas, image.Rect(logoX, 0, logoX+s.logo.Bounds().Dx(), logoHeight), s.logo, s.logo.Bounds().Min, draw.Over)
+	draw.Draw(canvas, image.Rect(0, logoHeight+logoGap, opts.Width, height), chartImg, image.Point{}, draw.Over)
+
+	return png.Encode(w, canvas)
+}
+
+// svgWithLogo nests the chart document in one that has the logo embedded
+// above it.
+func (s *Service) svgWithLogo(w io.Writer, chartSVG string, opts Options) error {
+	logoWidth := s.logo.Bounds().Dx() * logoHeight / max(s.logo.Bounds().Dy(), 1)
+	chartSVG = strings.Replace(chartSVG, "<svg ",
+		fmt.Sprintf(`<svg x="0" y="%d" width="%d" height="%d" `, logoHeight+logoGap, opts.Width, opts.Height), 1)
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`+
+		`<rect width="100%%" height="100%%" fill="white"/>`+
+		`<image x="%d" y="0" width="%d" height="%d" href="data:image/png;base64,%s"/>`+
+		`%s</svg>`,
+		opts.Width, logoHeight+logoGap+opts.Height,
+		(opts.Width-logoWidth)/2, logoWidth, logoHeight, base64.StdEncoding.EncodeToString(s.logoPNG),
+		chartSVG)
+	return err
+}
+
+func commaFormatter(v interface{}) string {
+	p := message.NewPrinter(language.English)
+	return p.Sprintf("%.2f", v)
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is every setting of the server. Load fills it from defaults, a
+// JSON file, the environment and command line flags, in that order.
+type Config struct {
+	Server        Server        `json:"server"`
+	Elasticsearch Elasticsearch `json:"elasticsearch"`
+	CORS          CORS          `json:"cors"`
+	Tokens        Tokens        `json:"tokens"`
+	Indices       Indices       `json:"indices"`
+	Uploads       Uploads       `json:"uploads"`
+	Mail          Mail          `json:"mail"`
+	Admin         Admin         `json:"admin"`
+	Alerts        Alerts        `json:"alerts"`
+}
+
+type Server struct {
+	// Addr is the host:port to listen on. It defaults to localhost only; an
+	// empty host, as in ":5000", listens on every interface.
+	Addr string `json:"addr"`
+	TLS  TLS    `json:"tls"`
+	// AppURL is the frontend address put in verification and reset links.
+	AppURL string `json:"app_url"`
+	// AssetsDir holds the static images, the product pictures placed by hand
+	// and the profile pictures uploaded before the blob store.
+	AssetsDir string `json:"assets_dir"`
+}
+
+// TLS serves HTTPS when both files are set.
+type TLS struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+type Elasticsearch struct {
+	Addresses []string `json:"addresses"`
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+	// CACertFile verifies the cluster certificate against a private CA.
+	CACertFile string `json:"ca_cert_file"`
+	// InsecureSkipVerify accepts any certificate, such as the self-signed
+	// one of a local cluster. It is ignored when CACertFile is set.
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+type CORS struct {
+	// AllowOrigins may call the API from a browser. "*" allows any origin.
+	// It defaults to the origin of Server.AppURL.
+	AllowOrigins []string `json:"allow_origins"`
+}
+
+type Tokens struct {
+	// KeysDir holds the JWT signing keys. Without it a throwaway key is
+	// generated, which logs everybody out on every restart.
+	KeysDir    string `json:"keys_dir"`
+	SigningKid string `json:"signing_kid"`
+	// CursorKey signs the pagination cursors, at least 32 bytes. Every
+	// instance behind a load balancer needs the same one; without it each
+	// generates its own.
+	CursorKey string `json:"cursor_key"`
+
+	AccessLifetime  Duration `json:"access_lifetime"`
+	RefreshLifetime Duration `json:"refresh_lifetime"`
+	// ChallengeLifetime is the time given to enter the OTP code on login.
+	ChallengeLifetime     Duration `json:"challenge_lifetime"`
+	VerifyEmailLifetime   Duration `json:"verify_email_lifetime"`
+	PasswordResetLifetime Duration `json:"password_reset_lifetime"`
+}
+
+// Indices names the Elasticsearch alias of each kind of document.
+type Indices struct {
+	Users          string `json:"users"`
+	Products       string `json:"products"`
+	Sales          string `json:"sales"`
+	StockMovements string `json:"stock_movements"`
+	RefreshTokens  string `json:"refresh_tokens"`
+	RevokedTokens  string `json:"revoked_tokens"`
+	LoginAttempts  string `json:"login_attempts"`
+}
+
+// Aliases maps the mapping directories under migrations/mappings to the
+// aliases they are applied to.
+func (i Indices) Aliases() map[string]string {
+	return map[string]string{
+		"users":           i.Users,
+		"products":        i.Products,
+		"sales":           i.Sales,
+		"stock_movements": i.StockMovements,
+		"refresh_tokens":  i.RefreshTokens,
+		"revoked_tokens":  i.RevokedTokens,
+		"login_attempts":  i.LoginAttempts,
+	}
+}
+
+// Uploads go to S3 when a bucket is set, and under Dir otherwise.
+type Uploads struct {
+	Dir string `json:"dir"`
+	S3  S3     `json:"s3"`
+}
+
+type S3 struct {
+	// Endpoint defaults to AWS in Region. Set it for a MinIO style server.
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+// Mail is sent through SMTPHost when it is set. Otherwise it is written to
+// Dir, or to the log, so signups can be tested locally.
+type Mail struct {
+	From         string `json:"from"`
+	Dir          string `json:"dir"`
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     string `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password"`
+}
+
+// Admin is the account created when nobody is an administrator. An empty
+// password is replaced by a random one that is logged once.
+type Admin struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// PromoteExisting grants the role to an existing account of that
+	// username instead of refusing to start.
+	PromoteExisting bool `json:"promote_existing"`
+}
+
+// Alerts are always logged, and also mailed and posted when these are set.
+type Alerts struct {
+	Emails  []string `json:"emails"`
+	Webhook string   `json:"webhook"`
+}
+
+// Default is the configuration of a development machine running the API,
+// Elasticsearch and the Vite dev server side by side.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:      "localhost:5000",
+			AppURL:    "http://localhost:5173",
+			AssetsDir: "./assets",
+		},
+		Elasticsearch: Elasticsearch{
+			Addresses:          []string{"https://localhost:9200"},
+			InsecureSkipVerify: true,
+		},
+		Tokens: Tokens{
+			AccessLifetime:        Duration(8 * time.Hour),
+			RefreshLifetime:       Duration(7 * 24 * time.Hour),
+			ChallengeLifetime:     Duration(5 * time.Minute),
+			VerifyEmailLifetime:   Duration(24 * time.Hour),
+			PasswordResetLifetime: Duration(30 * time.Minute),
+		},
+		Indices: Indices{
+			Users:          "users",
+			Products:       "products",
+			Sales:          "sales",
+			StockMovements: "stock_movements",
+			RefreshTokens:  "refresh_tokens",
+			RevokedTokens:  "revoked_tokens",
+			LoginAttempts:  "login_attempts",
+		},
+		Uploads: Uploads{
+			Dir: "./uploads",
+			S3:  S3{Region: "us-east-1"},
+		},
+		Mail: Mail{
+			From:     "noreply@localhost",
+			SMTPPort: "587",
+		},
+		Admin: Admin{
+			Username: "admin",
+			Email:    "admin@localhost",
+		},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		fail("server.addr %q is not a host:port address", c.Server.Addr)
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		fail("server.tls needs both cert_file and key_file")
+	}
+	if !isHTTPURL(c.Server.AppURL) {
+		fail("server.app_url %q is not an http(s) URL", c.Server.AppURL)
+	}
+	if c.Server.AssetsDir == "" {
+		fail("server.assets_dir is required")
+	}
+
+	if len(c.Elasticsearch.Addresses) == 0 {
+		fail("elasticsearch.addresses is required")
+	}
+	for _, addr := range c.Elasticsearch.Addresses {
+		if !isHTTPURL(addr) {
+			fail("elasticsearch address %q is not an http(s) URL", addr)
+		}
+	}
+
+	for _, origin := range c.CORS.AllowOrigins {
+		if origin == "*" {
+			if len(c.CORS.AllowOrigins) > 1 {
+				fail("cors.allow_origins cannot list origins next to \"*\"")
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || !isHTTPURL(origin) || strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" {
+			fail("cors origin %q is not a scheme://host[:port] origin", origin)
+		}
+	}
+
+	t := c.Tokens
+	lifetimes := []struct {
+		name string
+		d    Duration
+	}{
+		{"access_lifetime", t.AccessLifetime},
+		{"refresh_lifetime", t.RefreshLifetime},
+		{"challenge_lifetime", t.ChallengeLifetime},
+		{"verify_email_lifetime", t.VerifyEmailLifetime},
+		{"password_reset_lifetime", t.PasswordResetLifetime},
+	}
+	for _, l := range lifetimes {
+		if l.d <= 0 {
+			fail("tokens.%s must be positive", l.name)
+		}
+	}
+	if t.AccessLifetime > t.RefreshLifetime {
+		fail("tokens.access_lifetime cannot be longer than tokens.refresh_lifetime")
+	}
+	if t.CursorKey != "" && len(t.CursorKey) < 32 {
+		fail("tokens.cursor_key must be at least 32 bytes")
+	}
+
+	aliases := c.Indices.Aliases()
+	seen := map[string]string{}
+	for _, dir := range slices.Sorted(maps.Keys(aliases)) {
+		name := aliases[dir]
+		if err := checkIndexName(name); err != nil {
+			fail("indices.%s: %s", dir, err)
+		}
+		if other, ok := seen[name]; ok && name != "" {
+			fail("indices.%s and indices.%s are both %q", other, dir, name)
+		}
+		seen[name] = dir
+	}
+
+	if c.Uploads.S3.Bucket == "" {
+		if c.Uploads.Dir == "" {
+			fail("uploads.dir is required when no S3 bucket is set")
+		}
+	} else {
+		if c.Uploads.S3.AccessKey == "" || c.Uploads.S3.SecretKey == "" {
+			fail("uploads.s3 needs access_key and secret_key")
+		}
+		if c.Uploads.S3.Endpoint != "" && !isHTTPURL(c.Uploads.S3.Endpoint) {
+			fail("uploads.s3.endpoint %q is not an http(s) URL", c.Uploads.S3.Endpoint)
+		}
+	}
+
+	if c.Mail.SMTPHost != "" {
+		if port, err := strconv.Atoi(c.Mail.SMTPPort); err != nil || port < 1 || port > 65535 {
+			fail("mail.smtp_port %q is not a port number", c.Mail.SMTPPort)
+		}
+	}
+	if c.Admin.Username == "" {
+		fail("admin.username is required")
+	}
+	if c.Alerts.Webhook != "" && !isHTTPURL(c.Alerts.Webhook) {
+		fail("alerts.webhook %q is not an http(s) URL", c.Alerts.Webhook)
+	}
+
+	return errors.Join(errs...)
+}
+
+// applyDefaults fills the settings derived from others once all sources are
+// read.
+func (c *Config) applyDefaults() {
+	if len(c.CORS.AllowOrigins) == 0 {
+		if u, err := url.Parse(c.Server.AppURL); err == nil && u.Host != "" {
+			c.CORS.AllowOrigins = []string{u.Scheme + "://" + u.Host}
+		}
+	}
+	if c.Uploads.S3.Bucket != "" && c.Uploads.S3.Endpoint == "" {
+		c.Uploads.S3.Endpoint = "https://s3." + c.Uploads.S3.Region + ".amazonaws.com"
+	}
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// checkIndexName applies the Elasticsearch index naming rules.
+func checkIndexName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("name is required")
+	case name == "." || name == "..":
+		return fmt.Errorf("%q is not allowed", name)
+	case strings.ContainsAny(name[:1], "-_+"):
+		return fmt.Errorf("%q cannot start with -, _ or +", name)
+	case strings.ToLower(name) != name:
+		return fmt.Errorf("%q must be lowercase", name)
+	case strings.ContainsAny(name, `\/*?"<>| ,#:`):
+		return fmt.Errorf("%q contains a character Elasticsearch does not allow", name)
+	case len(name) > 255:
+		return fmt.Errorf("%q is longer than 255 bytes", name)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate runs Load away from the .env file and the environment of the
+// machine running the tests.
+func isolate(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	t.Setenv("CONFIG_FILE", "")
+	var c Config
+	for _, s := range c.settings() {
+		t.Setenv(s.env, "")
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		// fileEnv names the file in CONFIG_FILE instead of -config
+		fileEnv  bool
+		env      map[string]string
+		args     []string
+		addr     string
+		lifetime time.Duration
+		origins  []string
+	}{
+		{
+			name:     "defaults",
+			addr:     "localhost:5000",
+			lifetime: 8 * time.Hour,
+			origins:  []string{"http://localhost:5173"},
+		},
+		{
+			name:     "file over defaults",
+			file:     `{"server": {"addr": ":6000"}, "tokens": {"access_lifetime": "1h"}}`,
+			addr:     ":6000",
+			lifetime: time.Hour,
+			origins:  []string{"http://localhost:5173"},
+		},
+		{
+			name:     "env over file",
+			file:     `{"server": {"addr": ":6000"}, "tokens": {"access_lifetime": "1h"}}`,
+			env:      map[string]string{"SERVER_ADDR": ":7000", "JWT_ACCESS_LIFETIME": "2h"},
+			addr:     ":7000",
+			lifetime: 2 * time.Hour,
+			origins:  []string{"http://localhost:5173"},
+		},
+		{
+			name:     "flags over env",
+			file:     `{"server": {"addr": ":6000"}, "tokens": {"access_lifetime": "1h"}}`,
+			env:      map[string]string{"SERVER_ADDR": ":7000", "JWT_ACCESS_LIFETIME": "2h"},
+			args:     []string{"-addr", ":8000", "-access-lifetime", "3h"},
+			addr:     ":8000",
+			lifetime: 3 * time.Hour,
+			origins:  []string{"http://localhost:5173"},
+		},
+		{
+			name:     "file named in the environment",
+			fileEnv:  true,
+			file:     `{"server": {"app_url": "https://shop.example.com/app"}}`,
+			addr:     "localhost:5000",
+			lifetime: 8 * time.Hour,
+			origins:  []string{"https://shop.example.com"},
+		},
+		{
+			name:     "lists from the environment",
+			env:      map[string]string{"CORS_ORIGINS": " https://a.example.com, ,https://b.example.com "},
+			addr:     "localhost:5000",
+			lifetime: 8 * time.Hour,
+			origins:  []string{"https://a.example.com", "https://b.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			args := tt.args
+			if tt.file != "" {
+				name := writeFile(t, tt.file)
+				if tt.fileEnv {
+					t.Setenv("CONFIG_FILE", name)
+				} else {
+					args = append([]string{"-config", name}, args...)
+				}
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Addr != tt.addr {
+				t.Errorf("addr = %q, want %q", cfg.Server.Addr, tt.addr)
+			}
+			if got := time.Duration(cfg.Tokens.AccessLifetime); got != tt.lifetime {
+				t.Errorf("access lifetime = %s, want %s", got, tt.lifetime)
+			}
+			if !slices.Equal(cfg.CORS.AllowOrigins, tt.origins) {
+				t.Errorf("origins = %q, want %q", cfg.CORS.AllowOrigins, tt.origins)
+			}
+		})
+	}
+}
+
+func TestLoadKeepsUnsetSettings(t *testing.T) {
+	isolate(t)
+	// The file only overlays the settings it names
+	name := writeFile(t, `{"mail": {"smtp_host": "smtp.example.com"}}`)
+	cfg, err := Load([]string{"-config", name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mail.SMTPHost != "smtp.example.com" || cfg.Mail.SMTPPort != "587" || cfg.Mail.From != "noreply@localhost" {
+		t.Errorf("mail = %+v", cfg.Mail)
+	}
+	if cfg.Indices.Users != "users" || cfg.Tokens.RefreshLifetime != Duration(7*24*time.Hour) {
+		t.Errorf("defaults lost: %+v %+v", cfg.Indices, cfg.Tokens)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"30m", 30 * time.Minute, true},
+		{"8h", 8 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"7d", 7 * 24 * time.Hour, true},
+		{"0d", 0, true},
+		{"", 0, false},
+		{"8", 0, false},
+		{"1.5d", 0, false},
+		{"d", 0, false},
+		{"week", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseDuration(%q) error = %v", tt.in, err)
+			continue
+		}
+		if time.Duration(got) != tt.want {
+			t.Errorf("ParseDuration(%q) = %s, want %s", tt.in, time.Duration(got), tt.want)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want []string
+	}{
+		{
+			name: "duration in the environment",
+			env:  map[string]string{"JWT_ACCESS_LIFETIME": "soon"},
+			want: []string{"JWT_ACCESS_LIFETIME", `invalid duration "soon"`},
+		},
+		{
+			name: "duration flag",
+			args: []string{"-refresh-lifetime", "2x"},
+			want: []string{"-refresh-lifetime", `invalid duration "2x"`},
+		},
+		{
+			name: "duration as a number in the file",
+			file: `{"tokens": {"access_lifetime": 3600}}`,
+			want: []string{"duration must be text"},
+		},
+		{
+			name: "boolean",
+			env:  map[string]string{"ES_INSECURE_SKIP_VERIFY": "maybe"},
+			want: []string{"ES_INSECURE_SKIP_VERIFY", `invalid boolean "maybe"`},
+		},
+		{
+			name: "unknown file key",
+			file: `{"server": {"adress": ":5000"}}`,
+			want: []string{`unknown field "adress"`},
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-es-password", "secret"},
+			want: []string{"es-password"},
+		},
+		{
+			name: "every invalid setting at once",
+			env: map[string]string{
+				"SERVER_ADDR":            "5000",
+				"JWT_ACCESS_LIFETIME":    "8d",
+				"ES_INDEX_PRODUCTS":      "Products",
+				"ES_INDEX_SALES":         "users",
+				"CURSOR_KEY":             "short",
+				"TLS_CERT_FILE":          "cert.pem",
+				"STOCK_ALERT_WEBHOOK":    "ftp://hooks.example.com",
+				"MFA_CHALLENGE_LIFETIME": "0s",
+			},
+			want: []string{
+				`server.addr "5000"`,
+				"server.tls needs both",
+				"tokens.access_lifetime cannot be longer",
+				"tokens.challenge_lifetime must be positive",
+				"tokens.cursor_key",
+				`indices.products: "Products" must be lowercase`,
+				`indices.sales and indices.users are both "users"`,
+				"alerts.webhook",
+			},
+		},
+		{
+			name: "S3 without credentials",
+			file: `{"uploads": {"s3": {"bucket": "pictures"}}}`,
+			want: []string{"uploads.s3 needs access_key and secret_key"},
+		},
+		{
+			name: "wildcard next to an origin",
+			env:  map[string]string{"CORS_ORIGINS": "*,https://a.example.com"},
+			want: []string{`next to "*"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file)}, args...)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load(args)
+			if err == nil {
+				t.Fatal("loaded")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error lacks %q:\n%s", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	cfg := Default()
+	cfg.Server.AppURL = "https://shop.example.com:8443/app/"
+	cfg.Uploads.S3 = S3{Region: "eu-west-1", Bucket: "pictures"}
+	cfg.applyDefaults()
+
+	if !slices.Equal(cfg.CORS.AllowOrigins, []string{"https://shop.example.com:8443"}) {
+		t.Errorf("origins = %q", cfg.CORS.AllowOrigins)
+	}
+	if cfg.Uploads.S3.Endpoint != "https://s3.eu-west-1.amazonaws.com" {
+		t.Errorf("endpoint = %q", cfg.Uploads.S3.Endpoint)
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// setting binds a field to its environment variable and, unless flag is
+// empty, to a command line flag. Secrets get no flag, as the command line
+// is visible to other users of the machine.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	// value is a *string, *[]string, *bool or *Duration field of the Config.
+	value interface{}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"SERVER_ADDR", "addr", `host:port to listen on, ":5000" for every interface`, &c.Server.Addr},
+		{"TLS_CERT_FILE", "tls-cert", "TLS certificate file, serves HTTPS with -tls-key", &c.Server.TLS.CertFile},
+		{"TLS_KEY_FILE", "tls-key", "TLS private key file", &c.Server.TLS.KeyFile},
+		{"APP_URL", "app-url", "frontend address used in emailed links", &c.Server.AppURL},
+		{"ASSETS_DIR", "assets-dir", "directory of the static images", &c.Server.AssetsDir},
+
+		{"ES_HOST", "es-addresses", "comma separated Elasticsearch URLs", &c.Elasticsearch.Addresses},
+		{"ES_USER", "", "", &c.Elasticsearch.Username},
+		{"ES_PASSWORD", "", "", &c.Elasticsearch.Password},
+		{"ES_CA_CERT", "es-ca-cert", "CA certificate file of the Elasticsearch cluster", &c.Elasticsearch.CACertFile},
+		{"ES_INSECURE_SKIP_VERIFY", "es-insecure", "accept any Elasticsearch certificate", &c.Elasticsearch.InsecureSkipVerify},
+
+		{"CORS_ORIGINS", "cors-origins", `comma separated origins allowed to call the API, or "*"`, &c.CORS.AllowOrigins},
+
+		{"JWT_KEYS_DIR", "jwt-keys-dir", "directory of the JWT signing keys", &c.Tokens.KeysDir},
+		{"JWT_SIGNING_KID", "jwt-signing-kid", "key id to sign new tokens with", &c.Tokens.SigningKid},
+		{"CURSOR_KEY", "", "", &c.Tokens.CursorKey},
+		{"JWT_ACCESS_LIFETIME", "access-lifetime", "lifetime of access tokens", &c.Tokens.AccessLifetime},
+		{"JWT_REFRESH_LIFETIME", "refresh-lifetime", "lifetime of a login session", &c.Tokens.RefreshLifetime},
+		{"MFA_CHALLENGE_LIFETIME", "", "", &c.Tokens.ChallengeLifetime},
+		{"VERIFY_EMAIL_LIFETIME", "", "", &c.Tokens.VerifyEmailLifetime},
+		{"PASSWORD_RESET_LIFETIME", "", "", &c.Tokens.PasswordResetLifetime},
+
+		{"ES_INDEX_USERS", "", "", &c.Indices.Users},
+		{"ES_INDEX_PRODUCTS", "", "", &c.Indices.Products},
+		{"ES_INDEX_SALES", "", "", &c.Indices.Sales},
+		{"ES_INDEX_STOCK_MOVEMENTS", "", "", &c.Indices.StockMovements},
+		{"ES_INDEX_REFRESH_TOKENS", "", "", &c.Indices.RefreshTokens},
+		{"ES_INDEX_REVOKED_TOKENS", "", "", &c.Indices.RevokedTokens},
+		{"ES_INDEX_LOGIN_ATTEMPTS", "", "", &c.Indices.LoginAttempts},
+
+		{"BLOB_DIR", "upload-dir", "directory of uploaded files when no S3 bucket is set", &c.Uploads.Dir},
+		{"S3_ENDPOINT", "s3-endpoint", "S3 compatible server URL", &c.Uploads.S3.Endpoint},
+		{"S3_REGION", "s3-region", "S3 region", &c.Uploads.S3.Region},
+		{"S3_BUCKET", "s3-bucket", "S3 bucket of uploaded files", &c.Uploads.S3.Bucket},
+		{"S3_ACCESS_KEY", "", "", &c.Uploads.S3.AccessKey},
+		{"S3_SECRET_KEY", "", "", &c.Uploads.S3.SecretKey},
+
+		{"MAIL_FROM", "mail-from", "sender address of account emails", &c.Mail.From},
+		{"MAIL_DIR", "mail-dir", "directory to write emails to when no SMTP host is set", &c.Mail.Dir},
+		{"SMTP_HOST", "smtp-host", "SMTP relay host", &c.Mail.SMTPHost},
+		{"SMTP_PORT", "smtp-port", "SMTP relay port", &c.Mail.SMTPPort},
+		{"SMTP_USERNAME", "", "", &c.Mail.SMTPUsername},
+		{"SMTP_PASSWORD", "", "", &c.Mail.SMTPPassword},
+
+		{"ADMIN_USERNAME", "", "", &c.Admin.Username},
+		{"ADMIN_EMAIL", "", "", &c.Admin.Email},
+		{"ADMIN_PASSWORD", "", "", &c.Admin.Password},
+		{"ADMIN_PROMOTE_EXISTING", "", "", &c.Admin.PromoteExisting},
+
+		{"STOCK_ALERT_EMAIL", "", "", &c.Alerts.Emails},
+		{"STOCK_ALERT_WEBHOOK", "", "", &c.Alerts.Webhook},
+	}
+}
+
+func (s setting) set(v string) error {
+	switch p := s.value.(type) {
+	case *string:
+		*p = v
+	case *[]string:
+		*p = nil
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	case *bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*p = b
+	case *Duration:
+		d, err := ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*p = d
+	}
+	return nil
+}
+
+// Load reads the configuration from, by increasing precedence, the
+// defaults, the JSON file named by -config or CONFIG_FILE, the environment
+// and the command line flags in args. A .env file in the working directory
+// is read into the environment first when there is one, without replacing
+// variables that are already set. The result is validated.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	file := flags.String("config", "", "JSON configuration file (CONFIG_FILE)")
+	// Flags are only applied once the file and the environment are read
+	var fromFlags []func() error
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		flags.Func(s.flag, s.usage+" ("+s.env+")", func(v string) error {
+			fromFlags = append(fromFlags, func() error {
+				if err := s.set(v); err != nil {
+					return fmt.Errorf("-%s: %w", s.flag, err)
+				}
+				return nil
+			})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	if *file == "" {
+		*file = os.Getenv("CONFIG_FILE")
+	}
+	if *file != "" {
+		if err := cfg.readFile(*file); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v := os.Getenv(s.env); v != "" {
+			if err := s.set(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	for _, apply := range fromFlags {
+		if err := apply(); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.applyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return &cfg, nil
+}
+
+// readFile overlays the settings present in a JSON file. Unknown keys are
+// refused, so a misspelt setting does not go unnoticed.
+func (c *Config) readFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	return nil
+}
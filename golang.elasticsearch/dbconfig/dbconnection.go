@@ -1,66 +1,53 @@
-package config
+package dbconfig
 
 import (
 	"crypto/tls"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"sync"
 
 	"github.com/elastic/go-elasticsearch/v8"
+	"golang.elasticsearch/config"
 )
 
-var (
-	once   sync.Once
-	client *elasticsearch.Client
-)
-
-func Connection() *elasticsearch.Client {
-	once.Do(func() {
-
-		cfg := elasticsearch.Config{
-			Addresses: []string{os.Getenv("ES_HOST")}, // Ensure this is https://...
-			Username:  os.Getenv("ES_USER"),
-			Password:  os.Getenv("ES_PASSWORD"),
-			// Add this to skip SSL verification for local self-signed certs
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
-
-		// FIX: Use = instead of := to assign to the global 'client' variable
-		var err error
-		client, err = elasticsearch.NewClient(cfg)
+// Connection creates the Elasticsearch client and checks that the cluster
+// answers.
+func Connection(cfg config.Elasticsearch) (*elasticsearch.Client, error) {
+	esCfg := elasticsearch.Config{
+		Addresses: cfg.Addresses,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+	}
+	if cfg.CACertFile != "" {
+		cert, err := os.ReadFile(cfg.CACertFile)
 		if err != nil {
-			log.Fatalf("Error creating Elasticsearch client: %s", err)
+			return nil, fmt.Errorf("reading Elasticsearch CA certificate: %w", err)
 		}
-
-		// Verify general cluster connection
-		res, err := client.Info()
-		if err != nil {
-			log.Fatalf("Error connecting to Elasticsearch: %s", err)
-		}
-		defer res.Body.Close()
-
-		if res.IsError() {
-			log.Fatalf("Elasticsearch returned an error: %s", res.String())
+		esCfg.CACert = cert
+	} else if cfg.InsecureSkipVerify {
+		// For the self-signed certificate of a local cluster
+		esCfg.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
-
-		log.Println("Successfully connected to Elasticsearch cluster")
-
-		// Check if the specific index exists
-		// indexName := "golang125"
-		// existsRes, err := client.Indices.Exists([]string{indexName})
-		// if err != nil {
-		// 	log.Printf("Error checking index existence: %s", err)
-		// } else {
-		// 	defer existsRes.Body.Close()
-		// 	if existsRes.StatusCode == 200 {
-		// 		log.Printf("Successfully connected to index: %s", indexName)
-		// 	} else if existsRes.StatusCode == 404 {
-		// 		log.Printf("Index %s does not exist", indexName)
-		// 	}
-		// }
-	})
-	return client
+	}
+
+	client, err := elasticsearch.NewClient(esCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating Elasticsearch client: %w", err)
+	}
+
+	// Verify general cluster connection
+	res, err := client.Info()
+	if err != nil {
+		return nil, fmt.Errorf("connecting to Elasticsearch: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("Elasticsearch returned an error: %s", res.String())
+	}
+
+	log.Println("Successfully connected to Elasticsearch cluster")
+	return client, nil
 }
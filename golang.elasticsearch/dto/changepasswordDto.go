@@ -1,5 +1,6 @@
 package dto
 
 type ChangePassword struct {
-	Password string `json:"password" binding:"required"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Password        string `json:"password" binding:"required,password"`
 }
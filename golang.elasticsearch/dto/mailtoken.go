@@ -0,0 +1,14 @@
+package dto
+
+type EmailAddress struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MailToken struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResetPassword struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,password"`
+}
@@ -1,5 +1,10 @@
 package dto
 
+// MfaActivation turns MFA on or off. Turning it off, or replacing an active
+// secret, needs proof from the account holder: a current OTP code or the
+// password.
 type MfaActivation struct {
-	TwoFactoEnabled bool `json:"TwoFactorEnabled"`
+	TwoFactoEnabled bool   `json:"TwoFactorEnabled"`
+	Otp             string `json:"otp" binding:"omitempty,numeric,len=6"`
+	Password        string `json:"password"`
 }
@@ -0,0 +1,7 @@
+package dto
+
+type MfaChallenge struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Otp            string `json:"otp" binding:"omitempty,numeric,len=6"`
+	RecoveryCode   string `json:"recovery_code" binding:"required_without=Otp"`
+}
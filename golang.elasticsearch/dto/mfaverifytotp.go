@@ -1,5 +1,5 @@
 package dto
 
 type MfaKeys struct {
-	Otp string `json:"otp"`
+	Otp string `json:"otp" binding:"required,numeric,len=6"`
 }
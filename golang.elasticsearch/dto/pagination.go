@@ -0,0 +1,8 @@
+package dto
+
+// Pagination are the query parameters of the cursor paginated lists. Cursor
+// is the next_cursor of the previous page, empty for the first one.
+type Pagination struct {
+	Size   int    `form:"size" json:"size" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor" json:"cursor" binding:"max=2048"`
+}
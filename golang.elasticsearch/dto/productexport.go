@@ -0,0 +1,7 @@
+package dto
+
+// ProductExport picks the file format of a catalogue export. The products
+// are filtered with the ProductSearch parameters.
+type ProductExport struct {
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=csv xlsx ndjson"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+// ProductImport are the query parameters of the bulk product import. Format
+// is only needed when it cannot be told from the file name or content type.
+type ProductImport struct {
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=csv ndjson"`
+	DryRun bool   `form:"dryrun" json:"dryrun"`
+}
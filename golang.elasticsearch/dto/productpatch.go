@@ -0,0 +1,49 @@
+package dto
+
+// ProductPatch carries a partial product update. Only the fields present in
+// the request body are changed; the merged product is validated as Products.
+type ProductPatch struct {
+	Sku            *string  `json:"sku"`
+	Category       *string  `json:"category"`
+	Descriptions   *string  `json:"descriptions"`
+	Unit           *string  `json:"unit"`
+	Costprice      *float64 `json:"costprice"`
+	Sellprice      *float64 `json:"sellprice"`
+	Saleprice      *float64 `json:"saleprice"`
+	Productpicture *string  `json:"productpicture"`
+	Alertstocks    *float64 `json:"alertstocks"`
+	Criticalstocks *float64 `json:"criticalstocks"`
+}
+
+// Apply copies the fields set in the patch onto p and reports whether there
+// were any.
+func (patch ProductPatch) Apply(p *Products) bool {
+	changed := false
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+			changed = true
+		}
+	}
+	setFloat := func(dst *float64, src *float64) {
+		if src != nil {
+			*dst = *src
+			changed = true
+		}
+	}
+
+	setString(&p.Sku, patch.Sku)
+	setString(&p.Category, patch.Category)
+	setString(&p.Descriptions, patch.Descriptions)
+	setString(&p.Unit, patch.Unit)
+	setFloat(&p.Costprice, patch.Costprice)
+	setFloat(&p.Sellprice, patch.Sellprice)
+	setFloat(&p.Saleprice, patch.Saleprice)
+	setFloat(&p.Alertstocks, patch.Alertstocks)
+	setFloat(&p.Criticalstocks, patch.Criticalstocks)
+	if patch.Productpicture != nil {
+		p.Productpicture = patch.Productpicture
+		changed = true
+	}
+	return changed
+}
@@ -1,15 +1,33 @@
 package dto
 
+import "time"
+
+// Products is validated as a whole: prices and stock levels cannot be
+// negative, a product is not sold below cost, the sale price is a discount on
+// the selling price and the critical level sits below the alert level. Sku is
+// optional but unique when set.
 type Products struct {
-	Id             string  `json:"id"`
-	Category       string  `json:"category"`
-	Descriptions   string  `json:"descriptions"`
-	Qty            float64 `json:"qty"`
-	Unit           string  `json:"unit"`
-	Costprice      float64 `json:"costprice"`
-	Sellprice      float64 `json:"sellprice"`
-	Saleprice      float64 `json:"saleprice"`
-	Productpicture *string `json:"productpicture"`
-	Alertstocks    float64 `json:"alertstocks"`
-	Criticalstocks float64 `json:"criticalstocks"`
+	Id             string         `json:"id"`
+	Sku            string         `json:"sku" binding:"max=64"`
+	Category       string         `json:"category" binding:"required,max=50"`
+	Descriptions   string         `json:"descriptions" binding:"required,max=200"`
+	Qty            float64        `json:"qty" binding:"gte=0"`
+	Unit           string         `json:"unit" binding:"max=20"`
+	Costprice      float64        `json:"costprice" binding:"gte=0"`
+	Sellprice      float64        `json:"sellprice" binding:"gte=0,gtefield=Costprice"`
+	Saleprice      float64        `json:"saleprice" binding:"omitempty,gte=0,ltefield=Sellprice"`
+	Productpicture *string        `json:"productpicture"`
+	Images         *ProductImages `json:"images,omitempty"`
+	Alertstocks    float64        `json:"alertstocks" binding:"gte=0"`
+	Criticalstocks float64        `json:"criticalstocks" binding:"gte=0,ltefield=Alertstocks"`
+	CreatedAt      time.Time      `json:"created_at,omitzero"`
+	UpdatedAt      time.Time      `json:"updated_at,omitzero"`
+}
+
+// ProductImages are the keys of the uploaded picture sizes, served under
+// /media. They are only set by the image upload.
+type ProductImages struct {
+	Original  string `json:"original"`
+	Medium    string `json:"medium"`
+	Thumbnail string `json:"thumbnail"`
 }
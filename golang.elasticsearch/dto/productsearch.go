@@ -0,0 +1,20 @@
+package dto
+
+// ProductSearch are the filters of the catalogue search, which pages with
+// Pagination. Category may be repeated to match any of several categories.
+// MaxPrice cannot be below MinPrice, a rule checked on the whole struct.
+type ProductSearch struct {
+	Query    string   `form:"q" json:"q" binding:"max=200"`
+	Category []string `form:"category" json:"category" binding:"max=20,dive,max=50"`
+	MinPrice *float64 `form:"minprice" json:"minprice" binding:"omitempty,gte=0"`
+	MaxPrice *float64 `form:"maxprice" json:"maxprice" binding:"omitempty,gte=0"`
+	InStock  bool     `form:"instock" json:"instock"`
+	Sort     string   `form:"sort" json:"sort" binding:"omitempty,oneof=relevance price_asc price_desc name_asc name_desc newest"`
+}
+
+// ProductHit is a catalogue search result. Highlights hold the matched
+// fragments of descriptions and category, wrapped in <em> tags.
+type ProductHit struct {
+	Products
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+// ProductSuggest are the query parameters of the search autocomplete.
+type ProductSuggest struct {
+	Query string `form:"q" json:"q" binding:"required,max=100"`
+	Size  int    `form:"size" json:"size" binding:"omitempty,min=1,max=20"`
+}
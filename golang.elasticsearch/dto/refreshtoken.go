@@ -0,0 +1,5 @@
+package dto
+
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
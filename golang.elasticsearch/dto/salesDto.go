@@ -1,7 +1,13 @@
 package dto
 
+// Sales posts a sales transaction. Prices and totals are taken from the
+// products, the client only says what was sold.
 type Sales struct {
-	Amount    float64 `json:"amount"`
-	Salesdate string  `json:"salesdate"`
-	// MonthAbbr string  `json:"month_abbr"`
+	Salesdate string     `json:"salesdate" binding:"omitempty,isodate"`
+	Items     []SaleItem `json:"items" binding:"required,min=1,max=100,dive"`
+}
+
+type SaleItem struct {
+	ProductID string  `json:"product_id" binding:"required"`
+	Qty       float64 `json:"qty" binding:"gt=0"`
 }
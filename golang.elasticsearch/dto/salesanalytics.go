@@ -0,0 +1,11 @@
+package dto
+
+// SalesAnalytics are the query parameters of the sales analytics endpoint.
+// From and To are inclusive dates. Sale dates are UTC calendar days, so
+// there is no time zone: the buckets are cut at midnight UTC.
+type SalesAnalytics struct {
+	From     string `form:"from" json:"from" binding:"omitempty,isodate"`
+	To       string `form:"to" json:"to" binding:"omitempty,isodate"`
+	Interval string `form:"interval" json:"interval" binding:"omitempty,oneof=day week month quarter year"`
+	GroupBy  string `form:"groupby" json:"groupby" binding:"omitempty,oneof=category"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+// SalesChart are the query parameters of the sales chart images. Year is a
+// shortcut for the range from January 1 to December 31 of that year.
+type SalesChart struct {
+	Year     int    `form:"year" json:"year" binding:"omitempty,min=1900,max=9999,excluded_with=From To"`
+	From     string `form:"from" json:"from" binding:"omitempty,isodate"`
+	To       string `form:"to" json:"to" binding:"omitempty,isodate"`
+	Interval string `form:"interval" json:"interval" binding:"omitempty,oneof=day week month quarter year"`
+	Type     string `form:"type" json:"type" binding:"omitempty,oneof=bar line pie stacked"`
+	Format   string `form:"format" json:"format" binding:"omitempty,oneof=png svg"`
+	Width    int    `form:"width" json:"width" binding:"omitempty,min=200,max=4000"`
+	Height   int    `form:"height" json:"height" binding:"omitempty,min=200,max=4000"`
+	Logo     *bool  `form:"logo" json:"logo"`
+}
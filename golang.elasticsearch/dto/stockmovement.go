@@ -0,0 +1,10 @@
+package dto
+
+// StockMovement posts a change to a product's stock. Quantity is positive
+// except for adjustments, where a negative value writes stock off.
+type StockMovement struct {
+	Type      string  `json:"type" binding:"required,oneof=receipt issue adjustment return"`
+	Quantity  float64 `json:"quantity" binding:"required"`
+	Reference string  `json:"reference" binding:"max=100"`
+	Note      string  `json:"note" binding:"max=500"`
+}
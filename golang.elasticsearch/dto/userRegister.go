@@ -1,10 +1,10 @@
 package dto
 
 type UserRegister struct {
-	Firstname string `json:"firstname"`
-	Lastname  string `json:"lastname"`
-	Email     string `json:"email"`
-	Mobile    string `json:"mobile"`
-	Username  string `json:"username"`
-	Password  string `json:"password"`
+	Firstname string `json:"firstname" binding:"max=50"`
+	Lastname  string `json:"lastname" binding:"max=50"`
+	Email     string `json:"email" binding:"required,email,max=100"`
+	Mobile    string `json:"mobile" binding:"omitempty,mobile"`
+	Username  string `json:"username" binding:"required,min=3,max=30"`
+	Password  string `json:"password" binding:"required,password"`
 }
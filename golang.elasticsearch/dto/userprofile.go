@@ -1,7 +1,7 @@
 package dto
 
 type ProfileData struct {
-	Firstname string `json:"firstname"`
-	Lastname  string `json:"lastname"`
-	Mobile    string `json:"mobile"`
+	Firstname string `json:"firstname" binding:"max=50"`
+	Lastname  string `json:"lastname" binding:"max=50"`
+	Mobile    string `json:"mobile" binding:"omitempty,mobile"`
 }
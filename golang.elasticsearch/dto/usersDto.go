@@ -1,18 +1,21 @@
 package dto
 
+import "time"
+
+// Users is the public view of an account. It never carries the password
+// hash or the TOTP secret, nor the QR code that encodes it.
 type Users struct {
-	Id          string `json:"id"`
-	Firstname   string `json:"firstname"`
-	Lastname    string `json:"lastname"`
-	Email       string `json:"email"`
-	Mobile      string `json:"mobile"`
-	Username    string `json:"username"`
-	Password    string `json:"password"`
-	Roles       string `json:"roles"`
-	Isactivated bool   `json:"isactivated"`
-	Isblocked   bool   `json:"isblocked"`
-	Userpicture string `json:"userpicture"`
+	Id          string     `json:"id"`
+	Firstname   string     `json:"firstname"`
+	Lastname    string     `json:"lastname"`
+	Email       string     `json:"email"`
+	Mobile      string     `json:"mobile"`
+	Username    string     `json:"username"`
+	Roles       []string   `json:"roles"`
+	Isactivated bool       `json:"isactivated"`
+	Isblocked   bool       `json:"isblocked"`
+	Lockeduntil *time.Time `json:"lockeduntil"`
+	Userpicture string     `json:"userpicture"`
 	// Mailtoken   float64 `json:"mailtoken"`
-	Qrcodeurl *string `json:"qrcodeurl"`
-	Secret    *string `json:"secret"`
+	Mfaenabled bool `json:"mfaenabled"`
 }
@@ -0,0 +1,165 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeAll(t *testing.T, format string, columns []string, rows ...[]interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+1+2", "'+1+2"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"Widget = good", "Widget = good"},
+		{"'quoted", "'quoted"},
+		{"", ""},
+		{-2.5, "-2.5"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		data := writeAll(t, FormatCSV, []string{"value", "sku"}, []interface{}{tt.value, "W-1"})
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := records[1][0]; got != tt.want {
+			t.Errorf("%q written as %q, want %q", tt.value, got, tt.want)
+		}
+		if s, ok := tt.value.(string); ok {
+			if back := UnescapeFormula(records[1][0]); back != s {
+				t.Errorf("%q reads back as %q", s, back)
+			}
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	data := writeAll(t, FormatCSV, []string{"sku", "descriptions", "qty"},
+		[]interface{}{"W-1", "Widget, large", 1200.0},
+		[]interface{}{"W-2", nil, 0.5},
+	)
+	want := "sku,descriptions,qty\nW-1,\"Widget, large\",1200\nW-2,,0.5\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	data := writeAll(t, FormatNDJSON, []string{"sku", "qty", "unit"},
+		[]interface{}{"=W-1", 3.0, nil},
+	)
+	// Keys stay in column order and values are not escaped
+	want := `{"sku":"=W-1","qty":3,"unit":null}` + "\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal(data, &row); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	data := writeAll(t, FormatXLSX, []string{"sku", "descriptions", "qty"},
+		[]interface{}{"W-1", "=1+1 <b> & \x01", 4.0},
+		[]interface{}{"W-2", nil, 1.5},
+	)
+
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var sheet string
+	for _, f := range z.File {
+		names = append(names, f.Name)
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(b)
+	}
+	for _, part := range []string{"[Content_Types].xml", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if !slices.Contains(names, part) {
+			t.Errorf("workbook lacks %s", part)
+		}
+	}
+
+	for _, cell := range []string{
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">sku</t></is></c>`,
+		// Text that looks like a formula stays an inline string
+		`<c r="B2" s="0" t="inlineStr"><is><t xml:space="preserve">=1+1 &lt;b&gt; &amp; </t></is></c>`,
+		`<c r="C2" s="0"><v>4</v></c>`,
+		`<row r="3"><c r="A3" s="0" t="inlineStr"><is><t xml:space="preserve">W-2</t></is></c><c r="C3" s="0"><v>1.5</v></c></row>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("sheet lacks %s", cell)
+		}
+	}
+	if strings.Contains(sheet, "<f>") {
+		t.Error("sheet holds a formula")
+	}
+}
+
+func TestCellRef(t *testing.T) {
+	tests := []struct {
+		col, row int
+		want     string
+	}{
+		{0, 1, "A1"},
+		{25, 2, "Z2"},
+		{26, 3, "AA3"},
+		{27, 4, "AB4"},
+		{701, 5, "ZZ5"},
+		{702, 6, "AAA6"},
+	}
+	for _, tt := range tests {
+		if got := cellRef(tt.col, tt.row); got != tt.want {
+			t.Errorf("cellRef(%d, %d) = %q, want %q", tt.col, tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := NewWriter(io.Discard, "pdf", []string{"sku"}); err == nil {
+		t.Error("pdf accepted")
+	}
+}
@@ -0,0 +1,113 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for the accepted upload formats
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels bounds the decoded size of an upload, so a small file that
+// expands to a huge bitmap is refused before it is decoded.
+const MaxPixels = 40_000_000
+
+var (
+	// ErrUnsupported is returned for content that is not a JPEG, PNG, GIF
+	// or WebP image, whatever its name or declared type says.
+	ErrUnsupported = errors.New("file is not a JPEG, PNG, GIF or WebP image")
+	ErrTooLarge    = fmt.Errorf("image is larger than %d megapixels", MaxPixels/1_000_000)
+)
+
+var accepted = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Decode sniffs data, refuses anything but the accepted image types and
+// decodes it scaled down to fit a size x size box. The EXIF orientation of
+// a JPEG is applied to the pixels, as the metadata itself is not kept.
+func Decode(data []byte, size int) (image.Image, error) {
+	if !accepted[http.DetectContentType(data)] {
+		return nil, ErrUnsupported
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	return orient(Fit(img, size), jpegOrientation(data)), nil
+}
+
+// Fit scales img down to fit a size x size box, keeping its proportions.
+// Smaller images are returned as they are.
+func Fit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Encode writes img as a JPEG, or as a PNG when it has transparent pixels
+// a JPEG would lose. It returns the bytes, their content type and the file
+// extension to store them under.
+func Encode(img image.Image) ([]byte, string, string, error) {
+	if o, ok := img.(interface{ Opaque() bool }); ok && !o.Opaque() {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", ".png", nil
+	}
+	data, err := EncodeJPEG(img)
+	return data, "image/jpeg", ".jpg", err
+}
+
+// Square crops the middle of img to a square and scales it to size x size.
+func Square(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x, y := b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, image.Rect(x, y, x+side, y+side), draw.Src, nil)
+	return dst
+}
+
+// EncodeJPEG writes img as a JPEG. Transparent pixels are laid over white
+// rather than turning black.
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	if o, ok := img.(interface{ Opaque() bool }); !ok || !o.Opaque() {
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		img = flat
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -1,34 +1,30 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
+	"os"
 	"time"
+	_ "time/tzdata" // analytics time zones on hosts without a zoneinfo database
 
 	_ "golang.elasticsearch/docs"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
+	"golang.elasticsearch/alerts"
+	"golang.elasticsearch/config"
 	dbconfig "golang.elasticsearch/dbconfig"
-	"golang.elasticsearch/middleware"
+	"golang.elasticsearch/mailer"
 	auth "golang.elasticsearch/middleware/auth"
-	prods "golang.elasticsearch/middleware/prods"
-	users "golang.elasticsearch/middleware/users"
+	"golang.elasticsearch/migrations"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/routes"
+	"golang.elasticsearch/storage"
+	"golang.elasticsearch/utils"
 )
 
-func init() {
-	err := godotenv.Load(".env")
-	if err != nil {
-		log.Fatalf("Error loading .env file")
-	} else {
-		dbconfig.Connection()
-	}
-}
-
 // @title BARCLAYS BANK API Management
 // @version 1.0
 
@@ -53,53 +49,127 @@ func init() {
 // @name Authorization
 // @description Type "Bearer" followed by a space and your token.
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
-	router.Static("/assets", "./assets")
-
-	// router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler,
-		ginSwagger.InstanceName("swagger"),
-		ginSwagger.DefaultModelsExpandDepth(-1),
-	))
-
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "OPTIONS", "PUT", "PATCH", "DELETE", "HEAD"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	router.POST("/auth/signin", auth.Login)
-	router.POST("/auth/signup", auth.Register)
-	router.POST("/addproduct", prods.AddProduct)
-	router.GET("/products/list/:page", prods.GetProductList)
-	router.GET("/products/search/:page/:key", prods.ProductSearch)
-	router.GET("/productreport", prods.ProductPDFReport)
-	router.GET("/sales/barchart", prods.GetSalesChart)
-	router.GET("/sales/piechart", prods.GetLineChart)
-	router.POST("/addsalesdata", prods.AddSalesData)
-
-	authGuard := router.Group("/api")
-	authGuard.Use(middleware.AuthMiddleware())
-	{
-		authGuard.GET("/getallusers", users.GetAllUsers)
-		authGuard.GET("/getuserbyid/:id", users.GetUserid)
-		authGuard.PATCH("/mfa/activate/:id", auth.MfaActivate)
-		authGuard.PATCH("/mfa/verifytotp/:id", auth.MfaVerifyotp)
-		authGuard.PATCH("/changepassword/:id", users.ChangePassword)
-		authGuard.PATCH("/updateprofile/:id", users.UpdateProfile)
-		authGuard.PATCH("/uploadpicture/:id", users.UploadPicture)
-		authGuard.DELETE("/deleteuserbyid/:id", users.DeleteUserid)
+
+	esClient, err := dbconfig.Connection(cfg.Elasticsearch)
+	if err != nil {
+		log.Fatalf("Error connecting to Elasticsearch: %s", err)
+	}
+	if err := migrations.Run(context.Background(), esClient, cfg.Indices.Aliases()); err != nil {
+		log.Fatalf("Error migrating Elasticsearch indices: %s", err)
+	}
+
+	indices := cfg.Indices
+	userRepo := repository.NewElasticUserRepository(esClient, indices.Users)
+	err = repository.SeedAdmin(context.Background(), userRepo, cfg.Admin.Username, cfg.Admin.Email, cfg.Admin.Password, cfg.Admin.PromoteExisting)
+	if err != nil {
+		log.Fatalf("Error seeding admin account: %s", err)
+	}
+
+	tokenStore := repository.NewElasticTokenStore(esClient, indices.RefreshTokens, indices.RevokedTokens)
+	attemptStore := repository.NewElasticAttemptStore(esClient, indices.LoginAttempts)
+	go repository.RunCleanup(context.Background(), time.Hour, tokenStore, attemptStore)
+
+	keys, err := loadKeys(cfg.Tokens)
+	if err != nil {
+		log.Fatalf("Error loading JWT signing keys: %s", err)
 	}
 
-	host := "localhost"
-	port := "5000"
-	address := fmt.Sprintf("%s:%s", host, port)
-	log.Print("Listening to ", address)
-	log.Fatal(http.ListenAndServe(":5000", router))
+	if cfg.Tokens.CursorKey == "" {
+		log.Print("CURSOR_KEY is not set, pagination cursors only hold on this instance")
+	} else {
+		repository.SetCursorKey([]byte(cfg.Tokens.CursorKey))
+	}
+
+	mail := newMailer(cfg.Mail)
+	productRepo := repository.NewElasticProductRepository(esClient, indices.Products)
+	stockChecker := alerts.NewChecker(productRepo, newStockNotifier(mail, cfg.Alerts))
+	go stockChecker.Run(context.Background())
+
+	tokens := cfg.Tokens
+	router := routes.New(routes.Dependencies{
+		Users:       userRepo,
+		Products:    productRepo,
+		Sales:       repository.NewElasticSalesRepository(esClient, indices.Sales),
+		Stock:       stockChecker.Watch(repository.NewElasticStockRepository(esClient, indices.Products, indices.StockMovements)),
+		Tokens:      tokenStore,
+		Attempts:    attemptStore,
+		Keys:        keys,
+		Mailer:      mail,
+		Blobs:       newBlobStore(cfg.Uploads),
+		AppURL:      cfg.Server.AppURL,
+		CORSOrigins: cfg.CORS.AllowOrigins,
+		Lifetimes: auth.Lifetimes{
+			Access:        time.Duration(tokens.AccessLifetime),
+			Refresh:       time.Duration(tokens.RefreshLifetime),
+			Challenge:     time.Duration(tokens.ChallengeLifetime),
+			VerifyEmail:   time.Duration(tokens.VerifyEmailLifetime),
+			PasswordReset: time.Duration(tokens.PasswordResetLifetime),
+		},
+		AssetsDir: cfg.Server.AssetsDir,
+	})
+
+	server := &http.Server{Addr: cfg.Server.Addr, Handler: router}
+	if tls := cfg.Server.TLS; tls.Enabled() {
+		log.Print("Listening to https://", cfg.Server.Addr)
+		log.Fatal(server.ListenAndServeTLS(tls.CertFile, tls.KeyFile))
+	}
+	log.Print("Listening to http://", cfg.Server.Addr)
+	log.Fatal(server.ListenAndServe())
+}
+
+// loadKeys reads the signing keys from the keys directory. Without it a
+// throwaway key is generated, which logs everybody out on every restart.
+func loadKeys(cfg config.Tokens) (*utils.KeyManager, error) {
+	if cfg.KeysDir == "" {
+		log.Print("JWT_KEYS_DIR is not set, signing tokens with an ephemeral key")
+		return utils.NewEphemeralKeyManager()
+	}
+	return utils.LoadKeyManager(cfg.KeysDir, cfg.SigningKid)
+}
+
+// newMailer sends through the SMTP host when it is set. Otherwise mail is
+// written to the mail directory, or to the log, so signups can be tested
+// locally.
+func newMailer(cfg config.Mail) mailer.Mailer {
+	if cfg.SMTPHost == "" {
+		return mailer.NewFileMailer(cfg.Dir, cfg.From)
+	}
+	return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+}
+
+// newBlobStore keeps uploads in the S3 bucket when one is set, and under the
+// upload directory otherwise.
+func newBlobStore(cfg config.Uploads) storage.BlobStore {
+	if cfg.S3.Bucket == "" {
+		return storage.NewLocalStore(cfg.Dir)
+	}
+	return storage.NewS3Store(storage.S3Config{
+		Endpoint:  cfg.S3.Endpoint,
+		Region:    cfg.S3.Region,
+		Bucket:    cfg.S3.Bucket,
+		AccessKey: cfg.S3.AccessKey,
+		SecretKey: cfg.S3.SecretKey,
+	})
+}
+
+// newStockNotifier always logs stock alerts. They are also emailed and posted
+// to the webhook when those are configured.
+func newStockNotifier(mail mailer.Mailer, cfg config.Alerts) alerts.Notifier {
+	notifiers := []alerts.Notifier{alerts.LogNotifier{}}
+	if len(cfg.Emails) > 0 {
+		notifiers = append(notifiers, alerts.NewMailNotifier(mail, cfg.Emails))
+	}
+	if cfg.Webhook != "" {
+		notifiers = append(notifiers, alerts.NewWebhookNotifier(cfg.Webhook))
+	}
+	return alerts.Multi(notifiers...)
 }
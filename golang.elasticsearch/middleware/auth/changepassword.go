@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	utils "golang.elasticsearch/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+)
+
+// @Summary Change User Password
+// @Description User Change Password. The current password is required, and every other session of the user is logged out.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User Id"
+// @Param body body dto.ChangePassword true "New Password Details"
+// @Success 200 {object} dto.ChangePassword
+// @Router /api/changepassword/{id} [patch]
+// @Router /api/me/changepassword [patch]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	id := c.Param("id")
+	var userDto dto.ChangePassword
+
+	if !validation.BindJSON(c, &userDto) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(404, gin.H{"message": "User ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	// A stolen access token alone must not be enough to take over the account
+	valid := h.guardOTP(c, user, "The current password is incorrect.", func() (bool, error) {
+		return utils.ComparePassword(user.Password, []byte(userDto.CurrentPassword)), nil
+	})
+	if !valid {
+		return
+	}
+
+	// 1. Hash the new password
+	hash, err := utils.HashPassword(userDto.Password)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	// 2. Partial document update
+	err = h.users.Update(ctx, id, map[string]interface{}{
+		"password":   hash,
+		"updated_at": time.Now().UTC(),
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"message": fmt.Sprintf("Error updating database: %s", err)})
+		return
+	}
+
+	// 3. Whoever knew the old password may still hold a session; only the
+	// one making the change stays logged in
+	var current string
+	if value, ok := c.Get("claims"); ok {
+		if claims, ok := value.(*utils.Claims); ok && claims.Subject == id {
+			current = claims.SessionID
+		}
+	}
+	if err := h.revokeSessions(ctx, id, current); err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Password has been changed, your other sessions have been logged out."})
+}
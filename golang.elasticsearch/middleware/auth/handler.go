@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.elasticsearch/mailer"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/utils"
+)
+
+// Handler serves the authentication and MFA routes.
+type Handler struct {
+	users    repository.UserRepository
+	tokens   repository.TokenStore
+	keys     *utils.KeyManager
+	throttle throttle
+	mail     mailer.Mailer
+	// appURL is the frontend address used in links sent by email.
+	appURL    string
+	lifetimes Lifetimes
+}
+
+// Lifetimes are how long each kind of token stays valid.
+type Lifetimes struct {
+	Access time.Duration
+	// Refresh bounds a login session: refresh tokens, and therefore the
+	// session id, never outlive it.
+	Refresh       time.Duration
+	Challenge     time.Duration
+	VerifyEmail   time.Duration
+	PasswordReset time.Duration
+}
+
+func NewHandler(users repository.UserRepository, tokens repository.TokenStore, attempts repository.AttemptStore, keys *utils.KeyManager, mail mailer.Mailer, appURL string, lifetimes Lifetimes) *Handler {
+	return &Handler{
+		users:     users,
+		tokens:    tokens,
+		keys:      keys,
+		throttle:  throttle{attempts: attempts, users: users},
+		mail:      mail,
+		appURL:    strings.TrimSuffix(appURL, "/"),
+		lifetimes: lifetimes,
+	}
+}
+
+// GetUserInfo looks up a user by username, returning nil when there is none.
+func (h *Handler) GetUserInfo(ctx context.Context, userName string) (*models.User, error) {
+	users, _, err := h.users.Search(ctx, repository.UserQuery{Username: userName, Size: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil // User not found
+	}
+	return &users[0], nil
+}
+
+// checkOTP validates a TOTP code for the user and records the time step it
+// used, so the same code cannot be presented twice. The check runs against
+// the stored user and the write is conditional on it, so two requests racing
+// with one code cannot both pass.
+func (h *Handler) checkOTP(ctx context.Context, user *models.User, code string) (bool, error) {
+	var valid bool
+	err := h.users.Modify(ctx, user.ID, func(current *models.User) (map[string]interface{}, error) {
+		valid = false
+		if current.Secret == nil {
+			return nil, nil
+		}
+		step, ok := utils.ValidateTOTP(code, *current.Secret, current.Totpstep)
+		if !ok {
+			return nil, nil
+		}
+		valid = true
+		user.Totpstep = step
+		return map[string]interface{}{"totpstep": step}, nil
+	})
+	return valid, err
+}
+
+// useRecoveryCode accepts one of the user's recovery codes and removes it so
+// it cannot be used again, with the same conditional write as checkOTP.
+func (h *Handler) useRecoveryCode(ctx context.Context, user *models.User, code string) (bool, error) {
+	var valid bool
+	err := h.users.Modify(ctx, user.ID, func(current *models.User) (map[string]interface{}, error) {
+		valid = false
+		i := utils.MatchRecoveryCode(current.Recoverycodes, code)
+		if i < 0 {
+			return nil, nil
+		}
+		valid = true
+		user.Recoverycodes = slices.Delete(slices.Clone(current.Recoverycodes), i, i+1)
+		return map[string]interface{}{"recoverycodes": user.Recoverycodes}, nil
+	})
+	return valid, err
+}
+
+// issueTokens creates an access token and a fresh refresh token for the
+// session that started at started. Neither outlives the session, which ends
+// a refresh lifetime after it started however often it is refreshed. The
+// refresh token is only stored as a hash.
+func (h *Handler) issueTokens(ctx context.Context, user *models.User, sessionID string, started time.Time) (string, string, error) {
+	now := time.Now().UTC()
+	end := h.sessionEnd(started)
+
+	lifetime := h.lifetimes.Access
+	if rest := end.Sub(now); rest < lifetime {
+		lifetime = rest
+	}
+	access, err := h.keys.GenerateJWT(user.ID, user.Email, user.Roles, sessionID, lifetime)
+	if err != nil {
+		return "", "", err
+	}
+
+	refresh, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = h.tokens.SaveRefresh(ctx, &models.RefreshToken{
+		ID:          hash,
+		UserID:      user.ID,
+		Family:      sessionID,
+		FamilyStart: started.UTC(),
+		ExpiresAt:   end,
+		CreatedAt:   now,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+// sessionEnd is when a session started at started stops working.
+func (h *Handler) sessionEnd(started time.Time) time.Time {
+	return started.UTC().Add(h.lifetimes.Refresh)
+}
+
+// revokeSessions ends every session of the user but keep, which may be
+// empty, until the last of its tokens would have expired anyway.
+func (h *Handler) revokeSessions(ctx context.Context, userID string, keep string) error {
+	sessions, err := h.tokens.Sessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for family, expiresAt := range sessions {
+		if family == keep {
+			continue
+		}
+		if err := h.tokens.Revoke(ctx, family, expiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
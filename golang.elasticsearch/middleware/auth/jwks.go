@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify access tokens, selected by the token "kid" header. Access tokens carry the audience "api" and the type "at+jwt"; require both, as MFA challenge tokens are signed with the same keys.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": h.keys.JWKS()})
+}
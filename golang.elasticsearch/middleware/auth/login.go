@@ -1,16 +1,14 @@
 package middleware
 
 import (
-	"bytes"
-	"context"
-	"encoding/json"
-	"fmt"
+	"time"
 
-	dbconfig "golang.elasticsearch/dbconfig"
 	utils "golang.elasticsearch/utils"
 
 	"github.com/gin-gonic/gin"
 	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/validation"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -22,123 +20,112 @@ import (
 // @Param login body dto.UserLogin true "User Login Credentials"
 // @Success 200 {array} dto.UserLogin
 // @Router /auth/signin [post]
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	var userDto dto.UserLogin
 
-	if err := c.ShouldBindJSON(&userDto); err != nil {
-		c.JSON(400, gin.H{"message": "Invalid request format"})
+	if !validation.BindJSON(c, &userDto) {
 		return
 	}
 	plainPwd := userDto.Password
-	user, err := GetUserInfo(userDto.Username)
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+	key := userKey(userDto.Username)
+
+	wait, err := h.throttle.wait(ctx, key, ipKey(ip))
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
+	user, err := h.GetUserInfo(ctx, userDto.Username)
 	if err != nil {
 		c.JSON(400, gin.H{"message": err.Error()})
 		return
 	}
 
 	if user == nil {
-
+		if _, err := h.throttle.fail(ctx, nil, key, ip); err != nil {
+			c.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
 		c.JSON(404, gin.H{"message": "Username not found, please register."})
 		return
 	} else {
 
+		if user.Locked(time.Now()) {
+			accountLocked(c, user)
+			return
+		}
+
 		hashPwd := user.Password
 		err := bcrypt.CompareHashAndPassword([]byte(hashPwd), []byte(plainPwd))
 		if err != nil {
+			locked, err := h.throttle.fail(ctx, user, key, ip)
+			if err != nil {
+				c.JSON(500, gin.H{"message": err.Error()})
+				return
+			}
+			if locked {
+				accountLocked(c, user)
+				return
+			}
 			c.JSON(400, gin.H{"message": "Invalid Password."})
 			return
-		} else {
+		}
 
-			token, _ := utils.GenerateJWT(user.Email)
+		if err := h.throttle.succeed(ctx, user, key); err != nil {
+			c.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+
+		if !user.Isactivated {
+			c.JSON(403, gin.H{"message": "Please verify your email address before logging in."})
+			return
+		}
 
+		if user.Secret != nil {
+			// The password alone is not enough, hand out a challenge for the OTP step
+			challenge, err := h.keys.GenerateChallengeJWT(user.ID, h.lifetimes.Challenge)
+			if err != nil {
+				c.JSON(500, gin.H{"message": "Unable to create MFA challenge."})
+				return
+			}
 			c.JSON(200, gin.H{
-				"id":          user.Id,
-				"firstname":   user.Firstname,
-				"lastname":    user.Lastname,
-				"email":       user.Email,
-				"mobile":      user.Mobile,
-				"username":    user.Username,
-				"roles":       user.Roles,
-				"isactivated": user.Isactivated,
-				"isblocked":   user.Isblocked,
-				"userpicture": user.Userpicture,
-				"qrcodeurl":   user.Qrcodeurl,
-				"token":       token,
-				"message":     "Login Successfull."})
+				"mfa_required":    true,
+				"challenge_token": challenge,
+				"message":         "mfa_required"})
+			return
 		}
 
+		h.sessionResponse(c, user)
 	}
 }
 
-func GetUserInfo(userName string) (*dto.Users, error) {
-	client := dbconfig.Connection()
-
-	// 1. Build the search query
-	// Use "username.keyword" if your field is type 'text' to ensure an exact match
-	// query := map[string]interface{}{
-	// 	"query": map[string]interface{}{
-	// 		"term": map[string]interface{}{
-	// 			"username.keyword": userName,
-	// 		},
-	// 	},
-	// }
-
-	query := map[string]interface{}{
-		"query": map[string]interface{}{
-			"match": map[string]interface{}{
-				"username": userName, // Use the base field, not .keyword
-			},
-		},
-	}
-
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(query); err != nil {
-		return nil, err
-	}
-
-	// 2. Execute the Search
-	res, err := client.Search(
-		client.Search.WithContext(context.Background()),
-		client.Search.WithIndex("users"),
-		client.Search.WithBody(&buf),
-		client.Search.WithTrackTotalHits(true),
-	)
+// sessionResponse starts a new session and writes the login payload.
+func (h *Handler) sessionResponse(c *gin.Context, user *models.User) {
+	token, refresh, err := h.issueTokens(c.Request.Context(), user, utils.NewTokenID(), time.Now())
 	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	if res.IsError() {
-		return nil, fmt.Errorf("error searching user: %s", res.String())
-	}
-
-	// 3. Parse the Response
-	var r map[string]interface{}
-	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
-		return nil, err
-	}
-
-	// 4. Extract hits
-	hits := r["hits"].(map[string]interface{})["hits"].([]interface{})
-
-	if len(hits) == 0 {
-		return nil, nil // User not found
-	}
-
-	hit := hits[0].(map[string]interface{})
-
-	// 2. Extract the system ID (which is at the top level of the hit)
-	id := hit["_id"].(string)
-
-	// 5. Convert the first hit to your DTO
-	firstHit := hits[0].(map[string]interface{})["_source"]
-	sourceData, _ := json.Marshal(firstHit)
-
-	var user dto.Users
-	if err := json.Unmarshal(sourceData, &user); err != nil {
-		return nil, err
+		c.JSON(500, gin.H{"message": "Unable to create session token."})
+		return
 	}
-	user.Id = id
 
-	return &user, nil
+	c.JSON(200, gin.H{
+		"id":            user.ID,
+		"firstname":     user.Firstname,
+		"lastname":      user.Lastname,
+		"email":         user.Email,
+		"mobile":        user.Mobile,
+		"username":      user.Username,
+		"roles":         user.Roles,
+		"isactivated":   user.Isactivated,
+		"isblocked":     user.Isblocked,
+		"userpicture":   user.Userpicture,
+		"qrcodeurl":     user.Qrcodeurl,
+		"token":         token,
+		"refresh_token": refresh,
+		"message":       "Login Successfull."})
 }
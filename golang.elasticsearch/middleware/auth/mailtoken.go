@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.elasticsearch/mailer"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/utils"
+)
+
+// sendMailToken stores a new token for purpose on the user, replacing any
+// earlier one, and mails the user a link to the page that redeems it.
+func (h *Handler) sendMailToken(ctx context.Context, user *models.User, purpose string) error {
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	var msg mailer.Message
+	var lifetime time.Duration
+	switch purpose {
+	case models.MailVerifyEmail:
+		lifetime = h.lifetimes.VerifyEmail
+		msg.Subject = "Please verify your email address"
+		msg.Body = "Welcome %s,\n\nOpen the link below to activate your account:\n\n%s\n\nThe link expires in %s.\n"
+		msg.Body = fmt.Sprintf(msg.Body, user.Username, h.link("verifyemail", token), humanDuration(lifetime))
+	case models.MailResetPassword:
+		lifetime = h.lifetimes.PasswordReset
+		msg.Subject = "Reset your password"
+		msg.Body = "Hello %s,\n\nOpen the link below to choose a new password:\n\n%s\n\nThe link expires in %s. If you did not ask for it, you can ignore this email.\n"
+		msg.Body = fmt.Sprintf(msg.Body, user.Username, h.link("resetpassword", token), humanDuration(lifetime))
+	default:
+		return fmt.Errorf("unknown mail token purpose %q", purpose)
+	}
+
+	expires := time.Now().UTC().Add(lifetime)
+	err = h.users.Update(ctx, user.ID, map[string]interface{}{
+		"mailtoken":        hash,
+		"mailtokenpurpose": purpose,
+		"mailtokenexpires": expires,
+	})
+	if err != nil {
+		return err
+	}
+
+	msg.To = user.Email
+	return h.mail.Send(ctx, msg)
+}
+
+// humanDuration writes a lifetime the way a person would when it is a whole
+// number of days, hours or minutes: "24 hours", "30 minutes".
+func humanDuration(d time.Duration) string {
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, u := range units {
+		if d < u.size || d%u.size != 0 {
+			continue
+		}
+		if n := d / u.size; n != 1 {
+			return fmt.Sprintf("%d %ss", n, u.name)
+		}
+		return "1 " + u.name
+	}
+	return d.String()
+}
+
+func (h *Handler) link(page string, token string) string {
+	return fmt.Sprintf("%s/#/%s?token=%s", h.appURL, page, url.QueryEscape(token))
+}
+
+// findMailToken returns the user a live token for purpose was sent to, or nil
+// when the token is unknown, used for something else or expired.
+func (h *Handler) findMailToken(ctx context.Context, token string, purpose string) (*models.User, error) {
+	users, _, err := h.users.Search(ctx, repository.UserQuery{Mailtoken: utils.HashToken(token), Size: 1})
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+
+	user := &users[0]
+	if user.Mailtokenpurpose != purpose || user.Mailtokenexpires == nil || time.Now().After(*user.Mailtokenexpires) {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// consumeMailToken writes fields and clears the mail token found on user in
+// one conditional write. It reports false, writing nothing, when a
+// concurrent request used or replaced the token first.
+func (h *Handler) consumeMailToken(ctx context.Context, user *models.User, fields map[string]interface{}) (bool, error) {
+	var consumed bool
+	err := h.users.Modify(ctx, user.ID, func(current *models.User) (map[string]interface{}, error) {
+		consumed = current.Mailtoken != "" && current.Mailtoken == user.Mailtoken &&
+			current.Mailtokenpurpose == user.Mailtokenpurpose
+		if !consumed {
+			return nil, nil
+		}
+		update := map[string]interface{}{
+			"mailtoken":        "",
+			"mailtokenpurpose": "",
+			"mailtokenexpires": nil,
+		}
+		for k, v := range fields {
+			update[k] = v
+		}
+		return update, nil
+	})
+	return consumed, err
+}
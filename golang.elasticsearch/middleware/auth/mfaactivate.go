@@ -1,18 +1,16 @@
 package middleware
 
 import (
-	"bytes"
-	"context"
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 
-	dbconfig "golang.elasticsearch/dbconfig"
-	utils "golang.elasticsearch/utils"
-
 	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/utils"
+	"golang.elasticsearch/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pquerna/otp/totp"
@@ -20,7 +18,7 @@ import (
 )
 
 // @Summary MFA Activation
-// @Description Multi-Factor Authenticator
+// @Description Multi-Factor Authenticator. Turning it off, or replacing an active one, needs a current OTP code or the password.
 // @Tags MultiFactor Authenticator
 // @Accept json
 // @Produce json
@@ -29,113 +27,106 @@ import (
 // @Param body body dto.MfaActivation true "Enable MFA"
 // @Success 200 {array} dto.MfaActivation
 // @Router /api/mfa/activate/{id} [patch]
-func MfaActivate(c *gin.Context) {
+// @Router /api/me/mfa/activate [patch]
+func (h *Handler) MfaActivate(c *gin.Context) {
 	id := c.Param("id")
-	var user dto.MfaActivation
-	err := json.NewDecoder(c.Request.Body).Decode(&user)
+	var mfa dto.MfaActivation
+	if !validation.BindJSON(c, &mfa) {
+		return
+	}
 
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(404, gin.H{"message": "User ID not found."})
+		return
+	}
 	if err != nil {
-		log.Fatalf("Unable to decode the request body.  %v", err)
+		c.JSON(400, gin.H{"message": err.Error()})
+		return
 	}
 
-	if user.TwoFactoEnabled {
-		user, err := utils.GetUserid(id)
-		if err != nil {
-			c.JSON(400, gin.H{"message": err.Error()})
-			return
+	// A stolen access token alone must not be enough to strip or replace the
+	// second factor
+	proof := func() (bool, error) {
+		if mfa.Otp != "" {
+			return h.checkOTP(ctx, user, mfa.Otp)
 		}
+		return utils.ComparePassword(user.Password, []byte(mfa.Password)), nil
+	}
 
-		if len(user) > 0 {
-			key, err := totp.Generate(totp.GenerateOpts{
-				Issuer:      "BARCLAYS BANK", // The name of your application
-				AccountName: user[0].Email,   // The user's account identifier
-			})
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate TOTP secret"})
-				return
-			}
-			// The key.Secret() is the base32 encoded secret you must save
-			secret := key.Secret()
-			// The key.URL() is the otpauth URI, which can be converted into a QR code
-			qrCodeURL := key.URL()
-
-			pngBytes, err := qrcode.Encode(qrCodeURL, qrcode.Medium, 256)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to generate QR code: %v", err)})
+	if mfa.TwoFactoEnabled {
+		if user.Secret != nil {
+			if mfa.Otp == "" && mfa.Password == "" {
+				c.JSON(400, gin.H{"message": "Enter a current OTP code or your password to replace the Multi-Factor Authenticator."})
 				return
 			}
-			// 3. Base64 encode the PNG bytes
-			var mfaData dto.MfaData
-			// "data:image/png;base64,
-			base64Encoded := base64.StdEncoding.EncodeToString(pngBytes)
-			mfaData.Secret = secret
-			mfaData.Qrcodeurl = string(base64Encoded)
-
-			updateData := map[string]interface{}{
-				"doc": map[string]interface{}{
-					"secret":    mfaData.Secret,
-					"qrcodeurl": mfaData.Qrcodeurl,
-				},
-			}
-			payload, _ := json.Marshal(updateData)
-			esClient := dbconfig.Connection()
-
-			res, err := esClient.Update(
-				"users", // Index name
-				id,      // Document ID
-				bytes.NewReader(payload),
-				esClient.Update.WithContext(context.Background()),
-			)
-
-			if err != nil {
-				c.JSON(500, gin.H{"message": "Error updating database"})
+			if !h.guardOTP(c, user, "Invalid OTP code or password, please try again.", proof) {
 				return
 			}
-			defer res.Body.Close()
-
-			if res.IsError() {
-				c.JSON(500, gin.H{"message": fmt.Sprintf("Elasticsearch error: %s", res.String())})
-				return
-			}
-
-			c.JSON(200, gin.H{
-				"qrcodeurl": base64Encoded,
-				"message":   "Multi-Factor Authenticator has been enabled."})
-
-		}
-		log.Print("MFA is enabled..............")
-	} else {
-		log.Print("MFA is disabled...........................")
-		updateData := map[string]interface{}{
-			"script": map[string]interface{}{
-				"source": "ctx._source.secret = null; ctx._source.qrcodeurl = null;",
-				"lang":   "painless",
-			},
 		}
-		payload, _ := json.Marshal(updateData)
-		esClient := dbconfig.Connection()
-
-		res, err := esClient.Update(
-			"users", // Index name
-			id,      // Document ID
-			bytes.NewReader(payload),
-			esClient.Update.WithContext(context.Background()),
-		)
 
+		key, err := totp.Generate(totp.GenerateOpts{
+			Issuer:      "BARCLAYS BANK", // The name of your application
+			AccountName: user.Email,      // The user's account identifier
+		})
 		if err != nil {
-			c.JSON(500, gin.H{"message": "Error updating database"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate TOTP secret"})
 			return
 		}
-		defer res.Body.Close()
+		// The key.Secret() is the base32 encoded secret you must save
+		secret := key.Secret()
+		// The key.URL() is the otpauth URI, which can be converted into a QR code
+		qrCodeURL := key.URL()
 
-		if res.IsError() {
-			c.JSON(500, gin.H{"message": fmt.Sprintf("Elasticsearch error: %s", res.String())})
+		pngBytes, err := qrcode.Encode(qrCodeURL, qrcode.Medium, 256)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to generate QR code: %v", err)})
+			return
+		}
+		// Base64 encode the PNG bytes
+		var mfaData dto.MfaData
+		base64Encoded := base64.StdEncoding.EncodeToString(pngBytes)
+		mfaData.Secret = secret
+		mfaData.Qrcodeurl = base64Encoded
+
+		// Keep the secret pending until MfaVerifyotp confirms the user scanned it
+		err = h.users.Update(ctx, id, map[string]interface{}{
+			"pendingsecret":    mfaData.Secret,
+			"pendingqrcodeurl": mfaData.Qrcodeurl,
+		})
+		if err != nil {
+			c.JSON(500, gin.H{"message": fmt.Sprintf("Error updating database: %s", err)})
 			return
 		}
 
 		c.JSON(200, gin.H{
-			"message": "Multi-Factor Authenticator has been disabled."})
+			"qrcodeurl": base64Encoded,
+			"message":   "Scan the QR code, then enter the OTP code to enable Multi-Factor Authenticator."})
+		return
+	}
 
+	if !h.guardOTP(c, user, "Invalid OTP code or password, please try again.", proof) {
+		return
+	}
+
+	log.Print("MFA is disabled...........................")
+	err = h.users.Update(ctx, id, map[string]interface{}{
+		"secret":           nil,
+		"qrcodeurl":        nil,
+		"pendingsecret":    nil,
+		"pendingqrcodeurl": nil,
+		"recoverycodes":    nil,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(404, gin.H{"message": "User ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"message": fmt.Sprintf("Error updating database: %s", err)})
+		return
 	}
 
+	c.JSON(200, gin.H{
+		"message": "Multi-Factor Authenticator has been disabled."})
 }
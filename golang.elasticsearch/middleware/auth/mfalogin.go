@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary MFA Login Verification
+// @Description Exchange the login challenge token and an OTP or recovery code for a session token. Each challenge token gives a single session.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.MfaChallenge true "Challenge token with OTP or recovery code"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/mfa/verify [post]
+func (h *Handler) MfaLogin(c *gin.Context) {
+	var mfa dto.MfaChallenge
+	if !validation.BindJSON(c, &mfa) {
+		return
+	}
+
+	claims, err := h.keys.VerifyChallengeJWT(mfa.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Your login session has expired, please login again."})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, claims.Subject)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Your login session has expired, please login again."})
+		return
+	}
+
+	valid := h.guardOTP(c, user, "Invalid OTP or recovery code, please try again.", func() (bool, error) {
+		if mfa.RecoveryCode != "" {
+			return h.useRecoveryCode(ctx, user, mfa.RecoveryCode)
+		}
+		return h.checkOTP(ctx, user, mfa.Otp)
+	})
+	if !valid {
+		return
+	}
+
+	// A challenge gives one session; a mistyped code does not use it up
+	err = h.tokens.UseOnce(ctx, claims.ID, claims.ExpiresAt.Time)
+	if errors.Is(err, repository.ErrTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Your login session has expired, please login again."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	h.sessionResponse(c, user)
+}
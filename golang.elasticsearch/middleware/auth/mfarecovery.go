@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"errors"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	utils "golang.elasticsearch/utils"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary MFA Recovery Codes Count
+// @Description Number of unused recovery codes
+// @Tags MultiFactor Authenticator
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User Id"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/mfa/recoverycodes/{id} [get]
+// @Router /api/me/mfa/recoverycodes [get]
+func (h *Handler) MfaRecoveryCount(c *gin.Context) {
+	user, err := h.users.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(404, gin.H{"message": "User ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"remaining": len(user.Recoverycodes)})
+}
+
+// @Summary MFA Recovery Codes Regeneration
+// @Description Replace all recovery codes, the current OTP code is required
+// @Tags MultiFactor Authenticator
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User Id"
+// @Param body body dto.MfaKeys true "Enter OTP Code"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/mfa/recoverycodes/{id} [post]
+// @Router /api/me/mfa/recoverycodes [post]
+func (h *Handler) MfaRecoveryRegenerate(c *gin.Context) {
+	var mfa dto.MfaKeys
+	if !validation.BindJSON(c, &mfa) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(404, gin.H{"message": "User ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if user.Secret == nil {
+		c.JSON(400, gin.H{"message": "Multi-Factor Authenticator is not enabled."})
+		return
+	}
+
+	valid := h.guardOTP(c, user, "Invalid OTP code, please try again.", func() (bool, error) {
+		return h.checkOTP(ctx, user, mfa.Otp)
+	})
+	if !valid {
+		return
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(500, gin.H{"message": "Unable to generate recovery codes."})
+		return
+	}
+	if err := h.users.Update(ctx, user.ID, map[string]interface{}{"recoverycodes": hashes}); err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"recoverycodes": codes,
+		"message":       "New recovery codes have been generated."})
+}
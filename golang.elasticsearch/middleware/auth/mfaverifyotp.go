@@ -1,57 +1,106 @@
 package middleware
 
 import (
-	"net/http"
+	"errors"
 
 	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
 	utils "golang.elasticsearch/utils"
+	"golang.elasticsearch/validation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/pquerna/otp/totp"
 )
 
+// recoveryCodeCount is how many recovery codes are issued at a time.
+const recoveryCodeCount = 10
+
 // @Summary MFA TOTP Verification
-// @Description Multi-Factor Authenticator, OTP verification
+// @Description Multi-Factor Authenticator, OTP verification. The first valid OTP after activation confirms enrollment and returns the recovery codes.
 // @Tags MultiFactor Authenticator
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "User Id"
 // @Param body body dto.MfaKeys true "Enter OTP Code"
-// @Success 200 {array} dto.Users
+// @Success 200 {object} map[string]interface{}
 // @Router /api/mfa/verifytotp/{id} [patch]
-func MfaVerifyotp(c *gin.Context) {
+// @Router /api/me/mfa/verifytotp [patch]
+func (h *Handler) MfaVerifyotp(c *gin.Context) {
 	id := c.Param("id")
 
 	var mfa dto.MfaKeys
-	if err := c.ShouldBindJSON(&mfa); err != nil {
-		c.JSON(400, gin.H{"message": "Invalid request format"})
+	if !validation.BindJSON(c, &mfa) {
 		return
 	}
 
-	user, err := utils.GetUserid(id)
+	user, err := h.users.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(400, gin.H{"message": "User ID not found."})
+		return
+	}
 	if err != nil {
 		c.JSON(400, gin.H{"message": err.Error()})
 		return
 	}
 
-	secret := user[0].Secret
-
-	if len(user) > 0 {
+	if user.Secret == nil && user.Pendingsecret == nil {
+		c.JSON(400, gin.H{"message": "Multi-Factor Authenticator is not enabled."})
+		return
+	}
 
-		valid := totp.Validate(mfa.Otp, *secret)
-		if valid {
-			c.JSON(200, gin.H{
-				"username": user[0].Username,
-				"message":  "OTP code is successfully validated.s"})
-			return
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid OTP code, please try again."})
-			return
+	// The active secret keeps working while a replacement waits for its first
+	// code, so a pending enrollment cannot lock the user out
+	var confirming bool
+	var step int64
+	valid := h.guardOTP(c, user, "Invalid OTP code, please try again.", func() (bool, error) {
+		if user.Pendingsecret != nil {
+			if step, confirming = utils.ValidateTOTP(mfa.Otp, *user.Pendingsecret, 0); confirming {
+				return true, nil
+			}
+		}
+		if user.Secret == nil {
+			return false, nil
 		}
+		return h.checkOTP(c.Request.Context(), user, mfa.Otp)
+	})
+	if !valid {
+		return
+	}
+	if confirming {
+		h.confirmEnrollment(c, user, step)
+		return
+	}
 
-	} else {
-		c.JSON(400, gin.H{"message": "User ID not found."})
+	c.JSON(200, gin.H{
+		"username": user.Username,
+		"message":  "OTP code is successfully validated."})
+}
+
+// confirmEnrollment promotes the pending secret, whose code of the given
+// step the user just entered, and issues a new set of recovery codes.
+func (h *Handler) confirmEnrollment(c *gin.Context, user *models.User, step int64) {
+	codes, hashes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(500, gin.H{"message": "Unable to generate recovery codes."})
+		return
+	}
+
+	err = h.users.Update(c.Request.Context(), user.ID, map[string]interface{}{
+		"secret":           *user.Pendingsecret,
+		"qrcodeurl":        user.Pendingqrcodeurl,
+		"pendingsecret":    nil,
+		"pendingqrcodeurl": nil,
+		"totpstep":         step,
+		"recoverycodes":    hashes,
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
 	}
 
+	c.JSON(200, gin.H{
+		"username":      user.Username,
+		"recoverycodes": codes,
+		"message":       "Multi-Factor Authenticator has been enabled, keep your recovery codes in a safe place."})
 }
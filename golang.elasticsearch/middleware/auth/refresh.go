@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	utils "golang.elasticsearch/utils"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Refresh Session
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token can be used once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.RefreshToken true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	var body dto.RefreshToken
+	if !validation.BindJSON(c, &body) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	expired := gin.H{"message": "Your session has expired, please login again."}
+
+	stored, err := h.tokens.GetRefresh(ctx, utils.HashToken(body.RefreshToken))
+	if err != nil || time.Now().After(stored.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, expired)
+		return
+	}
+
+	revoked, err := h.tokens.IsRevoked(ctx, stored.Family)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, expired)
+		return
+	}
+
+	// Tokens stored before sessions kept their start count from their own
+	// creation, which bounds the session one refresh lifetime from now.
+	started := stored.FamilyStart
+	if started.IsZero() {
+		started = stored.CreatedAt
+	}
+
+	err = h.tokens.UseRefresh(ctx, stored.ID)
+	if errors.Is(err, repository.ErrTokenReused) {
+		// A rotated token came back, so someone else holds a copy of it.
+		// Kill the whole session for both parties.
+		log.Printf("Refresh token reuse detected for user %s, revoking session", stored.UserID)
+		if err := h.tokens.Revoke(ctx, stored.Family, h.sessionEnd(started)); err != nil {
+			log.Printf("Error revoking session: %s", err)
+		}
+		c.JSON(http.StatusUnauthorized, expired)
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	user, err := h.users.Get(ctx, stored.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, expired)
+		return
+	}
+	// The same gates as Login: a session does not survive a block
+	if user.Locked(time.Now()) {
+		accountLocked(c, user)
+		return
+	}
+	if !user.Isactivated {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Please verify your email address before logging in."})
+		return
+	}
+
+	token, refresh, err := h.issueTokens(ctx, user, stored.Family, started)
+	if err != nil {
+		c.JSON(500, gin.H{"message": "Unable to create session token."})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"token":         token,
+		"refresh_token": refresh,
+		"message":       "Session has been refreshed."})
+}
+
+// @Summary User Logout
+// @Description Revoke the current session, its access and refresh tokens stop working
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	value, _ := c.Get("claims")
+	claims, ok := value.(*utils.Claims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized Access."})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.tokens.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if claims.SessionID != "" {
+		err := h.tokens.Revoke(ctx, claims.SessionID, time.Now().UTC().Add(h.lifetimes.Refresh))
+		if err != nil {
+			c.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{"message": "You have been logged out."})
+}
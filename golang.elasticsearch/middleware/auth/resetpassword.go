@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/utils"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Forgot Password
+// @Description Email a password reset link
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.EmailAddress true "Email address"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/password/forgot [post]
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var body dto.EmailAddress
+	if !validation.BindJSON(c, &body) {
+		return
+	}
+
+	// The answer is the same whether or not the address is registered
+	ctx := c.Request.Context()
+	users, _, err := h.users.Search(ctx, repository.UserQuery{Email: strings.ToLower(body.Email), Size: 1})
+	if err == nil && len(users) > 0 {
+		if err := h.sendMailToken(ctx, &users[0], models.MailResetPassword); err != nil {
+			log.Printf("Error sending password reset email to user %s: %s", users[0].ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email address is registered, a password reset link has been sent."})
+}
+
+// @Summary Reset Password
+// @Description Set a new password with the token from the password reset email. Every session of the user is logged out.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.ResetPassword true "Reset token and new password"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/password/reset [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var body dto.ResetPassword
+	if !validation.BindJSON(c, &body) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.findMailToken(ctx, body.Token, models.MailResetPassword)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if user == nil {
+		c.JSON(400, gin.H{"message": "The password reset link is invalid or has expired."})
+		return
+	}
+
+	hashPwd, err := utils.HashPassword(body.Password)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The link reached the mailbox, which proves the address as well
+	consumed, err := h.consumeMailToken(ctx, user, map[string]interface{}{
+		"password":    hashPwd,
+		"isactivated": true,
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if !consumed {
+		c.JSON(400, gin.H{"message": "The password reset link is invalid or has expired."})
+		return
+	}
+
+	// Whoever knew the old password may still hold a session or be racing
+	// the lockout; the new password starts from a clean slate.
+	if err := h.revokeSessions(ctx, user.ID, ""); err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if err := h.throttle.attempts.Reset(ctx, userKey(user.Username), otpKey(user.ID)); err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"username": user.Username,
+		"message":  "Your password has been changed, you can now login."})
+}
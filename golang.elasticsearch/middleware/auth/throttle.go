@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Failed attempts are counted per username, per user for OTP codes and per
+// client IP. Past freeAttempts every new try has to wait a doubling delay, and
+// an account whose own counter reaches lockoutThreshold is blocked for
+// lockoutDuration.
+const (
+	failureWindow    = 15 * time.Minute
+	freeAttempts     = 2
+	maxDelay         = 5 * time.Minute
+	lockoutThreshold = 5
+	lockoutDuration  = 15 * time.Minute
+)
+
+func userKey(username string) string { return "user:" + strings.ToLower(username) }
+func otpKey(userID string) string    { return "otp:" + userID }
+func ipKey(ip string) string         { return "ip:" + ip }
+
+type throttle struct {
+	attempts repository.AttemptStore
+	users    repository.UserRepository
+}
+
+// delay is how long after the last failure the next attempt is allowed.
+func delay(failures int) time.Duration {
+	if failures <= freeAttempts {
+		return 0
+	}
+	d := time.Second << (failures - freeAttempts - 1)
+	if d <= 0 || d > maxDelay {
+		return maxDelay
+	}
+	return d
+}
+
+// wait returns how long the client has to hold off before trying any of the
+// keys again.
+func (t throttle) wait(ctx context.Context, keys ...string) (time.Duration, error) {
+	var longest time.Duration
+	for _, key := range keys {
+		attempt, err := t.attempts.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if d := time.Until(attempt.LastFailure.Add(delay(attempt.Failures))); d > longest {
+			longest = d
+		}
+	}
+	return longest, nil
+}
+
+// fail counts a failed attempt against the account key and the client IP. It
+// locks the user, and reports true, once the account key hits the threshold.
+// user is nil when the username does not exist.
+func (t throttle) fail(ctx context.Context, user *models.User, key string, ip string) (bool, error) {
+	if _, err := t.attempts.Fail(ctx, ipKey(ip), failureWindow); err != nil {
+		return false, err
+	}
+	attempt, err := t.attempts.Fail(ctx, key, failureWindow)
+	if err != nil || user == nil || attempt.Failures < lockoutThreshold {
+		return false, err
+	}
+
+	until := time.Now().UTC().Add(lockoutDuration)
+	err = t.users.Update(ctx, user.ID, map[string]interface{}{
+		"isblocked":   true,
+		"lockeduntil": until,
+	})
+	if err != nil {
+		return false, err
+	}
+	user.Isblocked = true
+	user.Lockeduntil = &until
+	log.Printf("Locked user %s until %s after %d failed attempts", user.ID, until.Format(time.RFC3339), attempt.Failures)
+
+	return true, t.attempts.Reset(ctx, key)
+}
+
+// succeed clears the account key and lifts a lockout that has run out.
+func (t throttle) succeed(ctx context.Context, user *models.User, key string) error {
+	if err := t.attempts.Reset(ctx, key); err != nil {
+		return err
+	}
+	if user.Isblocked && user.Lockeduntil != nil {
+		return t.unlock(ctx, user)
+	}
+	return nil
+}
+
+// unlock clears the block on the user and every counter tied to the account.
+func (t throttle) unlock(ctx context.Context, user *models.User) error {
+	err := t.users.Update(ctx, user.ID, map[string]interface{}{
+		"isblocked":   false,
+		"lockeduntil": nil,
+	})
+	if err != nil {
+		return err
+	}
+	user.Isblocked = false
+	user.Lockeduntil = nil
+	return t.attempts.Reset(ctx, userKey(user.Username), otpKey(user.ID))
+}
+
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"retry_after": seconds,
+		"message":     fmt.Sprintf("Too many failed attempts, please try again in %d seconds.", seconds)})
+}
+
+func accountLocked(c *gin.Context, user *models.User) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"lockeduntil": user.Lockeduntil,
+		"message":     "Your account is locked, please try again later or contact the administrator."})
+}
+
+// guardOTP runs check under the OTP throttle of the user. When the attempt is
+// refused or the code is wrong it writes the response itself and returns false.
+func (h *Handler) guardOTP(c *gin.Context, user *models.User, invalid string, check func() (bool, error)) bool {
+	ctx := c.Request.Context()
+	key := otpKey(user.ID)
+	ip := c.ClientIP()
+
+	wait, err := h.throttle.wait(ctx, key, ipKey(ip))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	if wait > 0 {
+		tooManyAttempts(c, wait)
+		return false
+	}
+	if user.Locked(time.Now()) {
+		accountLocked(c, user)
+		return false
+	}
+
+	valid, err := check()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	if !valid {
+		locked, err := h.throttle.fail(ctx, user, key, ip)
+		switch {
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		case locked:
+			accountLocked(c, user)
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"message": invalid})
+		}
+		return false
+	}
+
+	if err := h.throttle.attempts.Reset(ctx, key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	return true
+}
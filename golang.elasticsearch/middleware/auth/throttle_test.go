@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+)
+
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{freeAttempts, 0},
+		{freeAttempts + 1, time.Second},
+		{freeAttempts + 2, 2 * time.Second},
+		{freeAttempts + 4, 8 * time.Second},
+		{freeAttempts + 20, maxDelay},
+		{freeAttempts + 100, maxDelay},
+	}
+	for _, tt := range tests {
+		if got := delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func newTestThrottle(t *testing.T) (throttle, *models.User) {
+	t.Helper()
+	users := repository.NewMemoryUserRepository()
+	user := &models.User{Username: "Rey", Email: "rey@example.com", Isactivated: true}
+	if _, err := users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return throttle{attempts: repository.NewMemoryAttemptStore(), users: users}, user
+}
+
+func TestThrottleLocksAccount(t *testing.T) {
+	th, user := newTestThrottle(t)
+	ctx := context.Background()
+	key := userKey(user.Username)
+
+	for i := 1; i < lockoutThreshold; i++ {
+		locked, err := th.fail(ctx, user, key, "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked {
+			t.Fatalf("locked after %d failures, threshold is %d", i, lockoutThreshold)
+		}
+	}
+	wait, err := th.wait(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 {
+		t.Errorf("wait = %s after %d failures, want a delay", wait, lockoutThreshold-1)
+	}
+
+	locked, err := th.fail(ctx, user, key, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Fatalf("not locked after %d failures", lockoutThreshold)
+	}
+
+	stored, err := th.users.Get(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Locked(time.Now()) || stored.Locked(time.Now().Add(lockoutDuration+time.Second)) {
+		t.Errorf("stored lock = %v until %v, want %s from now", stored.Isblocked, stored.Lockeduntil, lockoutDuration)
+	}
+
+	// The account counter starts over, the IP keeps its failures
+	if attempt, _ := th.attempts.Get(ctx, key); attempt.Failures != 0 {
+		t.Errorf("account failures = %d after the lockout, want 0", attempt.Failures)
+	}
+	if attempt, _ := th.attempts.Get(ctx, ipKey("192.0.2.1")); attempt.Failures != lockoutThreshold {
+		t.Errorf("ip failures = %d, want %d", attempt.Failures, lockoutThreshold)
+	}
+}
+
+func TestThrottleUnknownUser(t *testing.T) {
+	th, _ := newTestThrottle(t)
+	ctx := context.Background()
+
+	for i := 0; i < lockoutThreshold*2; i++ {
+		locked, err := th.fail(ctx, nil, userKey("nobody"), "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked {
+			t.Fatal("a username that does not exist was locked")
+		}
+	}
+}
+
+func TestThrottleSucceed(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().UTC().Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		lockeduntil *time.Time
+		wantBlocked bool
+	}{
+		{"expired lockout is lifted", &past, false},
+		{"administrative block stays", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th, user := newTestThrottle(t)
+			key := userKey(user.Username)
+			err := th.users.Update(ctx, user.ID, map[string]interface{}{
+				"isblocked":   true,
+				"lockeduntil": tt.lockeduntil,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := th.attempts.Fail(ctx, key, failureWindow); err != nil {
+				t.Fatal(err)
+			}
+
+			user.Isblocked, user.Lockeduntil = true, tt.lockeduntil
+			if err := th.succeed(ctx, user, key); err != nil {
+				t.Fatal(err)
+			}
+
+			stored, err := th.users.Get(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Isblocked != tt.wantBlocked {
+				t.Errorf("blocked = %v, want %v", stored.Isblocked, tt.wantBlocked)
+			}
+			if attempt, _ := th.attempts.Get(ctx, key); attempt.Failures != 0 {
+				t.Errorf("failures = %d after success, want 0", attempt.Failures)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.elasticsearch/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Unlock a user
+// @Description Lift a lockout or an administrative block and clear the failed attempt counters of the account
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User Id"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/users/{id}/unlock [post]
+func (h *Handler) UnlockUser(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := h.throttle.unlock(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"username": user.Username,
+		"message":  "User account has been unlocked."})
+}
+
+// @Summary Unlock a client IP
+// @Description Clear the failed attempt counter of a client IP address
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param ip path string true "Client IP address"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/ips/{ip}/unlock [post]
+func (h *Handler) UnlockIP(c *gin.Context) {
+	ip := c.Param("ip")
+	if err := h.throttle.attempts.Reset(c.Request.Context(), ipKey(ip)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Client IP " + ip + " has been unlocked."})
+}
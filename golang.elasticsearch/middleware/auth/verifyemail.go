@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Email Verification
+// @Description Activate the account with the token from the verification email
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.MailToken true "Verification token"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/verify [post]
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var body dto.MailToken
+	if !validation.BindJSON(c, &body) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.findMailToken(ctx, body.Token, models.MailVerifyEmail)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if user == nil {
+		c.JSON(400, gin.H{"message": "The verification link is invalid or has expired."})
+		return
+	}
+
+	consumed, err := h.consumeMailToken(ctx, user, map[string]interface{}{"isactivated": true})
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if !consumed {
+		c.JSON(400, gin.H{"message": "The verification link is invalid or has expired."})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"username": user.Username,
+		"message":  "Your email address has been verified, you can now login."})
+}
+
+// @Summary Resend Verification Email
+// @Description Send a new verification link to an account that is not activated yet
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.EmailAddress true "Email address"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/verify/resend [post]
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var body dto.EmailAddress
+	if !validation.BindJSON(c, &body) {
+		return
+	}
+
+	// The answer is the same whether or not the address is registered
+	ctx := c.Request.Context()
+	users, _, err := h.users.Search(ctx, repository.UserQuery{Email: strings.ToLower(body.Email), Size: 1})
+	if err == nil && len(users) > 0 && !users[0].Isactivated {
+		if err := h.sendMailToken(ctx, &users[0], models.MailVerifyEmail); err != nil {
+			log.Printf("Error sending verification email to user %s: %s", users[0].ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account is waiting for verification, a new link has been sent."})
+}
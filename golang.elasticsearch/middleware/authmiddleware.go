@@ -3,13 +3,16 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
 	utils "golang.elasticsearch/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(users repository.UserRepository, tokens repository.TokenStore, keys *utils.KeyManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the Authorization header value
 		authHeader := c.GetHeader("Authorization")
@@ -30,17 +33,49 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Extract the token string by trimming the "Bearer " prefix
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		_, err := utils.VerifyJWT(token)
+		claims, err := keys.VerifyJWT(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid Bearer Token."})
 			c.Abort()
 			return
 		}
 
+		// Tokens are revoked one by one on logout, or per session on refresh token reuse
+		revoked, err := tokens.IsRevoked(c.Request.Context(), claims.ID, claims.SessionID)
+		if err != nil || revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid Bearer Token."})
+			c.Abort()
+			return
+		}
+
+		// Resolve the token subject so handlers see the current user document
+		user, err := users.Get(c.Request.Context(), claims.Subject)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid Bearer Token."})
+			c.Abort()
+			return
+		}
+
+		// Blocked or locked out accounts lose access with their open sessions
+		if user.Locked(time.Now()) {
+			c.JSON(http.StatusForbidden, gin.H{"message": "Your account is locked, please try again later or contact the administrator."})
+			c.Abort()
+			return
+		}
+
 		// store the token or relevant user info in the context for handlers
 		c.Set("authToken", token)
+		c.Set("claims", claims)
+		c.Set("user", user)
 
 		// Continue to the next handler
 		c.Next()
 	}
 }
+
+// CurrentUser returns the user resolved by AuthMiddleware, or nil.
+func CurrentUser(c *gin.Context) *models.User {
+	value, _ := c.Get("user")
+	user, _ := value.(*models.User)
+	return user
+}
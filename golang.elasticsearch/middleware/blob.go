@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.elasticsearch/storage"
+)
+
+// ReadFormFile reads the uploaded file of a multipart form field. A body
+// wrapped in http.MaxBytesReader fails with an *http.MaxBytesError.
+func ReadFormFile(c *gin.Context, field string) ([]byte, error) {
+	file, err := c.FormFile(field)
+	if err != nil {
+		return nil, err
+	}
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// ServeBlob answers with the blob stored under key, or a 404 when there is
+// none. cacheControl is sent as the Cache-Control header, and a request
+// carrying the current ETag gets a 304.
+func ServeBlob(c *gin.Context, blobs storage.BlobStore, key string, cacheControl string) {
+	obj, err := blobs.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "File not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer obj.Body.Close()
+
+	header := c.Writer.Header()
+	header.Set("Cache-Control", cacheControl)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if obj.ETag != "" {
+		header.Set("ETag", obj.ETag)
+		if c.GetHeader("If-None-Match") == obj.ETag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	if !obj.ModTime.IsZero() {
+		header.Set("Last-Modified", obj.ModTime.UTC().Format(http.TimeFormat))
+	}
+	header.Set("Content-Type", obj.ContentType)
+	if obj.Size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(c.Writer, obj.Body); err != nil {
+		log.Printf("Error sending %s: %s", key, err)
+	}
+}
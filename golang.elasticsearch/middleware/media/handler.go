@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"strings"
+
+	mw "golang.elasticsearch/middleware"
+	"golang.elasticsearch/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the files kept in the blob store.
+type Handler struct {
+	blobs storage.BlobStore
+}
+
+func NewHandler(blobs storage.BlobStore) *Handler {
+	return &Handler{blobs: blobs}
+}
+
+// @Summary Get Media File
+// @Description Download an uploaded file by its key, such as a product image. Keys change whenever the content does, so responses may be cached indefinitely.
+// @Tags Products
+// @Produce image/jpeg,image/png
+// @Param key path string true "Blob key"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /media/{key} [get]
+func (h *Handler) Serve(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	mw.ServeBlob(c, h.blobs, key, "public, max-age=31536000, immutable")
+}
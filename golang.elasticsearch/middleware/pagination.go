@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+)
+
+// BindPage reads the size and cursor query parameters of a paginated list.
+// On failure it writes a 400 response and returns false.
+func BindPage(c *gin.Context) (repository.PageQuery, bool) {
+	var params dto.Pagination
+	if !validation.BindQuery(c, &params) {
+		return repository.PageQuery{}, false
+	}
+	return repository.PageQuery{Cursor: params.Cursor, Size: params.Size}, true
+}
+
+// WritePage answers with one page of a list under key:
+//
+//	{"<key>": [...], "total": 42, "next_cursor": "..."}
+//
+// next_cursor is null on the last page.
+func WritePage(c *gin.Context, key string, items interface{}, total int64, next string) {
+	c.JSON(http.StatusOK, PageBody(key, items, total, next))
+}
+
+// PageBody is the response of WritePage, for lists that add fields of their
+// own next to the page.
+func PageBody(key string, items interface{}, total int64, next string) gin.H {
+	var cursor interface{}
+	if next != "" {
+		cursor = next
+	}
+	return gin.H{
+		key:           items,
+		"total":       total,
+		"next_cursor": cursor,
+	}
+}
+
+// PageError answers a page that could not be read. A stale or tampered
+// cursor is the client's to fix by starting over.
+func PageError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "The cursor is invalid or has expired, start again from the first page."})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+}
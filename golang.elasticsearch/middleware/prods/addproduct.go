@@ -1,13 +1,14 @@
 package middleware
 
 import (
-	"bytes"
-	"encoding/json"
+	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	dbconfig "golang.elasticsearch/dbconfig"
 	"golang.elasticsearch/dto"
+	mw "golang.elasticsearch/middleware"
 	"golang.elasticsearch/models"
+	"golang.elasticsearch/validation"
 )
 
 // @Summary Add New Product
@@ -19,60 +20,52 @@ import (
 // @Success 201 {object} map[string]interface{} "Successfully created"
 // @Failure 400 {object} map[string]interface{} "Invalid request format"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
-// @Router /api/addproduct [post]
-func AddProduct(c *gin.Context) {
+// @Router /addproduct [post]
+func (h *Handler) AddProduct(c *gin.Context) {
 	var productDto dto.Products
 
-	if err := c.ShouldBindJSON(&productDto); err != nil {
-		c.JSON(400, gin.H{"message": "Invalid request format"})
+	if !validation.BindJSON(c, &productDto) {
 		return
 	}
 
-	esClient := dbconfig.Connection()
-	indexName := "products"
-
-	productModel := &models.Product{
-		Category:       productDto.Category,
-		Descriptions:   productDto.Descriptions,
-		Qty:            productDto.Qty,
-		Unit:           productDto.Unit,
-		Costprice:      productDto.Costprice,
-		Sellprice:      productDto.Sellprice,
-		Saleprice:      productDto.Saleprice,
-		Productpicture: productDto.Productpicture,
-		Alertstocks:    productDto.Alertstocks,
-		Criticalstocks: productDto.Criticalstocks,
-	}
-
-	data, err := json.Marshal(productModel)
-	if err != nil {
-		c.JSON(500, gin.H{"message": "Error marshaling data"})
+	if !h.skuAvailable(c, productDto.Sku, "") {
 		return
 	}
 
-	// Perform the Indexing
-	res, err := esClient.Index(
-		indexName,
-		bytes.NewReader(data),
-		esClient.Index.WithRefresh("wait_for"),
-	)
+	// The starting quantity goes through the stock ledger like every other change
+	now := time.Now().UTC()
+	productModel := newProduct(productDto, now)
 
-	// Handle Elasticsearch Errors
+	ctx := c.Request.Context()
+	id, err := h.products.Create(ctx, &productModel)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Elasticsearch connection error"})
+		c.JSON(500, gin.H{"message": "Failed to index product in Elasticsearch"})
 		return
 	}
 
-	// Always close the body when res is not nil
-	defer res.Body.Close()
-
-	if res.IsError() {
-		c.JSON(500, gin.H{"message": "Failed to index product in Elasticsearch"})
-		return
+	if productDto.Qty > 0 {
+		opening := &models.StockMovement{
+			ProductID: id,
+			Type:      models.MovementReceipt,
+			Quantity:  productDto.Qty,
+			Note:      "Opening balance",
+			CreatedAt: now,
+		}
+		if user := mw.CurrentUser(c); user != nil {
+			opening.UserID = user.ID
+		}
+		if err := h.stock.Move(ctx, opening); err != nil {
+			// Otherwise a retry would leave an empty duplicate behind
+			if delErr := h.products.Delete(ctx, id); delErr != nil {
+				log.Printf("Error deleting product %s after failing to record its opening balance: %s", id, delErr)
+			}
+			stockError(c, err)
+			return
+		}
 	}
 
-	// Only send the success response ONCE at the very end
 	c.JSON(201, gin.H{
+		"id":      id,
 		"message": "New product has been added successfully.",
 	})
 }
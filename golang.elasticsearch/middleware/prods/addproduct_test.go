@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+)
+
+// serve runs handler on a JSON request with the given path parameters,
+// the way the router would.
+func serve(handler gin.HandlerFunc, body string, params ...gin.Param) *httptest.ResponseRecorder {
+	validation.Register()
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	handler(c)
+	return w
+}
+
+// failingStock refuses every movement, as when the ledger index is down.
+type failingStock struct {
+	repository.StockRepository
+}
+
+func (failingStock) Move(ctx context.Context, m *models.StockMovement) error {
+	return errors.New("stock_movements is unavailable")
+}
+
+func TestAddProductOpeningBalance(t *testing.T) {
+	ctx := context.Background()
+	products := repository.NewMemoryProductRepository()
+	stock := repository.NewMemoryStockRepository(products)
+	h := NewHandler(products, nil, stock, nil, nil, "")
+
+	w := serve(h.AddProduct, `{"sku":"W-1","category":"tools","descriptions":"Widget","qty":5}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	found, err := products.BySKU(ctx, []string{"W-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	product := found["W-1"]
+	if product.Qty != 5 {
+		t.Errorf("qty = %v, want 5", product.Qty)
+	}
+	page, err := stock.History(ctx, repository.StockQuery{ProductID: product.ID}, repository.PageQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Type != models.MovementReceipt || page.Items[0].Balance != 5 {
+		t.Errorf("movements = %+v, want the opening receipt of 5", page.Items)
+	}
+}
+
+func TestAddProductOpeningBalanceFails(t *testing.T) {
+	ctx := context.Background()
+	products := repository.NewMemoryProductRepository()
+	h := NewHandler(products, nil, failingStock{}, nil, nil, "")
+
+	body := `{"sku":"W-1","category":"tools","descriptions":"Widget","qty":5}`
+	if w := serve(h.AddProduct, body); w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	// No product is left behind without its stock
+	found, err := products.BySKU(ctx, []string{"W-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("product left behind: %+v", found)
+	}
+
+	// So trying again is not refused as a duplicate SKU
+	h = NewHandler(products, nil, repository.NewMemoryStockRepository(products), nil, nil, "")
+	if w := serve(h.AddProduct, body); w.Code != http.StatusCreated {
+		t.Fatalf("retry status %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"time"
+
+	"golang.elasticsearch/charts"
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/storage"
+)
+
+// Handler serves the product, report and sales chart routes.
+type Handler struct {
+	products repository.ProductRepository
+	sales    repository.SalesRepository
+	stock    repository.StockRepository
+	charts   *charts.Service
+	blobs    storage.BlobStore
+	// logo is the PNG file put at the top of the PDF report.
+	logo string
+}
+
+func NewHandler(products repository.ProductRepository, sales repository.SalesRepository, stock repository.StockRepository, charts *charts.Service, blobs storage.BlobStore, logo string) *Handler {
+	return &Handler{products: products, sales: sales, stock: stock, charts: charts, blobs: blobs, logo: logo}
+}
+
+// newProduct is a product to be created from p, with no stock yet.
+func newProduct(p dto.Products, now time.Time) models.Product {
+	return models.Product{
+		Sku:            p.Sku,
+		Category:       p.Category,
+		Descriptions:   p.Descriptions,
+		Unit:           p.Unit,
+		Costprice:      p.Costprice,
+		Sellprice:      p.Sellprice,
+		Saleprice:      p.Saleprice,
+		Productpicture: p.Productpicture,
+		Alertstocks:    p.Alertstocks,
+		Criticalstocks: p.Criticalstocks,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// productFields are the stored fields an update of p writes. The quantity
+// is left out, it only changes through stock movements.
+func productFields(p dto.Products, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"sku":            p.Sku,
+		"category":       p.Category,
+		"descriptions":   p.Descriptions,
+		"unit":           p.Unit,
+		"costprice":      p.Costprice,
+		"sellprice":      p.Sellprice,
+		"saleprice":      p.Saleprice,
+		"productpicture": p.Productpicture,
+		"alertstocks":    p.Alertstocks,
+		"criticalstocks": p.Criticalstocks,
+		"updated_at":     now,
+	}
+}
+
+func toProductDto(p models.Product) dto.Products {
+	return dto.Products{
+		Id:             p.ID,
+		Sku:            p.Sku,
+		Category:       p.Category,
+		Descriptions:   p.Descriptions,
+		Qty:            p.Qty,
+		Unit:           p.Unit,
+		Costprice:      p.Costprice,
+		Sellprice:      p.Sellprice,
+		Saleprice:      p.Saleprice,
+		Productpicture: p.Productpicture,
+		Images:         toImagesDto(p.Images),
+		Alertstocks:    p.Alertstocks,
+		Criticalstocks: p.Criticalstocks,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
+
+func toImagesDto(i *models.ProductImages) *dto.ProductImages {
+	if i == nil {
+		return nil
+	}
+	return &dto.ProductImages{Original: i.Original, Medium: i.Medium, Thumbnail: i.Thumbnail}
+}
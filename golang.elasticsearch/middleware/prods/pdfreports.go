@@ -1,9 +1,7 @@
 package middleware
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 
@@ -19,12 +17,14 @@ import (
 	"github.com/johnfercher/maroto/v2/pkg/consts/align"
 	"github.com/johnfercher/maroto/v2/pkg/consts/extension"
 	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/core"
 	"github.com/johnfercher/maroto/v2/pkg/props"
-	dbconfig "golang.elasticsearch/dbconfig"
 	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
 )
 
-func ProductPDFReport(c *gin.Context) {
+func (h *Handler) ProductPDFReport(c *gin.Context) {
 	cfg := config.NewBuilder().
 		WithPageNumber(props.PageNumber{
 			Pattern: "Page {current} of {total}",
@@ -48,64 +48,19 @@ func ProductPDFReport(c *gin.Context) {
 		return
 	}
 
-	esClient := dbconfig.Connection()
-
-	// Elasticsearch Query
-	query := map[string]interface{}{
-		"query": map[string]interface{}{
-			"match_all": map[string]interface{}{},
-		},
-		"size": 10000, // Max limit for standard search
-	}
-
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(query); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error encoding query"})
-		return
-	}
-
-	res, err := esClient.Search(
-		esClient.Search.WithContext(context.Background()),
-		esClient.Search.WithIndex("products"), // Ensure this matches your actual index
-		esClient.Search.WithBody(&buf),
-		esClient.Search.WithTrackTotalHits(true),
-	)
+	list, err := h.allProducts(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search request failed", "details": err.Error()})
 		return
 	}
-	defer res.Body.Close()
-
-	if res.IsError() {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Elasticsearch error", "status": res.Status()})
-		return
-	}
-
-	var response struct {
-		Hits struct {
-			Hits []struct {
-				ID      string          `json:"_id"`
-				Source_ json.RawMessage `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-
-	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Decoding error", "details": err.Error()})
-		return
-	}
 
 	var products []dto.Products
-	for _, hit := range response.Hits.Hits {
-		var product dto.Products
-		if err := json.Unmarshal(hit.Source_, &product); err == nil {
-			product.Id = hit.ID
-			products = append(products, product)
-		}
+	for _, p := range list {
+		products = append(products, toProductDto(p))
 	}
 
 	now := time.Now()
-	imgBytes, _ := os.ReadFile("assets/images/logo.png")
+	imgBytes, _ := os.ReadFile(h.logo)
 
 	// Header Rows
 	m.AddRows(
@@ -156,6 +111,11 @@ func ProductPDFReport(c *gin.Context) {
 		))
 	}
 
+	if err := h.addStockAlertRows(c, m); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search request failed", "details": err.Error()})
+		return
+	}
+
 	// Document Generation
 	doc, err := m.Generate()
 	if err != nil {
@@ -168,3 +128,84 @@ func ProductPDFReport(c *gin.Context) {
 	c.Header("Content-Disposition", "attachment; filename=products_report.pdf")
 	c.Data(http.StatusOK, "application/pdf", pdfBytes)
 }
+
+// addStockAlertRows appends the products that reached their critical or
+// alert level, critical ones first and in red.
+func (h *Handler) addStockAlertRows(c *gin.Context, m core.Maroto) error {
+	critical, err := h.productsAtLevel(c.Request.Context(), models.StockCritical)
+	if err != nil {
+		return err
+	}
+	low, err := h.productsAtLevel(c.Request.Context(), models.StockLow)
+	if err != nil {
+		return err
+	}
+
+	m.AddRows(
+		row.New(20).Add(
+			text.NewCol(12, "Stock Alerts", props.Text{
+				Top:   10,
+				Size:  14,
+				Style: fontstyle.Bold,
+				Align: align.Left,
+			}),
+		),
+	)
+
+	if len(critical) == 0 && len(low) == 0 {
+		m.AddRows(text.NewRow(8, "All products are above their alert levels."))
+		return nil
+	}
+
+	m.AddRows(
+		row.New(10).Add(
+			text.NewCol(4, "Product Descriptions", props.Text{Style: fontstyle.Bold, Align: align.Center}),
+			text.NewCol(2, "Level", props.Text{Style: fontstyle.Bold, Align: align.Center}),
+			text.NewCol(2, "Stocks", props.Text{Style: fontstyle.Bold, Align: align.Center}),
+			text.NewCol(2, "Alert", props.Text{Style: fontstyle.Bold, Align: align.Center}),
+			text.NewCol(2, "Critical", props.Text{Style: fontstyle.Bold, Align: align.Center}),
+		),
+	)
+
+	red := &props.Color{Red: 200}
+	for _, group := range []struct {
+		level    string
+		color    *props.Color
+		products []dto.Products
+	}{
+		{models.StockCritical, red, critical},
+		{models.StockLow, nil, low},
+	} {
+		for _, p := range group.products {
+			m.AddRows(row.New(8).Add(
+				text.NewCol(4, p.Descriptions, props.Text{Color: group.color}),
+				text.NewCol(2, group.level, props.Text{Align: align.Center, Color: group.color}),
+				text.NewCol(2, fmt.Sprintf("%.0f", p.Qty), props.Text{Align: align.Center, Color: group.color}),
+				text.NewCol(2, fmt.Sprintf("%.0f", p.Alertstocks), props.Text{Align: align.Center}),
+				text.NewCol(2, fmt.Sprintf("%.0f", p.Criticalstocks), props.Text{Align: align.Center}),
+			))
+		}
+	}
+	return nil
+}
+
+// reportPageSize is how many products the report reads per request.
+const reportPageSize = 500
+
+// allProducts reads every product, a page at a time, so the report is not
+// cut off at the search window.
+func (h *Handler) allProducts(ctx context.Context) ([]models.Product, error) {
+	var list []models.Product
+	pg := repository.PageQuery{Size: reportPageSize}
+	for {
+		page, err := h.products.List(ctx, repository.ProductQuery{}, pg)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, page.Items...)
+		if page.NextCursor == "" {
+			return list, nil
+		}
+		pg.Cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get Product
+// @Description Retrieve a product by its id
+// @Tags Products
+// @Produce json
+// @Param id path string true "Product Id"
+// @Success 200 {object} dto.Products
+// @Router /api/products/{id} [get]
+func (h *Handler) GetProduct(c *gin.Context) {
+	product, err := h.products.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toProductDto(*product))
+}
+
+// @Summary Replace Product
+// @Description Overwrite every field of a product except qty, which only changes through stock movements
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product Id"
+// @Param product body dto.Products true "Product object data"
+// @Success 200 {object} dto.Products
+// @Router /api/products/{id} [put]
+func (h *Handler) ReplaceProduct(c *gin.Context) {
+	var productDto dto.Products
+	if !validation.BindJSON(c, &productDto) {
+		return
+	}
+
+	h.updateProduct(c, productDto)
+}
+
+// @Summary Update Product
+// @Description Change only the product fields present in the request body
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product Id"
+// @Param product body dto.ProductPatch true "Fields to change"
+// @Success 200 {object} dto.Products
+// @Router /api/products/{id} [patch]
+func (h *Handler) PatchProduct(c *gin.Context) {
+	var patch dto.ProductPatch
+	if !validation.BindJSON(c, &patch) {
+		return
+	}
+
+	product, err := h.products.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The rules span several fields, so they are checked on the merged product
+	merged := toProductDto(*product)
+	if !patch.Apply(&merged) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Nothing to update."})
+		return
+	}
+	if !validation.Struct(c, &merged) {
+		return
+	}
+	h.updateProduct(c, merged)
+}
+
+// updateProduct saves every editable field of productDto, stamps UpdatedAt
+// and answers with the stored product. The quantity is left alone, it only
+// changes through stock movements.
+func (h *Handler) updateProduct(c *gin.Context, productDto dto.Products) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	if !h.skuAvailable(c, productDto.Sku, id) {
+		return
+	}
+	err := h.products.Update(ctx, id, productFields(productDto, time.Now().UTC()))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	product, err := h.products.Get(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, toProductDto(*product))
+}
+
+// @Summary Delete Product
+// @Description Remove a product
+// @Tags Products
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product Id"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/products/{id} [delete]
+func (h *Handler) DeleteProduct(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	product, err := h.products.Get(ctx, id)
+	if err == nil {
+		err = h.products.Delete(ctx, id)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if product.Images != nil {
+		h.deleteBlobs(ctx, product.Images.Keys(), nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product has been deleted."})
+}
+
+// skuAvailable checks that no product other than id uses sku. It answers
+// with a 409 and returns false when one does.
+func (h *Handler) skuAvailable(c *gin.Context, sku string, id string) bool {
+	if sku == "" {
+		return true
+	}
+	found, err := h.products.BySKU(c.Request.Context(), []string{sku})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	if other, ok := found[sku]; ok && other.ID != id {
+		c.JSON(http.StatusConflict, gin.H{"message": "SKU is already used by another product."})
+		return false
+	}
+	return true
+}
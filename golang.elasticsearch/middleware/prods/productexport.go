@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/export"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportColumns are the dto.Products fields, so that an exported file can
+// be imported back.
+var exportColumns = []string{
+	"id", "sku", "category", "descriptions", "qty", "unit", "costprice", "sellprice", "saleprice",
+	"productpicture", "alertstocks", "criticalstocks", "created_at", "updated_at",
+}
+
+func exportRow(p models.Product) []interface{} {
+	var picture interface{}
+	if p.Productpicture != nil {
+		picture = *p.Productpicture
+	}
+	return []interface{}{
+		p.ID, p.Sku, p.Category, p.Descriptions, p.Qty, p.Unit, p.Costprice, p.Sellprice, p.Saleprice,
+		picture, p.Alertstocks, p.Criticalstocks,
+		p.CreatedAt.UTC().Format(time.RFC3339), p.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// @Summary Export Products
+// @Description Download every product matching the catalogue search filters as CSV, XLSX or NDJSON. The file is streamed, however large the catalogue.
+// @Tags Products
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
+// @Security BearerAuth
+// @Param format query string false "File format" Enums(csv, xlsx, ndjson) default(csv)
+// @Param q query string false "Search text"
+// @Param category query []string false "Categories, repeat for several" collectionFormat(multi)
+// @Param minprice query number false "Lowest selling price"
+// @Param maxprice query number false "Highest selling price"
+// @Param instock query bool false "Only products with stock on hand"
+// @Param sort query string false "Sort order" Enums(relevance, price_asc, price_desc, name_asc, name_desc, newest) default(relevance)
+// @Success 200 {file} file
+// @Router /api/products/export [get]
+func (h *Handler) ExportProducts(c *gin.Context) {
+	var params dto.ProductExport
+	if !validation.BindQuery(c, &params) {
+		return
+	}
+	var filters dto.ProductSearch
+	if !validation.BindQuery(c, &filters) {
+		return
+	}
+	format := params.Format
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products-%s.%s"`, time.Now().Format("2006-01-02"), format))
+	w, err := export.NewWriter(c.Writer, format, exportColumns)
+	if err == nil {
+		err = h.products.Each(c.Request.Context(), catalogueQuery(filters), func(p models.Product) error {
+			return w.Write(exportRow(p))
+		})
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	// The writers buffer, so a failure on the first page can still be answered
+	if !c.Writer.Written() {
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	log.Printf("Product export cut short: %s", err)
+	c.Abort()
+}
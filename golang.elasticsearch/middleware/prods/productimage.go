@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.elasticsearch/images"
+	mw "golang.elasticsearch/middleware"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImageSize caps an uploaded product picture.
+const maxImageSize = 10 << 20
+
+// imageSizes are the stored sizes of a product picture, each fitting a
+// square box of that many pixels.
+var imageSizes = []struct {
+	name string
+	box  int
+}{
+	{"original", 1600},
+	{"medium", 600},
+	{"thumbnail", 200},
+}
+
+// @Summary Upload Product Image
+// @Description Upload a JPEG, PNG, GIF or WebP picture of the product, up to 10 MB. The type is told from the content, not the file name. The picture is stored without its metadata in three sizes, whose keys are served under /media, and replaces the previous one.
+// @Tags Products
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param image formData file true "Product picture"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Router /api/products/{id}/image [post]
+func (h *Handler) UploadProductImage(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	product, err := h.products.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageSize+1<<20)
+	data, err := mw.ReadFormFile(c, "image")
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) || err == nil && len(data) > maxImageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "The image is larger than 10 MB."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Send the picture as the image form field."})
+		return
+	}
+
+	img, err := images.Decode(data, imageSizes[0].box)
+	switch {
+	case errors.Is(err, images.ErrUnsupported):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"message": err.Error()})
+		return
+	case errors.Is(err, images.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// Keys change with the content, so the served files can be cached for good
+	sum := sha256.Sum256(data)
+	prefix := fmt.Sprintf("products/%s/%x", id, sum[:8])
+	stored := &models.ProductImages{}
+	targets := []*string{&stored.Original, &stored.Medium, &stored.Thumbnail}
+	for i, size := range imageSizes {
+		encoded, contentType, ext, err := images.Encode(images.Fit(img, size.box))
+		if err == nil {
+			*targets[i] = prefix + "-" + size.name + ext
+			err = h.blobs.Put(ctx, *targets[i], encoded, contentType)
+		}
+		if err != nil {
+			// A re-upload of the current picture shares its keys
+			h.deleteBlobs(ctx, stored.Keys()[:i+1], product.Images)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	err = h.products.Update(ctx, id, map[string]interface{}{"images": stored, "updated_at": time.Now()})
+	if err != nil {
+		h.deleteBlobs(ctx, stored.Keys(), product.Images)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if product.Images != nil {
+		h.deleteBlobs(ctx, product.Images.Keys(), stored)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product image has been uploaded.",
+		"images":  toImagesDto(stored),
+	})
+}
+
+// @Summary Delete Product Image
+// @Description Remove the uploaded picture of the product.
+// @Tags Products
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/products/{id}/image [delete]
+func (h *Handler) DeleteProductImage(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	product, err := h.products.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if product.Images == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "The product has no uploaded image."})
+		return
+	}
+
+	err = h.products.Update(ctx, id, map[string]interface{}{"images": nil, "updated_at": time.Now()})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	h.deleteBlobs(ctx, product.Images.Keys(), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product image has been deleted."})
+}
+
+// deleteBlobs removes keys from the blob store, except those still used by
+// keep. The product is already saved by then, so failures are only logged
+// and leave an orphaned file behind.
+func (h *Handler) deleteBlobs(ctx context.Context, keys []string, keep *models.ProductImages) {
+	kept := map[string]bool{}
+	if keep != nil {
+		for _, k := range keep.Keys() {
+			kept[k] = true
+		}
+	}
+	for _, k := range keys {
+		if k == "" || kept[k] {
+			continue
+		}
+		if err := h.blobs.Delete(ctx, k); err != nil {
+			log.Printf("Error deleting product image %s: %s", k, err)
+		}
+	}
+}
@@ -0,0 +1,543 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/export"
+	mw "golang.elasticsearch/middleware"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+const (
+	// maxImportSize caps the uploaded file.
+	maxImportSize = 32 << 20
+	// importBatchSize rows are looked up by SKU and queued together.
+	importBatchSize = 500
+	// maxImportErrors caps the row errors listed in the report.
+	maxImportErrors = 1000
+)
+
+// @Summary Import Products
+// @Description Create or update products from a CSV file, whose header names dto.Products fields, or from NDJSON with one product per line. Rows with the SKU of a stored product update it, other rows create products. Each row is validated on its own and the rejected ones are listed with their line number. With dryrun nothing is written.
+// @Tags Products
+// @Accept text/csv,application/x-ndjson,multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file false "CSV or NDJSON file, instead of the request body"
+// @Param format query string false "File format, when the name or content type does not tell" Enums(csv, ndjson)
+// @Param dryrun query bool false "Only validate"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/products/import [post]
+func (h *Handler) ImportProducts(c *gin.Context) {
+	var params dto.ProductImport
+	if !validation.BindQuery(c, &params) {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	body, name := io.Reader(c.Request.Body), ""
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			importFailed(c, err)
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			importFailed(c, err)
+			return
+		}
+		defer f.Close()
+		body, name = f, file.Filename
+	}
+
+	format := params.Format
+	if format == "" {
+		format = importFormat(name, c.ContentType())
+	}
+	var rows rowReader
+	switch format {
+	case formatCSV:
+		r, err := newCSVRows(body)
+		if err != nil {
+			importFailed(c, err)
+			return
+		}
+		rows = r
+	case formatNDJSON:
+		rows = newNDJSONRows(body)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"message": "Send a CSV or NDJSON file, or set format."})
+		return
+	}
+
+	ctx := c.Request.Context()
+	imp := &productImport{h: h, report: importReport{DryRun: params.DryRun, Errors: []importError{}}}
+	if user := mw.CurrentUser(c); user != nil {
+		imp.userID = user.ID
+	}
+	if !params.DryRun {
+		bulk, err := h.products.Bulk(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		imp.bulk = bulk
+	}
+
+	readErr := imp.run(ctx, rows)
+	if err := imp.close(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error(), "report": imp.report})
+		return
+	}
+	if readErr != nil {
+		// Rows before the failure may already be stored, so the report goes along
+		status := http.StatusBadRequest
+		var tooBig *http.MaxBytesError
+		if errors.As(readErr, &tooBig) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{"message": readErr.Error(), "report": imp.report})
+		return
+	}
+	c.JSON(http.StatusOK, imp.report)
+}
+
+func importFailed(c *gin.Context, err error) {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "The file is larger than 32 MB."})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+}
+
+// importFormat tells the format from the file extension, or else from the
+// content type of the request.
+func importFormat(name, contentType string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return formatCSV
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	}
+	switch contentType {
+	case "text/csv":
+		return formatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return formatNDJSON
+	}
+	return ""
+}
+
+// importReport sums up an import. Rows counts the rows read, Created and
+// Updated those stored and Failed those rejected.
+type importReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Rows    int           `json:"rows"`
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Failed  int           `json:"failed"`
+	Errors  []importError `json:"errors"`
+}
+
+// importError is a rejected row. Line is the line of the file it starts on.
+type importError struct {
+	Line    int                     `json:"line"`
+	Sku     string                  `json:"sku,omitempty"`
+	Message string                  `json:"message"`
+	Errors  []validation.FieldError `json:"errors,omitempty"`
+}
+
+type importRow struct {
+	line    int
+	product dto.Products
+	// present holds the columns the row sets. An update leaves the others,
+	// and the stock when qty is missing, as they are.
+	present map[string]bool
+}
+
+// updateFields are the stored fields an update from the row writes.
+func (row importRow) updateFields(now time.Time) map[string]interface{} {
+	fields := productFields(row.product, now)
+	for name := range fields {
+		if name != "updated_at" && !row.present[name] {
+			delete(fields, name)
+		}
+	}
+	return fields
+}
+
+// presentColumns lists the names of a decoded row.
+func presentColumns[V any](fields map[string]V) map[string]bool {
+	present := make(map[string]bool, len(fields))
+	for name := range fields {
+		present[name] = true
+	}
+	return present
+}
+
+// rowError rejects one row; the rows after it are still read.
+type rowError struct {
+	line    int
+	message string
+}
+
+func (e *rowError) Error() string { return e.message }
+
+// rowReader yields the rows of an import file. next returns io.EOF after the
+// last row, a *rowError for a row that cannot be read, and any other error
+// when the rest of the file cannot be read either.
+type rowReader interface {
+	next() (importRow, error)
+}
+
+// productImport queues the rows of one import in batches and keeps the
+// report. The bulk callbacks run concurrently, hence the lock.
+type productImport struct {
+	h      *Handler
+	bulk   repository.ProductBulk
+	userID string
+
+	batch []importRow
+	skus  map[string]int
+
+	mu     sync.Mutex
+	report importReport
+	stock  []*models.StockMovement
+	lines  map[*models.StockMovement]importRow
+}
+
+func (imp *productImport) run(ctx context.Context, rows rowReader) error {
+	imp.skus = map[string]int{}
+	imp.lines = map[*models.StockMovement]importRow{}
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			return imp.flush(ctx)
+		}
+		var bad *rowError
+		if err != nil && !errors.As(err, &bad) {
+			if flushErr := imp.flush(ctx); flushErr != nil {
+				return flushErr
+			}
+			return err
+		}
+		imp.mu.Lock()
+		imp.report.Rows++
+		imp.mu.Unlock()
+		if bad != nil {
+			imp.fail(importError{Line: bad.line, Message: bad.message})
+			continue
+		}
+
+		if err := binding.Validator.ValidateStruct(&row.product); err != nil {
+			fields := validation.Translate(err)
+			imp.fail(importError{Line: row.line, Sku: row.product.Sku, Message: fields[0].Message, Errors: fields})
+			continue
+		}
+		if sku := row.product.Sku; sku != "" {
+			if first, dup := imp.skus[sku]; dup {
+				imp.fail(importError{Line: row.line, Sku: sku, Message: fmt.Sprintf("SKU already appears on line %d.", first)})
+				continue
+			}
+			imp.skus[sku] = row.line
+		}
+
+		imp.batch = append(imp.batch, row)
+		if len(imp.batch) == importBatchSize {
+			if err := imp.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// flush looks up the SKUs of the batch and queues a create or an update for
+// each row. A dry run only counts them.
+func (imp *productImport) flush(ctx context.Context) error {
+	if len(imp.batch) == 0 {
+		return nil
+	}
+	var skus []string
+	for _, row := range imp.batch {
+		if row.product.Sku != "" {
+			skus = append(skus, row.product.Sku)
+		}
+	}
+	existing, err := imp.h.products.BySKU(ctx, skus)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, row := range imp.batch {
+		current, found := existing[row.product.Sku]
+		if imp.bulk == nil {
+			imp.mu.Lock()
+			if found {
+				imp.report.Updated++
+			} else {
+				imp.report.Created++
+			}
+			imp.mu.Unlock()
+			continue
+		}
+
+		if found {
+			err = imp.bulk.Update(ctx, current.ID, row.updateFields(now), imp.done(row, current))
+		} else {
+			err = imp.bulk.Create(ctx, newProduct(row.product, now), imp.done(row, models.Product{}))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	imp.batch = imp.batch[:0]
+	return nil
+}
+
+// done records the outcome of a stored row and the stock movement that sets
+// its quantity: an opening balance for a new product, an adjustment by the
+// difference for an existing one.
+func (imp *productImport) done(row importRow, current models.Product) func(id string, err error) {
+	return func(id string, err error) {
+		imp.mu.Lock()
+		defer imp.mu.Unlock()
+		if err != nil {
+			imp.failLocked(importError{Line: row.line, Sku: row.product.Sku, Message: err.Error()})
+			return
+		}
+
+		movement := &models.StockMovement{ProductID: id, UserID: imp.userID, Reference: "Import"}
+		if current.ID == "" {
+			imp.report.Created++
+			movement.Type = models.MovementReceipt
+			movement.Quantity = row.product.Qty
+			movement.Note = "Opening balance"
+		} else {
+			imp.report.Updated++
+			movement.Type = models.MovementAdjustment
+			movement.Quantity = row.product.Qty - current.Qty
+			movement.Note = "Stock count"
+		}
+		if row.present["qty"] && movement.Quantity != 0 {
+			imp.stock = append(imp.stock, movement)
+			imp.lines[movement] = row
+		}
+	}
+}
+
+// close waits for the queued writes, then posts the stock movements. They
+// go through the ledger one by one, so that alerts are raised as usual.
+func (imp *productImport) close(ctx context.Context) error {
+	if imp.bulk == nil {
+		return nil
+	}
+	if err := imp.bulk.Close(ctx); err != nil {
+		return err
+	}
+	for _, movement := range imp.stock {
+		movement.CreatedAt = time.Now().UTC()
+		if err := imp.h.stock.Move(ctx, movement); err != nil {
+			row := imp.lines[movement]
+			imp.fail(importError{Line: row.line, Sku: row.product.Sku, Message: "Product saved, but its quantity was not: " + err.Error()})
+		}
+	}
+	return nil
+}
+
+func (imp *productImport) fail(e importError) {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	imp.failLocked(e)
+}
+
+func (imp *productImport) failLocked(e importError) {
+	imp.report.Failed++
+	if len(imp.report.Errors) < maxImportErrors {
+		imp.report.Errors = append(imp.report.Errors, e)
+	}
+}
+
+// readOnlyColumns are set by the server, or by the image upload. An import
+// skips them, so that an export can be imported back.
+var readOnlyColumns = map[string]bool{"id": true, "created_at": true, "updated_at": true, "images": true}
+
+// importColumns maps the JSON names of the importable dto.Products fields to
+// their kind.
+var importColumns = func() map[string]reflect.Kind {
+	columns := map[string]reflect.Kind{}
+	t := reflect.TypeOf(dto.Products{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || readOnlyColumns[name] {
+			continue
+		}
+		kind := t.Field(i).Type.Kind()
+		if kind == reflect.Pointer {
+			kind = t.Field(i).Type.Elem().Kind()
+		}
+		columns[name] = kind
+	}
+	return columns
+}()
+
+type csvRows struct {
+	r       *csv.Reader
+	columns []string
+}
+
+// newCSVRows reads the header, whose names must be dto.Products fields in
+// any case and order.
+func newCSVRows(body io.Reader) (*csvRows, error) {
+	r := csv.NewReader(body)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("The file is empty.")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := importColumns[name]; !ok && !readOnlyColumns[name] {
+			return nil, fmt.Errorf("Unknown column %q.", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("Column %q appears twice.", name)
+		}
+		seen[name] = true
+		if !readOnlyColumns[name] {
+			columns[i] = name
+		}
+	}
+	return &csvRows{r: r, columns: columns}, nil
+}
+
+func (rows *csvRows) next() (importRow, error) {
+	record, err := rows.r.Read()
+	if err == io.EOF {
+		return importRow{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return importRow{}, &rowError{line: parseErr.StartLine, message: parseErr.Err.Error()}
+	}
+	if err != nil {
+		return importRow{}, err
+	}
+
+	line, _ := rows.r.FieldPos(0)
+	fields := map[string]interface{}{}
+	for i, value := range record {
+		name := rows.columns[i]
+		value = strings.TrimSpace(value)
+		if value == "" || name == "" {
+			continue
+		}
+		if importColumns[name] != reflect.Float64 {
+			fields[name] = export.UnescapeFormula(value)
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+		if err != nil {
+			return importRow{}, &rowError{line: line, message: name + " must be a number"}
+		}
+		fields[name] = n
+	}
+
+	row := importRow{line: line, present: presentColumns(fields)}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return importRow{}, err
+	}
+	if err := json.Unmarshal(data, &row.product); err != nil {
+		return importRow{}, &rowError{line: line, message: err.Error()}
+	}
+	return row, nil
+}
+
+type ndjsonRows struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newNDJSONRows(body io.Reader) *ndjsonRows {
+	s := bufio.NewScanner(body)
+	s.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	return &ndjsonRows{s: s}
+}
+
+func (rows *ndjsonRows) next() (importRow, error) {
+	for rows.s.Scan() {
+		rows.line++
+		data := bytes.TrimSpace(rows.s.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return importRow{}, &rowError{line: rows.line, message: "not a JSON object"}
+		}
+		for name := range fields {
+			if readOnlyColumns[name] {
+				delete(fields, name)
+			} else if _, ok := importColumns[name]; !ok {
+				return importRow{}, &rowError{line: rows.line, message: fmt.Sprintf("unknown field %q", name)}
+			}
+		}
+
+		row := importRow{line: rows.line, present: presentColumns(fields)}
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return importRow{}, err
+		}
+		if err := json.Unmarshal(data, &row.product); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				want := "text"
+				if importColumns[typeErr.Field] == reflect.Float64 {
+					want = "a number"
+				}
+				return importRow{}, &rowError{line: rows.line, message: fmt.Sprintf("%s must be %s", typeErr.Field, want)}
+			}
+			return importRow{}, &rowError{line: rows.line, message: err.Error()}
+		}
+		return row, nil
+	}
+	if err := rows.s.Err(); err != nil {
+		return importRow{}, err
+	}
+	return importRow{}, io.EOF
+}
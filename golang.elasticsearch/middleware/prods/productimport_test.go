@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAll collects the rows of r, with the line of each rejected one.
+func readAll(t *testing.T, r rowReader) ([]importRow, []int) {
+	t.Helper()
+	var rows []importRow
+	var rejected []int
+	for {
+		row, err := r.next()
+		if err == io.EOF {
+			return rows, rejected
+		}
+		var bad *rowError
+		if errors.As(err, &bad) {
+			rejected = append(rejected, bad.line)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCSVRows(t *testing.T) {
+	file := "\ufeffSKU, Descriptions,Category,Qty,SellPrice,id,images\n" +
+		`W-1,Widget,tools,"1,200",2.50,99,ignored` + "\n" +
+		"W-2,Gadget,tools,,3,,\n" +
+		"W-3,Broken,tools,many,1,,\n"
+
+	r, err := newCSVRows(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, rejected := readAll(t, r)
+
+	if len(rows) != 2 {
+		t.Fatalf("read %d rows, want 2", len(rows))
+	}
+	first := rows[0]
+	if first.line != 2 || first.product.Sku != "W-1" || first.product.Qty != 1200 || first.product.Sellprice != 2.5 {
+		t.Errorf("first row = line %d %+v", first.line, first.product)
+	}
+	if first.product.Id != "" || first.product.Images != nil {
+		t.Errorf("read-only columns were read: id %q, images %v", first.product.Id, first.product.Images)
+	}
+	if rows[1].present["qty"] {
+		t.Error("an empty qty cell counts as present")
+	}
+	if !slices.Equal(rejected, []int{4}) {
+		t.Errorf("rejected lines %v, want [4]", rejected)
+	}
+}
+
+func TestCSVHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"empty file", ""},
+		{"unknown column", "sku,colour\n"},
+		{"column twice", "sku,SKU\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newCSVRows(strings.NewReader(tt.header)); err == nil {
+				t.Error("header accepted")
+			}
+		})
+	}
+}
+
+func TestNDJSONRows(t *testing.T) {
+	file := `{"sku":"W-1","descriptions":"Widget","category":"tools","qty":3,"images":{"original":"x"}}` + "\n" +
+		"\n" +
+		`{"sku":"W-2","colour":"red"}` + "\n" +
+		`{"sku":"W-3","qty":"three"}` + "\n" +
+		"[1,2]\n" +
+		`{"sku":"W-4","sellprice":9}` + "\n"
+
+	rows, rejected := readAll(t, newNDJSONRows(strings.NewReader(file)))
+
+	if len(rows) != 2 {
+		t.Fatalf("read %d rows, want 2", len(rows))
+	}
+	if rows[0].line != 1 || rows[0].product.Qty != 3 || rows[0].product.Images != nil {
+		t.Errorf("first row = line %d %+v", rows[0].line, rows[0].product)
+	}
+	if rows[1].line != 6 || rows[1].product.Sellprice != 9 {
+		t.Errorf("last row = line %d %+v", rows[1].line, rows[1].product)
+	}
+	if !slices.Equal(rejected, []int{3, 4, 5}) {
+		t.Errorf("rejected lines %v, want [3 4 5]", rejected)
+	}
+}
+
+func TestUpdateFields(t *testing.T) {
+	rows, _ := readAll(t, newNDJSONRows(strings.NewReader(`{"sku":"W-1","sellprice":4}`)))
+	if len(rows) != 1 {
+		t.Fatalf("read %d rows, want 1", len(rows))
+	}
+
+	got := slices.Sorted(maps.Keys(rows[0].updateFields(time.Now())))
+	if want := []string{"sellprice", "sku", "updated_at"}; !slices.Equal(got, want) {
+		t.Errorf("update writes %v, want %v", got, want)
+	}
+}
+
+func TestImportFormat(t *testing.T) {
+	tests := []struct {
+		name, contentType, want string
+	}{
+		{"products.CSV", "", formatCSV},
+		{"products.jsonl", "text/csv", formatNDJSON},
+		{"", "text/csv", formatCSV},
+		{"", "application/x-ndjson", formatNDJSON},
+		{"products.txt", "text/plain", ""},
+	}
+	for _, tt := range tests {
+		if got := importFormat(tt.name, tt.contentType); got != tt.want {
+			t.Errorf("importFormat(%q, %q) = %q, want %q", tt.name, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestCSVUnescapesFormulas(t *testing.T) {
+	// As written by the CSV export
+	file := "sku,descriptions,category\nW-1,'=1+1,'tools\n"
+	r, err := newCSVRows(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := readAll(t, r)
+	if len(rows) != 1 {
+		t.Fatalf("read %d rows, want 1", len(rows))
+	}
+	if p := rows[0].product; p.Descriptions != "=1+1" || p.Category != "'tools" {
+		t.Errorf("read descriptions %q and category %q", p.Descriptions, p.Category)
+	}
+}
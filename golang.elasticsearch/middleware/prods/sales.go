@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.elasticsearch/dto"
+	mw "golang.elasticsearch/middleware"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Post Sale
+// @Description Record a sale of one or more products. Each unit price is the product's sale price, or its sell price when it has none, the total is computed by the server and the sold quantities are issued from stock.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param sale body dto.Sales true "Products sold"
+// @Success 201 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{} "Unknown product"
+// @Failure 409 {object} map[string]interface{} "Insufficient stock"
+// @Router /api/sales [post]
+func (h *Handler) PostSale(c *gin.Context) {
+	var salesDto dto.Sales
+	if !validation.BindJSON(c, &salesDto) {
+		return
+	}
+
+	now := time.Now().UTC()
+	sale := &models.Sale{
+		Salesdate: now.Truncate(24 * time.Hour),
+		CreatedAt: now,
+	}
+	if salesDto.Salesdate != "" {
+		// Already checked by the isodate rule
+		sale.Salesdate, _ = time.Parse("2006-01-02", salesDto.Salesdate)
+	}
+	if user := mw.CurrentUser(c); user != nil {
+		sale.UserID = user.ID
+	}
+
+	ctx := c.Request.Context()
+	for _, item := range salesDto.Items {
+		product, err := h.products.Get(ctx, item.ProductID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Product ID %s not found.", item.ProductID)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		price := product.Saleprice
+		if price <= 0 {
+			price = product.Sellprice
+		}
+		line := models.SaleItem{
+			ProductID:    product.ID,
+			Descriptions: product.Descriptions,
+			Category:     product.Category,
+			Qty:          item.Qty,
+			Unitprice:    price,
+			Amount:       repository.RoundCents(item.Qty * price),
+		}
+		sale.Items = append(sale.Items, line)
+		sale.Amount += line.Amount
+	}
+	sale.Amount = repository.RoundCents(sale.Amount)
+
+	if _, err := h.sales.Create(ctx, sale); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to index sales"})
+		return
+	}
+
+	if item, err := h.issueSale(ctx, sale); err != nil {
+		if delErr := h.sales.Delete(ctx, sale.ID); delErr != nil {
+			log.Printf("Error deleting sale %s after failing to issue its stock: %s", sale.ID, delErr)
+		}
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{"message": fmt.Sprintf("Insufficient stock of %s.", item.Descriptions)})
+			return
+		}
+		stockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"sale":    sale,
+		"message": "New Sales has been added successfully."})
+}
+
+// issueSale takes the sold quantities out of stock. If one of them cannot be
+// issued, the quantities already taken are put back and the failing item is
+// returned with the error.
+func (h *Handler) issueSale(ctx context.Context, sale *models.Sale) (models.SaleItem, error) {
+	reference := "Sale " + sale.ID
+	for i, item := range sale.Items {
+		err := h.stock.Move(ctx, &models.StockMovement{
+			ProductID: item.ProductID,
+			Type:      models.MovementIssue,
+			Quantity:  item.Qty,
+			Reference: reference,
+			UserID:    sale.UserID,
+			CreatedAt: sale.CreatedAt,
+		})
+		if err == nil {
+			continue
+		}
+
+		for _, issued := range sale.Items[:i] {
+			undo := &models.StockMovement{
+				ProductID: issued.ProductID,
+				Type:      models.MovementAdjustment,
+				Quantity:  issued.Qty,
+				Reference: reference,
+				Note:      "Sale cancelled",
+				UserID:    sale.UserID,
+				CreatedAt: time.Now().UTC(),
+			}
+			if undoErr := h.stock.Move(ctx, undo); undoErr != nil {
+				log.Printf("Error returning %v of product %s to stock: %s", issued.Qty, issued.ProductID, undoErr)
+			}
+		}
+		return item, err
+	}
+	return models.SaleItem{}, nil
+}
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+)
+
+// saleFixture is a handler over memory repositories holding the products
+// of the sales tests.
+type saleFixture struct {
+	products repository.ProductRepository
+	sales    repository.SalesRepository
+	stock    repository.StockRepository
+	handler  *Handler
+	// widget, gadget and bolt ids
+	widget, gadget, bolt string
+}
+
+func newSaleFixture(t *testing.T) *saleFixture {
+	t.Helper()
+	f := &saleFixture{
+		products: repository.NewMemoryProductRepository(),
+		sales:    repository.NewMemorySalesRepository(),
+	}
+	f.stock = repository.NewMemoryStockRepository(f.products)
+	f.handler = NewHandler(f.products, f.sales, f.stock, nil, nil, "")
+
+	create := func(p models.Product) string {
+		id, err := f.products.Create(context.Background(), &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	f.widget = create(models.Product{Descriptions: "Widget", Category: "tools", Qty: 10, Sellprice: 2.5})
+	// On sale, below its sell price
+	f.gadget = create(models.Product{Descriptions: "Gadget", Category: "tools", Qty: 5, Sellprice: 20, Saleprice: 15.99})
+	f.bolt = create(models.Product{Descriptions: "Bolt", Category: "parts", Qty: 1, Sellprice: 0.1})
+	return f
+}
+
+func (f *saleFixture) qty(t *testing.T, id string) float64 {
+	t.Helper()
+	product, err := f.products.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product.Qty
+}
+
+func (f *saleFixture) saleCount(t *testing.T) int64 {
+	t.Helper()
+	_, total, err := f.sales.Search(context.Background(), repository.SalesQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return total
+}
+
+func TestPostSaleTotals(t *testing.T) {
+	f := newSaleFixture(t)
+
+	// Prices and amounts sent by the client are ignored
+	body := `{"salesdate":"2026-03-14","items":[
+		{"product_id":"` + f.widget + `","qty":4,"unitprice":0.01,"amount":0.04},
+		{"product_id":"` + f.gadget + `","qty":3},
+		{"product_id":"` + f.bolt + `","qty":1}
+	],"amount":1}`
+	w := serve(f.handler.PostSale, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var res struct {
+		Sale models.Sale `json:"sale"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	sale := res.Sale
+
+	var prices, amounts []float64
+	for _, item := range sale.Items {
+		prices = append(prices, item.Unitprice)
+		amounts = append(amounts, item.Amount)
+	}
+	if want := []float64{2.5, 15.99, 0.1}; !slices.Equal(prices, want) {
+		t.Errorf("unit prices = %v, want %v", prices, want)
+	}
+	if want := []float64{10, 47.97, 0.1}; !slices.Equal(amounts, want) {
+		t.Errorf("amounts = %v, want %v", amounts, want)
+	}
+	if sale.Amount != 58.07 {
+		t.Errorf("total = %v, want 58.07", sale.Amount)
+	}
+	if !sale.Salesdate.Equal(time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("sales date = %s", sale.Salesdate)
+	}
+	if sale.Items[1].Descriptions != "Gadget" || sale.Items[2].Category != "parts" {
+		t.Errorf("items = %+v", sale.Items)
+	}
+
+	// The stored sale is the one answered
+	stored, err := f.sales.Get(context.Background(), sale.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Amount != sale.Amount || len(stored.Items) != 3 {
+		t.Errorf("stored sale = %+v", stored)
+	}
+}
+
+func TestPostSaleIssuesStock(t *testing.T) {
+	f := newSaleFixture(t)
+
+	body := `{"items":[{"product_id":"` + f.widget + `","qty":4},{"product_id":"` + f.bolt + `","qty":1}]}`
+	w := serve(f.handler.PostSale, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if got := f.qty(t, f.widget); got != 6 {
+		t.Errorf("widget qty = %v, want 6", got)
+	}
+	if got := f.qty(t, f.bolt); got != 0 {
+		t.Errorf("bolt qty = %v, want 0", got)
+	}
+
+	page, err := f.stock.History(context.Background(), repository.StockQuery{ProductID: f.widget}, repository.PageQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Fatalf("widget movements = %+v, want one issue", page.Items)
+	}
+	if m := page.Items[0]; m.Type != models.MovementIssue || m.Quantity != 4 || m.Reference == "" {
+		t.Errorf("movement = %+v", m)
+	}
+}
+
+// failingFor fails the movements of one product with err.
+type failingFor struct {
+	repository.StockRepository
+	productID string
+	err       error
+}
+
+func (s failingFor) Move(ctx context.Context, m *models.StockMovement) error {
+	if m.ProductID == s.productID {
+		return s.err
+	}
+	return s.StockRepository.Move(ctx, m)
+}
+
+func TestPostSaleRollsBack(t *testing.T) {
+	tests := []struct {
+		name   string
+		qty    string
+		stock  func(f *saleFixture) repository.StockRepository
+		status int
+	}{
+		{
+			name:   "not enough stock",
+			qty:    "2",
+			stock:  func(f *saleFixture) repository.StockRepository { return f.stock },
+			status: http.StatusConflict,
+		},
+		{
+			name: "ledger unavailable",
+			qty:  "1",
+			stock: func(f *saleFixture) repository.StockRepository {
+				return failingFor{f.stock, f.bolt, errors.New("stock_movements is unavailable")}
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "product updated concurrently",
+			qty:  "1",
+			stock: func(f *saleFixture) repository.StockRepository {
+				return failingFor{f.stock, f.bolt, repository.ErrConflict}
+			},
+			status: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSaleFixture(t)
+			h := NewHandler(f.products, f.sales, tt.stock(f), nil, nil, "")
+
+			// The bolt, last, fails once the widget and gadget are issued
+			body := `{"items":[
+				{"product_id":"` + f.widget + `","qty":4},
+				{"product_id":"` + f.gadget + `","qty":2},
+				{"product_id":"` + f.bolt + `","qty":` + tt.qty + `}
+			]}`
+			w := serve(h.PostSale, body)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			for id, want := range map[string]float64{f.widget: 10, f.gadget: 5, f.bolt: 1} {
+				if got := f.qty(t, id); got != want {
+					t.Errorf("qty of %s = %v, want %v", id, got, want)
+				}
+			}
+			if n := f.saleCount(t); n != 0 {
+				t.Errorf("%d sales left behind", n)
+			}
+
+			// The ledger shows the issue and its reversal
+			page, err := f.stock.History(context.Background(), repository.StockQuery{ProductID: f.widget}, repository.PageQuery{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var types []string
+			for _, m := range page.Items {
+				types = append(types, m.Type)
+			}
+			if want := []string{models.MovementAdjustment, models.MovementIssue}; !slices.Equal(types, want) {
+				t.Errorf("widget movements newest first = %v, want %v", types, want)
+			}
+		})
+	}
+}
+
+func TestPostSaleUnknownProduct(t *testing.T) {
+	f := newSaleFixture(t)
+
+	body := `{"items":[{"product_id":"` + f.widget + `","qty":1},{"product_id":"missing","qty":1}]}`
+	if w := serve(f.handler.PostSale, body); w.Code != http.StatusNotFound {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	// Nothing was stored or issued
+	if got := f.qty(t, f.widget); got != 10 {
+		t.Errorf("widget qty = %v, want 10", got)
+	}
+	if n := f.saleCount(t); n != 0 {
+		t.Errorf("%d sales stored", n)
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Sales Analytics
+// @Description Sales totals per day, week, month, quarter or year, optionally split by product category. Without dates the current year is summarized. Sale dates are stored as UTC calendar days, so the buckets are cut at midnight UTC and there is no time zone parameter; a range may hold at most 500 of them.
+// @Tags Products
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Param interval query string false "Bucket size" Enums(day, week, month, quarter, year) default(month)
+// @Param groupby query string false "Split each bucket" Enums(category)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/sales/analytics [get]
+func (h *Handler) GetSalesAnalytics(c *gin.Context) {
+	var params dto.SalesAnalytics
+	if !validation.BindQuery(c, &params) {
+		return
+	}
+
+	interval := params.Interval
+	if interval == "" {
+		interval = repository.IntervalMonth
+	}
+	from, to, ok := dateRange(c, params.From, params.To)
+	if !ok {
+		return
+	}
+
+	buckets, err := h.sales.Summarize(c.Request.Context(), repository.SalesSummaryQuery{
+		From:       from,
+		To:         to,
+		Interval:   interval,
+		ByCategory: params.GroupBy == "category",
+	})
+	if errors.Is(err, repository.ErrTooManyBuckets) {
+		tooManyBuckets(c, interval)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	var total float64
+	var count int64
+	for _, b := range buckets {
+		total += b.Amount
+		count += b.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from.Format("2006-01-02"),
+		"to":       to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"interval": interval,
+		"total":    repository.RoundCents(total),
+		"count":    count,
+		"buckets":  buckets,
+	})
+}
+
+// dateRange turns the inclusive from and to dates, already checked by the
+// isodate rule, into the [from, to) range of sale dates they cover. Sale
+// dates are stored as midnight UTC, so the range is in UTC. Without dates it
+// is the current year, with one of them the year starting or ending there.
+func dateRange(c *gin.Context, fromDate, toDate string) (time.Time, time.Time, bool) {
+	var from, to time.Time
+	if fromDate != "" {
+		from, _ = time.Parse("2006-01-02", fromDate)
+	}
+	if toDate != "" {
+		to, _ = time.Parse("2006-01-02", toDate)
+		to = to.AddDate(0, 0, 1)
+	}
+
+	switch {
+	case from.IsZero() && to.IsZero():
+		from = time.Date(time.Now().UTC().Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(1, 0, 0)
+	case from.IsZero():
+		from = to.AddDate(-1, 0, 0)
+	case to.IsZero():
+		to = from.AddDate(1, 0, 0)
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "to must not be before from."})
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// tooManyBuckets answers a range split into more intervals than a summary
+// holds.
+func tooManyBuckets(c *gin.Context, interval string) {
+	c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("The range holds more than %d %s intervals, pick a shorter range or a longer interval.", repository.MaxSalesBuckets, interval)})
+}
@@ -2,122 +2,174 @@ package middleware
 
 import (
 	"bytes"
-	"encoding/json"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/png"
+	"errors"
+	"fmt"
 	"net/http"
-	"os"
-
-	"context"
+	"sort"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/wcharczuk/go-chart/v2"
-	dbconfig "golang.elasticsearch/dbconfig"
-	"golang.elasticsearch/models"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
+	"golang.elasticsearch/charts"
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
 )
 
-func GetSalesChart(c *gin.Context) {
-	esClient := dbconfig.Connection()
-	res, err := esClient.Search(
-		esClient.Search.WithContext(context.Background()),
-		esClient.Search.WithIndex("sales"),
-		esClient.Search.WithSort("salesdate:asc"),
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ES search failed"})
+var intervalTitles = map[string]string{
+	repository.IntervalDay:     "Daily",
+	repository.IntervalWeek:    "Weekly",
+	repository.IntervalMonth:   "Monthly",
+	repository.IntervalQuarter: "Quarterly",
+	repository.IntervalYear:    "Yearly",
+}
+
+// @Summary Sales Chart
+// @Description Sales per interval drawn as a bar chart by default. Without a year or dates the current year is charted.
+// @Tags Products
+// @Produce png
+// @Param year query int false "Calendar year, instead of from and to"
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Param interval query string false "Bucket size" Enums(day, week, month, quarter, year) default(month)
+// @Param type query string false "Chart type" Enums(bar, line, pie, stacked) default(bar)
+// @Param format query string false "Image format" Enums(png, svg) default(png)
+// @Param width query int false "Width in pixels" default(1280)
+// @Param height query int false "Height in pixels" default(512)
+// @Param logo query bool false "Draw the logo above the chart" default(true)
+// @Success 200 {file} file
+// @Router /sales/barchart [get]
+func (h *Handler) GetSalesChart(c *gin.Context) {
+	h.salesChart(c, charts.Options{Type: charts.TypeBar, Width: 1280, Height: 512})
+}
+
+// salesChart answers a chart request, using defaults for what the query
+// string leaves out.
+func (h *Handler) salesChart(c *gin.Context, defaults charts.Options) {
+	var params dto.SalesChart
+	if !validation.BindQuery(c, &params) {
 		return
 	}
-	defer res.Body.Close()
 
-	var response struct {
-		Hits struct {
-			Hits []struct {
-				Source json.RawMessage `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
+	opts := charts.Options{
+		Type:   params.Type,
+		Format: params.Format,
+		Width:  params.Width,
+		Height: params.Height,
+		Logo:   params.Logo == nil || *params.Logo,
+	}
+	if opts.Type == "" {
+		opts.Type = defaults.Type
+	}
+	if opts.Format == "" {
+		opts.Format = charts.FormatPNG
+	}
+	if opts.Width == 0 {
+		opts.Width = defaults.Width
+	}
+	if opts.Height == 0 {
+		opts.Height = defaults.Height
 	}
 
-	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-		c.Status(http.StatusInternalServerError)
+	interval := params.Interval
+	if interval == "" {
+		interval = repository.IntervalMonth
+	}
+	from, to, ok := dateRange(c, params.From, params.To)
+	if !ok {
 		return
 	}
-	monthKeys := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
-	salesMap := make(map[string]float64)
-
-	for _, hit := range response.Hits.Hits {
-		var sale models.Sale
-		if err := json.Unmarshal(hit.Source, &sale); err == nil {
-			month := sale.Salesdate.Format("Jan")
-			salesMap[month] += sale.Amount
-
-		}
+	if params.Year != 0 {
+		from = time.Date(params.Year, 1, 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(1, 0, 0)
 	}
 
-	var chartValues []chart.Value
-	for _, m := range monthKeys {
-		chartValues = append(chartValues, chart.Value{
-			Label: m,
-			Value: salesMap[m], // Defaults to 0.0 if month is missing in salesMap
-		})
+	buckets, err := h.sales.Summarize(c.Request.Context(), repository.SalesSummaryQuery{
+		From:       from,
+		To:         to,
+		Interval:   interval,
+		ByCategory: opts.Type == charts.TypeStacked,
+	})
+	if errors.Is(err, repository.ErrTooManyBuckets) {
+		tooManyBuckets(c, interval)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ES search failed"})
+		return
 	}
 
-	graph := chart.BarChart{
-		Title: "Monthly Sales Report",
-		Background: chart.Style{
-			Padding: chart.Box{Top: 20, Bottom: 20},
-		},
-		Height:   512,
-		Width:    1280,
-		BarWidth: 60,
-		Bars:     chartValues,
-		YAxis: chart.YAxis{
-			// Style: chart.Style{
-			// 	TextRotationDegrees: -90.0, // Rotates labels sideways
-			// },
-			ValueFormatter: commaFormatter,
-		},
+	switch opts.Type {
+	case charts.TypePie:
+		opts.Title = intervalTitles[interval] + " Sales Distribution"
+	case charts.TypeStacked:
+		opts.Title = intervalTitles[interval] + " Sales by Category"
+	default:
+		opts.Title = intervalTitles[interval] + " Sales Report"
 	}
 
-	// 2. Render chart to a temporary buffer
-	buffer := bytes.NewBuffer([]byte{})
-	if err := graph.Render(chart.PNG, buffer); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to render chart"})
+	var image bytes.Buffer
+	err = h.charts.Render(&image, chartData(buckets, interval, from.Year() != to.AddDate(0, 0, -1).Year()), opts)
+	if errors.Is(err, charts.ErrNoData) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "No sales in this period."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render chart"})
 		return
 	}
-	chartImg, _, _ := image.Decode(buffer)
-
-	// 3. Load your Logo
-	logoFile, _ := os.Open("assets/images/logo.png") // Ensure path is correct
-	defer logoFile.Close()
-	logoImg, _, _ := image.Decode(logoFile)
-
-	// 4. Create a combined canvas
-	// Height = Logo Height + Gap + Chart Height
-	logoHeight := 60
-	gap := 10
-	combinedHeight := logoHeight + gap + graph.Height
-	canvas := image.NewRGBA(image.Rect(0, 0, graph.Width, combinedHeight))
 
-	// Draw Background (White)
-	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	c.Data(http.StatusOK, charts.ContentType(opts.Format), image.Bytes())
+}
 
-	// 5. Place Logo (centered at the top)
-	logoX := (graph.Width - logoImg.Bounds().Dx()) / 2
-	draw.Draw(canvas, image.Rect(logoX, 0, logoX+logoImg.Bounds().Dx(), logoHeight), logoImg, image.Point{}, draw.Over)
+// chartData lays the buckets out as a "Sales" series, followed by one series
+// per category when the buckets were split by category.
+func chartData(buckets []repository.SalesBucket, interval string, manyYears bool) charts.Data {
+	data := charts.Data{Series: []charts.Series{{Name: "Sales"}}}
+	categories := map[string]bool{}
+	for _, b := range buckets {
+		data.Labels = append(data.Labels, bucketLabel(b.Start, interval, manyYears))
+		data.Series[0].Values = append(data.Series[0].Values, b.Amount)
+		for name := range b.Categories {
+			categories[name] = true
+		}
+	}
 
-	// 6. Place Chart (below the logo)
-	draw.Draw(canvas, image.Rect(0, logoHeight+gap, graph.Width, combinedHeight), chartImg, image.Point{}, draw.Over)
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		series := charts.Series{Name: name}
+		for _, b := range buckets {
+			series.Values = append(series.Values, b.Categories[name])
+		}
+		data.Series = append(data.Series, series)
+	}
 
-	// 7. Write final image to response
-	c.Header("Content-Type", "image/png")
-	png.Encode(c.Writer, canvas)
+	// A stacked chart shows the categories only
+	if len(names) > 0 {
+		data.Series = data.Series[1:]
+	}
+	return data
 }
 
-func commaFormatter(v interface{}) string {
-	p := message.NewPrinter(language.English)
-	return p.Sprintf("%.2f", v)
+// bucketLabel names the interval starting at start, with the year when the
+// chart covers more than one.
+func bucketLabel(start time.Time, interval string, withYear bool) string {
+	var label string
+	switch interval {
+	case repository.IntervalDay, repository.IntervalWeek:
+		label = start.Format("Jan 02")
+	case repository.IntervalQuarter:
+		label = fmt.Sprintf("Q%d", (int(start.Month())+2)/3)
+	case repository.IntervalYear:
+		return start.Format("2006")
+	default:
+		label = start.Format("Jan")
+	}
+	if withYear {
+		label += start.Format(" 2006")
+	}
+	return label
 }
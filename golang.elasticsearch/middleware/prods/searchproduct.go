@@ -1,112 +1,92 @@
 package middleware
 
 import (
-	"context"
-	"encoding/json"
-	"math"
-	"strconv"
+	"net/http"
 	"strings"
 
-	dbconfig "golang.elasticsearch/dbconfig"
 	"golang.elasticsearch/dto"
+	mw "golang.elasticsearch/middleware"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
-// @Summary Products Wild Cards Search
-// @Description Product Search with pagination
+// @Summary Product Catalogue Search
+// @Description Full text search over descriptions and category that tolerates typos, with price and stock filters, category facets and highlighted matches. Facet counts ignore the category filter so other categories stay selectable. Pass the next_cursor of a page to get the one after it.
 // @Tags Products
-// @Accept json
 // @Produce json
-// @Param page path int true "Page number"
-// @Param key path string true "Key string"
-// @Success 200 {array} dto.Products
-// @Router /products/search/{page}/{key} [get]
-func ProductSearch(c *gin.Context) {
-	pageStr := c.Param("page")
-	param1 := c.Param("key")
-	key := "*" + strings.ToLower(param1) + "*"
-
-	// 1. Setup Pagination variables
-	perPage := 5
-
-	page, _ := strconv.Atoi(pageStr)
-	if page < 1 {
-		page = 1
-	}
-	from := (page - 1) * perPage
-
-	// 2. Initialize Elasticsearch client
-	esClient := dbconfig.Connection()
-
-	indexName := "products"
-
-	// 3. Define the Search Query (Match query for descriptions)
-	query := map[string]interface{}{
-		"from": from,
-		"size": perPage,
-		"query": map[string]interface{}{
-			"match": map[string]interface{}{
-				"descriptions": key,
-				// "case_insensitive": true,
-			},
-		},
+// @Param q query string false "Search text"
+// @Param category query []string false "Categories, repeat for several" collectionFormat(multi)
+// @Param minprice query number false "Lowest selling price"
+// @Param maxprice query number false "Highest selling price"
+// @Param instock query bool false "Only products with stock on hand"
+// @Param sort query string false "Sort order" Enums(relevance, price_asc, price_desc, name_asc, name_desc, newest) default(relevance)
+// @Param size query int false "Products per page" default(20)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Router /products/search [get]
+func (h *Handler) CatalogueSearch(c *gin.Context) {
+	var params dto.ProductSearch
+	if !validation.BindQuery(c, &params) {
+		return
 	}
-
-	// Encode query to JSON
-	var buf strings.Builder
-	if err := json.NewEncoder(&buf).Encode(query); err != nil {
-		c.JSON(500, gin.H{"message": "Error encoding query"})
+	pg, ok := mw.BindPage(c)
+	if !ok {
 		return
 	}
 
-	// 4. Execute Search
-	res, err := esClient.Search(
-		esClient.Search.WithContext(context.Background()),
-		esClient.Search.WithIndex(indexName),
-		esClient.Search.WithBody(strings.NewReader(buf.String())),
-		esClient.Search.WithTrackTotalHits(true),
-	)
+	result, err := h.products.Catalogue(c.Request.Context(), catalogueQuery(params), pg)
 	if err != nil {
-		c.JSON(500, gin.H{"message": err.Error()})
+		mw.PageError(c, err)
 		return
 	}
-	defer res.Body.Close()
 
-	// 5. Parse Response
-	var r map[string]interface{}
-	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
-		c.JSON(500, gin.H{"message": "Error parsing response"})
-		return
+	prods := make([]dto.ProductHit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		prods = append(prods, dto.ProductHit{Products: toProductDto(hit.Product), Highlights: hit.Highlights})
 	}
 
-	// Extract hits and total records
-	hits := r["hits"].(map[string]interface{})
-	totalRecords := int64(hits["total"].(map[string]interface{})["value"].(float64))
-	totalPages := math.Ceil(float64(totalRecords) / float64(perPage))
-
-	// 6. Map results to your DTO
-	var prods []dto.Products
-	for _, hit := range hits["hits"].([]interface{}) {
-		source := hit.(map[string]interface{})["_source"]
+	body := mw.PageBody("products", prods, result.Total, result.NextCursor)
+	body["facets"] = gin.H{"category": result.Categories}
+	c.JSON(http.StatusOK, body)
+}
 
-		// Convert map to your DTO struct
-		var prod dto.Products
-		byteData, _ := json.Marshal(source)
-		json.Unmarshal(byteData, &prod)
+// catalogueQuery turns the search parameters into a catalogue query.
+func catalogueQuery(params dto.ProductSearch) repository.CatalogueQuery {
+	return repository.CatalogueQuery{
+		Text:       strings.TrimSpace(params.Query),
+		Categories: params.Category,
+		MinPrice:   params.MinPrice,
+		MaxPrice:   params.MaxPrice,
+		InStock:    params.InStock,
+		Sort:       params.Sort,
+	}
+}
 
-		prods = append(prods, prod)
+// @Summary Product Search Suggestions
+// @Description Products whose descriptions or category have words starting with what was typed so far, best match first
+// @Tags Products
+// @Produce json
+// @Param q query string true "Text typed so far"
+// @Param size query int false "Number of suggestions" default(8)
+// @Success 200 {object} map[string]interface{}
+// @Router /products/suggest [get]
+func (h *Handler) SuggestProducts(c *gin.Context) {
+	var params dto.ProductSuggest
+	if !validation.BindQuery(c, &params) {
+		return
 	}
 
-	if len(prods) == 0 {
-		c.JSON(404, gin.H{"message": "products not found."})
+	size := params.Size
+	if size == 0 {
+		size = 8
+	}
+	suggestions, err := h.products.Suggest(c.Request.Context(), strings.TrimSpace(params.Query), size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"page":         page,
-		"totpage":      totalPages,
-		"totalrecords": totalRecords,
-		"products":     prods,
-	})
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
 }
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.elasticsearch/dto"
+	mw "golang.elasticsearch/middleware"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Post Stock Movement
+// @Description Receive, issue, return or adjust stock. The product quantity is updated atomically and cannot go below zero.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product Id"
+// @Param movement body dto.StockMovement true "Stock movement"
+// @Success 201 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Insufficient stock or concurrent update"
+// @Router /api/products/{id}/movements [post]
+func (h *Handler) PostStockMovement(c *gin.Context) {
+	var body dto.StockMovement
+	if !validation.BindJSON(c, &body) {
+		return
+	}
+
+	movement := &models.StockMovement{
+		ProductID: c.Param("id"),
+		Type:      body.Type,
+		Quantity:  body.Quantity,
+		Reference: body.Reference,
+		Note:      body.Note,
+		CreatedAt: time.Now().UTC(),
+	}
+	if user := mw.CurrentUser(c); user != nil {
+		movement.UserID = user.ID
+	}
+
+	if !h.moveStock(c, movement) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"movement": movement,
+		"qty":      movement.Balance,
+		"message":  "Stock movement has been posted."})
+}
+
+// moveStock posts the movement, writing the error response when it fails.
+func (h *Handler) moveStock(c *gin.Context, movement *models.StockMovement) bool {
+	if err := h.stock.Move(c.Request.Context(), movement); err != nil {
+		stockError(c, err)
+		return false
+	}
+	return true
+}
+
+// stockError answers a failed stock movement.
+func stockError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product ID not found."})
+	case errors.Is(err, repository.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"message": "Insufficient stock, the quantity cannot go below zero."})
+	case errors.Is(err, repository.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"message": "The product is being updated by someone else, please try again."})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// @Summary Stock Movement History
+// @Description Movements of a product, newest first
+// @Tags Products
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product Id"
+// @Param size query int false "Movements per page" default(20)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/products/{id}/movements [get]
+func (h *Handler) GetStockMovements(c *gin.Context) {
+	pg, ok := mw.BindPage(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	if _, err := h.products.Get(ctx, id); errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product ID not found."})
+		return
+	}
+
+	page, err := h.stock.History(ctx, repository.StockQuery{ProductID: id}, pg)
+	if err != nil {
+		mw.PageError(c, err)
+		return
+	}
+
+	mw.WritePage(c, "movements", page.Items, page.Total, page.NextCursor)
+}
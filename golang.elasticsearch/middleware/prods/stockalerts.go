@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAlertProducts caps each list of the stock alert endpoint and report.
+const maxAlertProducts = 1000
+
+// @Summary Low and Critical Stocks
+// @Description Products at or below their critical stock level, and those at or below their alert level but above critical
+// @Tags Products
+// @Produce json
+// @Security BearerAuth
+// @Param level query string false "Only this level" Enums(low, critical)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/products/alerts [get]
+func (h *Handler) GetStockAlerts(c *gin.Context) {
+	levels := []string{models.StockCritical, models.StockLow}
+	switch level := c.Query("level"); level {
+	case "":
+	case models.StockCritical, models.StockLow:
+		levels = []string{level}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "level must be low or critical."})
+		return
+	}
+
+	result := gin.H{}
+	for _, level := range levels {
+		products, err := h.productsAtLevel(c.Request.Context(), level)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		result[level] = products
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) productsAtLevel(ctx context.Context, level string) ([]dto.Products, error) {
+	list, _, err := h.products.Search(ctx, repository.ProductQuery{
+		Level: level,
+		Size:  maxAlertProducts,
+		Sort:  []string{"descriptions.keyword:asc"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]dto.Products, 0, len(list))
+	for _, p := range list {
+		products = append(products, toProductDto(p))
+	}
+	return products, nil
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	utils "golang.elasticsearch/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.elasticsearch/models"
+)
+
+// RequireRole lets the request through when the caller has at least one of
+// the given roles. It must run after AuthMiddleware. The roles stored on the
+// user document win over the token so a revoked role takes effect at once.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasAnyRole(c, roles...) {
+			c.Next()
+			return
+		}
+		forbidden(c)
+	}
+}
+
+// RequireOwnerOrAdmin only allows requests whose path parameter matches the
+// authenticated user's id, unless the caller is an administrator.
+func RequireOwnerOrAdmin(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user != nil && user.ID == c.Param(param) {
+			c.Next()
+			return
+		}
+		if hasAnyRole(c, models.RoleAdmin) {
+			c.Next()
+			return
+		}
+		forbidden(c)
+	}
+}
+
+// Self fills the path parameter with the authenticated user's id, so the
+// /api/me routes can share handlers with their /:id counterparts.
+func Self(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized Access."})
+			c.Abort()
+			return
+		}
+		c.Params = append(c.Params, gin.Param{Key: param, Value: user.ID})
+		c.Next()
+	}
+}
+
+func hasAnyRole(c *gin.Context, roles ...string) bool {
+	var granted []string
+	if user := CurrentUser(c); user != nil {
+		granted = user.Roles
+	} else if value, ok := c.Get("claims"); ok {
+		if claims, _ := value.(*utils.Claims); claims != nil {
+			granted = claims.Roles
+		}
+	}
+
+	for _, role := range roles {
+		if slices.Contains(granted, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func forbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"message": "You are not allowed to access this resource."})
+	c.Abort()
+}
@@ -1,12 +1,11 @@
 package middleware
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	dbconfig "golang.elasticsearch/dbconfig"
+	"golang.elasticsearch/repository"
 )
 
 // DeleteUserid godoc
@@ -17,48 +16,31 @@ import (
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "User Id"
-// @Success 200 {object} DeleteResponse
-// @Failure 404 {object} ErrorResponse "User not found"
-// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /api/deleteuserbyid/{id} [delete]
-func DeleteUserid(c *gin.Context) {
+func (h *Handler) DeleteUserid(c *gin.Context) {
 	id := c.Param("id")
-	esClient := dbconfig.Connection()
+	ctx := c.Request.Context()
 
-	// 1. Direct Delete call using ID
-	// No search query is needed for a simple ID-based deletion.
-	res, err := esClient.Delete(
-		"users",
-		id,
-		esClient.Delete.WithContext(context.Background()),
-	)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute delete"})
-		return
+	user, err := h.users.Get(ctx, id)
+	if err == nil {
+		err = h.users.Delete(ctx, id)
 	}
-	defer res.Body.Close()
-
-	// 2. Check for HTTP errors (e.g., 404 Not Found)
-	if res.IsError() {
-		if res.StatusCode == 404 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Elasticsearch error"})
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-
-	// 3. Optional: Parse response for confirmation
-	var r map[string]interface{}
-	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error parsing ES response"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute delete"})
 		return
 	}
 
-	// Elasticsearch returns "result": "deleted" on success
+	h.removePicture(ctx, id, user.Userpicture)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User has been deleted successfully",
-		"result":  r["result"],
+		"result":  "deleted",
 	})
 }
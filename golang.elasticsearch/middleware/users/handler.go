@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/storage"
+)
+
+// Handler serves the user management routes.
+type Handler struct {
+	users repository.UserRepository
+	blobs storage.BlobStore
+	// pictureDir holds the pictures saved before they went to the blob
+	// store, and the default pix.png.
+	pictureDir string
+}
+
+func NewHandler(users repository.UserRepository, blobs storage.BlobStore, pictureDir string) *Handler {
+	return &Handler{users: users, blobs: blobs, pictureDir: pictureDir}
+}
+
+func toUserDto(user models.User) dto.Users {
+	return dto.Users{
+		Id:          user.ID,
+		Firstname:   user.Firstname,
+		Lastname:    user.Lastname,
+		Email:       user.Email,
+		Mobile:      user.Mobile,
+		Username:    user.Username,
+		Roles:       user.Roles,
+		Isactivated: user.Isactivated,
+		Isblocked:   user.Isblocked,
+		Lockeduntil: user.Lockeduntil,
+		Userpicture: user.Userpicture,
+		Mfaenabled:  user.Secret != nil,
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Grant a role
+// @Description Add a role to a user account
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User Id"
+// @Param role path string true "Role name, e.g. ROLE_ADMIN"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/users/{id}/roles/{role} [put]
+func (h *Handler) GrantRole(c *gin.Context) {
+	id := c.Param("id")
+	role := c.Param("role")
+
+	if !slices.Contains(models.KnownRoles, role) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Unknown role " + role + "."})
+		return
+	}
+
+	user, err := h.users.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	roles := user.Roles
+	if !roles.Has(role) {
+		roles = append(roles, role)
+		if err := h.saveRoles(c, id, roles); err != nil {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles":   roles,
+		"message": "Role " + role + " has been granted."})
+}
+
+// @Summary Revoke a role
+// @Description Remove a role from a user account
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User Id"
+// @Param role path string true "Role name, e.g. ROLE_ADMIN"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/admin/users/{id}/roles/{role} [delete]
+func (h *Handler) RevokeRole(c *gin.Context) {
+	id := c.Param("id")
+	role := c.Param("role")
+	ctx := c.Request.Context()
+
+	user, err := h.users.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if role == models.RoleAdmin && user.Roles.Has(role) {
+		_, admins, err := h.users.Search(ctx, repository.UserQuery{Role: models.RoleAdmin, Size: 1})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		if admins <= 1 {
+			c.JSON(http.StatusConflict, gin.H{"message": "Cannot revoke the last administrator."})
+			return
+		}
+	}
+
+	roles := slices.DeleteFunc(slices.Clone(user.Roles), func(r string) bool { return r == role })
+	if len(roles) != len(user.Roles) {
+		if err := h.saveRoles(c, id, roles); err != nil {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles":   roles,
+		"message": "Role " + role + " has been revoked."})
+}
+
+func (h *Handler) saveRoles(c *gin.Context, id string, roles models.Roles) error {
+	err := h.users.Update(c.Request.Context(), id, map[string]interface{}{
+		"roles":      roles,
+		"updated_at": time.Now().UTC(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Error updating database: %s", err)})
+	}
+	return err
+}
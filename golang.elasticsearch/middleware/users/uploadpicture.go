@@ -1,21 +1,33 @@
 package middleware
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
 
-	dbconfig "golang.elasticsearch/dbconfig"
-	utils "golang.elasticsearch/utils"
+	"golang.elasticsearch/images"
+	mw "golang.elasticsearch/middleware"
+	"golang.elasticsearch/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// maxPictureSize caps an uploaded profile picture.
+	maxPictureSize = 5 << 20
+	// pictureSize is the side of the square the picture is cropped to.
+	pictureSize    = 256
+	defaultPicture = "pix.png"
+)
+
 // @Summary Update user profile picture
-// @Description Upload user picture
+// @Description Upload a JPEG, PNG, GIF or WebP picture of up to 5 MB. The type is told from the content, not the file name. The picture is cropped to a square, re-encoded as a JPEG without its metadata and replaces the previous one.
 // @Tags User
 // @Accept multipart/form-data
 // @Produce json
@@ -23,66 +35,140 @@ import (
 // @Param id path string true "User Id"
 // @Param userpic formData file true "New Profile Picture"
 // @Success 200 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
 // @Router /api/uploadpicture/{id} [patch]
-func UploadPicture(c *gin.Context) {
+// @Router /api/me/uploadpicture [patch]
+func (h *Handler) UploadPicture(c *gin.Context) {
 	id := c.Param("id")
-	user, err := utils.GetUserid(id)
+	ctx := c.Request.Context()
+
+	user, err := h.users.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(400, gin.H{"message": "User ID not found."})
+		return
+	}
 	if err != nil {
 		c.JSON(400, gin.H{"message": err.Error()})
 		return
 	}
-	if len(user) > 0 {
 
-		file, err := c.FormFile("userpic") // "file" is the key for the form data
-		if err != nil {
-			c.String(http.StatusBadRequest, fmt.Sprintf("get form err: %s", err.Error()))
-			return
-		}
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPictureSize+1<<20)
+	data, err := mw.ReadFormFile(c, "userpic")
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) || err == nil && len(data) > maxPictureSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "The picture is larger than 5 MB."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Send the picture as the userpic form field."})
+		return
+	}
 
-		filename := filepath.Base(file.Filename)
-		ext := filepath.Ext(filename)
-		newfile := "00" + id + ext
-		dst := filepath.Join("./assets/users/", newfile) // Destination path
+	img, err := images.Decode(data, 4*pictureSize)
+	switch {
+	case errors.Is(err, images.ErrUnsupported):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"message": err.Error()})
+		return
+	case errors.Is(err, images.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	encoded, err := images.EncodeJPEG(images.Square(img, pictureSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
 
-		// Save the uploaded file to the specified destination
-		if err := c.SaveUploadedFile(file, dst); err != nil {
-			c.String(http.StatusInternalServerError, fmt.Sprintf("upload file err: %s", err.Error()))
-			return
-		}
+	sum := sha256.Sum256(encoded)
+	key := fmt.Sprintf("users/%s/%x.jpg", id, sum[:8])
+	if err := h.blobs.Put(ctx, key, encoded, "image/jpeg"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Error saving picture: %s", err)})
+		return
+	}
 
-		updateData := map[string]interface{}{
-			"doc": map[string]interface{}{
-				"userpicture": newfile,
-			},
-		}
-		payload, _ := json.Marshal(updateData)
-
-		// 4. Execute the Elasticsearch Update request
-		client := dbconfig.Connection()
-		res, err := client.Update(
-			"users", // Index name
-			id,      // Document ID
-			bytes.NewReader(payload),
-			client.Update.WithContext(context.Background()),
-		)
-
-		if err != nil {
-			c.JSON(500, gin.H{"message": "Error updating database"})
-			return
+	if err := h.users.Update(ctx, id, map[string]interface{}{"userpicture": key}); err != nil {
+		if key != user.Userpicture {
+			h.removePicture(ctx, id, key)
 		}
-		defer res.Body.Close()
+		c.JSON(500, gin.H{"message": fmt.Sprintf("Error updating database: %s", err)})
+		return
+	}
+	if user.Userpicture != key {
+		h.removePicture(ctx, id, user.Userpicture)
+	}
 
-		if res.IsError() {
-			c.JSON(500, gin.H{"message": fmt.Sprintf("Elasticsearch error: %s", res.String())})
-			return
-		}
+	c.JSON(200, gin.H{
+		"userpic": key,
+		"message": "Profile picture has been changed."})
+}
 
-		c.JSON(200, gin.H{
-			"userpic": newfile,
-			"message": "Profile picture has been changed."})
+// @Summary Get user profile picture
+// @Description The current picture of the user, or the default one. The address stays the same when the picture changes, so it is only cached briefly; add the userpicture value as a query parameter to refresh it at once.
+// @Tags User
+// @Produce image/jpeg,image/png
+// @Param id path string true "User Id"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /users/{id}/picture [get]
+func (h *Handler) GetPicture(c *gin.Context) {
+	user, err := h.users.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User ID not found."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
 
-	} else {
-		c.JSON(400, gin.H{"message": "User ID not found."})
+	const cacheControl = "public, max-age=300"
+	if isBlobKey(user.Userpicture) {
+		mw.ServeBlob(c, h.blobs, user.Userpicture, cacheControl)
+		return
+	}
+
+	// Older uploads were saved under any extension the client sent, so only
+	// image ones are served, and never sniffed as something else
+	name := filepath.Base(user.Userpicture)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+	default:
+		name = defaultPicture
 	}
+	path := filepath.Join(h.pictureDir, name)
+	if _, err := os.Stat(path); err != nil {
+		path = filepath.Join(h.pictureDir, defaultPicture)
+	}
+	c.Header("Cache-Control", cacheControl)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(path)
+}
+
+// removePicture deletes a picture that is no longer used. The default
+// picture, shared by every account, is kept. Failures are only logged.
+func (h *Handler) removePicture(ctx context.Context, id string, picture string) {
+	var err error
+	switch {
+	case isBlobKey(picture):
+		err = h.blobs.Delete(ctx, picture)
+	// Older uploads were saved as 00<id><ext>
+	case strings.HasPrefix(picture, "00"+id+".") && filepath.Base(picture) == picture:
+		err = os.Remove(filepath.Join(h.pictureDir, picture))
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Printf("Error deleting picture %s of user %s: %s", picture, id, err)
+	}
+}
 
+// isBlobKey tells a blob store key from the file name of an older upload.
+func isBlobKey(picture string) bool {
+	return strings.Contains(picture, "/")
 }
@@ -0,0 +1,344 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Each directory under mappings is an alias name, each file in it a numbered
+// mapping version (v1.json, v2.json, ...). The highest version is the one the
+// alias should point at.
+//
+//go:embed mappings
+var mappingFiles embed.FS
+
+// VersionIndex stores one record per alias with the mapping version applied.
+const VersionIndex = "schema_migrations"
+
+type Migration struct {
+	Alias   string
+	Version int
+	Body    []byte
+}
+
+type record struct {
+	Alias     string    `json:"alias"`
+	Version   int       `json:"version"`
+	Index     string    `json:"index"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// PhysicalIndex is the concrete index name behind an alias for a version.
+func (m Migration) PhysicalIndex() string {
+	return fmt.Sprintf("%s_v%d", m.Alias, m.Version)
+}
+
+// Latest returns the newest checked-in mapping for every alias.
+func Latest() ([]Migration, error) {
+	dirs, err := fs.ReadDir(mappingFiles, "mappings")
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Migration
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		files, err := fs.ReadDir(mappingFiles, path.Join("mappings", dir.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		latest := Migration{Alias: dir.Name()}
+		for _, f := range files {
+			name := strings.TrimSuffix(f.Name(), ".json")
+			version, err := strconv.Atoi(strings.TrimPrefix(name, "v"))
+			if err != nil || !strings.HasPrefix(name, "v") {
+				return nil, fmt.Errorf("bad mapping file name %s/%s", dir.Name(), f.Name())
+			}
+			if version > latest.Version {
+				latest.Version = version
+				latest.Body, err = mappingFiles.ReadFile(path.Join("mappings", dir.Name(), f.Name()))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		if latest.Version > 0 {
+			list = append(list, latest)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Alias < list[j].Alias })
+	return list, nil
+}
+
+// Run brings every alias up to its latest mapping version. A new physical
+// index is created, documents are reindexed from whatever the alias pointed at
+// before, and the alias is swapped over once the copy is complete.
+//
+// aliases renames the alias of a mapping directory. Directories missing from
+// it keep their own name.
+func Run(ctx context.Context, client *elasticsearch.Client, aliases map[string]string) error {
+	if err := ensureVersionIndex(ctx, client); err != nil {
+		return err
+	}
+
+	list, err := Latest()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		if alias := aliases[m.Alias]; alias != "" {
+			m.Alias = alias
+		}
+		applied, err := appliedVersion(ctx, client, m.Alias)
+		if err != nil {
+			return err
+		}
+		current, legacy, err := aliasTarget(ctx, client, m.Alias)
+		if err != nil {
+			return err
+		}
+		if applied == m.Version && current == m.PhysicalIndex() {
+			continue
+		}
+		if applied > m.Version {
+			return fmt.Errorf("%s is at mapping version %d, newer than this build (%d)", m.Alias, applied, m.Version)
+		}
+
+		log.Printf("Migrating %s to mapping version %d", m.Alias, m.Version)
+		if err := migrate(ctx, client, m, current, legacy); err != nil {
+			return fmt.Errorf("migrating %s: %w", m.Alias, err)
+		}
+		if err := saveVersion(ctx, client, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate(ctx context.Context, client *elasticsearch.Client, m Migration, current string, legacy bool) error {
+	target := m.PhysicalIndex()
+
+	if current != target {
+		exists, err := indexExists(ctx, client, target)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			res, err := client.Indices.Create(target,
+				client.Indices.Create.WithContext(ctx),
+				client.Indices.Create.WithBody(bytes.NewReader(m.Body)),
+			)
+			if err := check(res, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	if current != "" && current != target {
+		if err := reindex(ctx, client, current, target); err != nil {
+			return err
+		}
+	}
+
+	// An index created by dynamic mapping has the alias name itself, so it
+	// has to go before the alias can take its place. The copy has been
+	// verified by then, as the documents only live on in the new index.
+	if legacy {
+		res, err := client.Indices.Delete([]string{current}, client.Indices.Delete.WithContext(ctx))
+		if err := check(res, err); err != nil {
+			return err
+		}
+	}
+
+	actions := []map[string]interface{}{
+		{"add": map[string]interface{}{"index": target, "alias": m.Alias, "is_write_index": true}},
+	}
+	if current != "" && current != target && !legacy {
+		actions = append([]map[string]interface{}{
+			{"remove": map[string]interface{}{"index": current, "alias": m.Alias}},
+		}, actions...)
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"actions": actions})
+
+	res, err := client.Indices.UpdateAliases(bytes.NewReader(payload),
+		client.Indices.UpdateAliases.WithContext(ctx),
+	)
+	return check(res, err)
+}
+
+// reindex copies every document of source into target. Documents that fail
+// to copy do not fail the request, so the counts are checked as well.
+func reindex(ctx context.Context, client *elasticsearch.Client, source, target string) error {
+	body := fmt.Sprintf(`{"source":{"index":%q},"dest":{"index":%q}}`, source, target)
+	res, err := client.Reindex(strings.NewReader(body),
+		client.Reindex.WithContext(ctx),
+		client.Reindex.WithWaitForCompletion(true),
+		client.Reindex.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r struct {
+		TimedOut bool              `json:"timed_out"`
+		Total    int               `json:"total"`
+		Created  int               `json:"created"`
+		Updated  int               `json:"updated"`
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return err
+	}
+	switch {
+	case len(r.Failures) > 0:
+		return fmt.Errorf("reindexing %s into %s: %d documents failed, first: %s", source, target, len(r.Failures), r.Failures[0])
+	case r.TimedOut:
+		return fmt.Errorf("reindexing %s into %s timed out", source, target)
+	case r.Created+r.Updated != r.Total:
+		return fmt.Errorf("reindexing %s into %s copied %d of %d documents", source, target, r.Created+r.Updated, r.Total)
+	}
+	return nil
+}
+
+// aliasTarget reports the index the alias resolves to. legacy is true when
+// the name is a plain index rather than an alias.
+func aliasTarget(ctx context.Context, client *elasticsearch.Client, alias string) (string, bool, error) {
+	res, err := client.Indices.GetAlias(
+		client.Indices.GetAlias.WithContext(ctx),
+		client.Indices.GetAlias.WithName(alias),
+	)
+	if err != nil {
+		return "", false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		exists, err := indexExists(ctx, client, alias)
+		if err != nil || !exists {
+			return "", false, err
+		}
+		return alias, true, nil
+	}
+	if res.IsError() {
+		return "", false, fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return "", false, err
+	}
+	for index := range r {
+		return index, false, nil
+	}
+	return "", false, nil
+}
+
+func indexExists(ctx context.Context, client *elasticsearch.Client, name string) (bool, error) {
+	res, err := client.Indices.Exists([]string{name}, client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	return res.StatusCode == 200, nil
+}
+
+func ensureVersionIndex(ctx context.Context, client *elasticsearch.Client) error {
+	exists, err := indexExists(ctx, client, VersionIndex)
+	if err != nil || exists {
+		return err
+	}
+
+	mapping := `{
+		"mappings": {
+			"properties": {
+				"alias":      { "type": "keyword" },
+				"version":    { "type": "integer" },
+				"index":      { "type": "keyword" },
+				"applied_at": { "type": "date" }
+			}
+		}
+	}`
+	res, err := client.Indices.Create(VersionIndex,
+		client.Indices.Create.WithContext(ctx),
+		client.Indices.Create.WithBody(strings.NewReader(mapping)),
+	)
+	return check(res, err)
+}
+
+func appliedVersion(ctx context.Context, client *elasticsearch.Client, alias string) (int, error) {
+	res, err := client.Get(VersionIndex, alias, client.Get.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return 0, nil
+	}
+	if res.IsError() {
+		return 0, fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r struct {
+		Source record `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return 0, err
+	}
+	return r.Source.Version, nil
+}
+
+func saveVersion(ctx context.Context, client *elasticsearch.Client, m Migration) error {
+	data, err := json.Marshal(record{
+		Alias:     m.Alias,
+		Version:   m.Version,
+		Index:     m.PhysicalIndex(),
+		AppliedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Index(VersionIndex, bytes.NewReader(data),
+		client.Index.WithContext(ctx),
+		client.Index.WithDocumentID(m.Alias),
+		client.Index.WithRefresh("true"),
+	)
+	return check(res, err)
+}
+
+// check turns a failed request or an error status into an error and closes
+// the response body.
+func check(res *esapi.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// fakeCluster keeps the indices, aliases and version records a migration
+// reads and writes, and logs every change made to them.
+type fakeCluster struct {
+	mu sync.Mutex
+	// indices holds the document count of each index.
+	indices  map[string]int
+	aliases  map[string]string
+	versions map[string]record
+	changes  []string
+	// reindexed builds the reindex response; nil copies every document.
+	reindexed func(total int) map[string]interface{}
+}
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{
+		indices:  map[string]int{},
+		aliases:  map[string]string{},
+		versions: map[string]record{},
+	}
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	reply := func(status int, body interface{}) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+	missing := map[string]interface{}{"error": map[string]interface{}{"type": "index_not_found_exception"}}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodHead && len(parts) == 1:
+		_, index := f.indices[parts[0]]
+		_, alias := f.aliases[parts[0]]
+		if index || alias {
+			w.WriteHeader(200)
+		} else {
+			w.WriteHeader(404)
+		}
+
+	case r.Method == http.MethodPut && len(parts) == 1:
+		f.indices[parts[0]] = 0
+		f.changes = append(f.changes, "create "+parts[0])
+		reply(200, map[string]interface{}{"acknowledged": true})
+
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		delete(f.indices, parts[0])
+		f.changes = append(f.changes, "delete "+parts[0])
+		reply(200, map[string]interface{}{"acknowledged": true})
+
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "_alias":
+		index, ok := f.aliases[parts[1]]
+		if !ok {
+			reply(404, map[string]interface{}{"error": "alias [" + parts[1] + "] missing", "status": 404})
+			return
+		}
+		reply(200, map[string]interface{}{index: map[string]interface{}{"aliases": map[string]interface{}{parts[1]: map[string]interface{}{}}}})
+
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == VersionIndex:
+		rec, ok := f.versions[parts[2]]
+		if !ok {
+			reply(404, map[string]interface{}{"found": false})
+			return
+		}
+		reply(200, map[string]interface{}{"found": true, "_source": rec})
+
+	case r.Method == http.MethodPut && len(parts) == 3 && parts[0] == VersionIndex:
+		var rec record
+		json.NewDecoder(r.Body).Decode(&rec)
+		f.versions[parts[2]] = rec
+		reply(200, map[string]interface{}{"result": "updated"})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/_reindex":
+		var body struct {
+			Source struct{ Index string } `json:"source"`
+			Dest   struct{ Index string } `json:"dest"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		total := f.indices[body.Source.Index]
+		res := map[string]interface{}{"total": total, "created": total, "updated": 0, "timed_out": false, "failures": []interface{}{}}
+		if f.reindexed != nil {
+			res = f.reindexed(total)
+		}
+		copied, _ := res["created"].(int)
+		f.indices[body.Dest.Index] += copied
+		f.changes = append(f.changes, "reindex "+body.Source.Index+" into "+body.Dest.Index)
+		reply(200, res)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/_aliases":
+		var body struct {
+			Actions []map[string]struct {
+				Index string `json:"index"`
+				Alias string `json:"alias"`
+			} `json:"actions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		// The actions apply together or not at all
+		next := map[string]string{}
+		for alias, index := range f.aliases {
+			next[alias] = index
+		}
+		for _, action := range body.Actions {
+			for kind, a := range action {
+				switch kind {
+				case "add":
+					if _, ok := f.indices[a.Alias]; ok {
+						reply(400, map[string]interface{}{"error": "an index exists with the same name as the alias"})
+						return
+					}
+					next[a.Alias] = a.Index
+				case "remove":
+					if next[a.Alias] != a.Index {
+						reply(404, missing)
+						return
+					}
+					delete(next, a.Alias)
+				}
+				f.changes = append(f.changes, kind+" alias "+a.Alias+" on "+a.Index)
+			}
+		}
+		f.aliases = next
+		reply(200, map[string]interface{}{"acknowledged": true})
+
+	default:
+		reply(400, map[string]interface{}{"error": "unexpected " + r.Method + " " + r.URL.Path})
+	}
+}
+
+func newClient(t *testing.T, f *fakeCluster) *elasticsearch.Client {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// latest returns the newest checked-in mapping of alias.
+func latest(t *testing.T, alias string) Migration {
+	t.Helper()
+	list, err := Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range list {
+		if m.Alias == alias {
+			return m
+		}
+	}
+	t.Fatalf("no mapping for %s", alias)
+	return Migration{}
+}
+
+// only keeps the changes naming alias or one of its indices.
+func only(changes []string, alias string) []string {
+	var kept []string
+	for _, c := range changes {
+		if strings.Contains(c, alias) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func TestLatest(t *testing.T) {
+	list, err := Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var aliases []string
+	for _, m := range list {
+		aliases = append(aliases, m.Alias)
+		if !json.Valid(m.Body) {
+			t.Errorf("%s v%d is not valid JSON", m.Alias, m.Version)
+		}
+	}
+	want := []string{"login_attempts", "products", "refresh_tokens", "revoked_tokens", "sales", "stock_movements", "users"}
+	if !slices.Equal(aliases, want) {
+		t.Errorf("aliases = %v, want %v", aliases, want)
+	}
+	if p := latest(t, "products"); p.Version < 2 || p.PhysicalIndex() != "products_v"+strconv.Itoa(p.Version) {
+		t.Errorf("products = v%d in %s", p.Version, p.PhysicalIndex())
+	}
+}
+
+func TestRunFreshCluster(t *testing.T) {
+	f := newFakeCluster()
+	client := newClient(t, f)
+
+	aliases := map[string]string{"products": "shop_products"}
+	if err := Run(context.Background(), client, aliases); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := Latest()
+	for _, m := range list {
+		if m.Alias == "products" {
+			m.Alias = "shop_products"
+		}
+		if f.aliases[m.Alias] != m.PhysicalIndex() {
+			t.Errorf("%s points at %q, want %s", m.Alias, f.aliases[m.Alias], m.PhysicalIndex())
+		}
+		if rec := f.versions[m.Alias]; rec.Version != m.Version || rec.Index != m.PhysicalIndex() {
+			t.Errorf("%s recorded as %+v", m.Alias, rec)
+		}
+	}
+	if _, ok := f.aliases["products"]; ok {
+		t.Error("the renamed alias was created under its directory name")
+	}
+
+	// Up to date, a second run changes nothing
+	f.changes = nil
+	if err := Run(context.Background(), client, aliases); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.changes) != 0 {
+		t.Errorf("second run made changes: %q", f.changes)
+	}
+}
+
+func TestRunUpgrade(t *testing.T) {
+	m := latest(t, "products")
+	previous := "products_v" + strconv.Itoa(m.Version-1)
+
+	f := newFakeCluster()
+	f.indices[previous] = 5
+	f.aliases["products"] = previous
+	f.versions["products"] = record{Alias: "products", Version: m.Version - 1, Index: previous}
+
+	if err := Run(context.Background(), newClient(t, f), nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"create " + m.PhysicalIndex(),
+		"reindex " + previous + " into " + m.PhysicalIndex(),
+		"remove alias products on " + previous,
+		"add alias products on " + m.PhysicalIndex(),
+	}
+	if got := only(f.changes, "products"); !slices.Equal(got, want) {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+	if f.indices[m.PhysicalIndex()] != 5 || f.aliases["products"] != m.PhysicalIndex() {
+		t.Errorf("products_v%d holds %d documents, alias on %s", m.Version, f.indices[m.PhysicalIndex()], f.aliases["products"])
+	}
+	// The previous index is kept to roll back to
+	if _, ok := f.indices[previous]; !ok {
+		t.Errorf("%s was deleted", previous)
+	}
+	if f.versions["products"].Version != m.Version {
+		t.Errorf("version = %+v", f.versions["products"])
+	}
+}
+
+func TestRunNewerVersion(t *testing.T) {
+	m := latest(t, "products")
+	f := newFakeCluster()
+	f.versions["products"] = record{Alias: "products", Version: m.Version + 1}
+
+	err := Run(context.Background(), newClient(t, f), nil)
+	if err == nil || !strings.Contains(err.Error(), "newer than this build") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRunLegacyIndex(t *testing.T) {
+	m := latest(t, "products")
+	f := newFakeCluster()
+	// Created by dynamic mapping, before the alias existed
+	f.indices["products"] = 5
+
+	if err := Run(context.Background(), newClient(t, f), nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"create " + m.PhysicalIndex(),
+		"reindex products into " + m.PhysicalIndex(),
+		"delete products",
+		"add alias products on " + m.PhysicalIndex(),
+	}
+	if got := only(f.changes, "products"); !slices.Equal(got, want) {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+	if f.indices[m.PhysicalIndex()] != 5 || f.aliases["products"] != m.PhysicalIndex() {
+		t.Errorf("%s holds %d documents, alias on %q", m.PhysicalIndex(), f.indices[m.PhysicalIndex()], f.aliases["products"])
+	}
+}
+
+// The legacy index holds the only copy of the documents, so it must survive
+// any reindex that did not copy all of them.
+func TestRunLegacyIndexKeptOnIncompleteCopy(t *testing.T) {
+	tests := []struct {
+		name      string
+		reindexed func(total int) map[string]interface{}
+		want      string
+	}{
+		{
+			name: "documents missing",
+			reindexed: func(total int) map[string]interface{} {
+				return map[string]interface{}{"total": total, "created": total - 1, "updated": 0}
+			},
+			want: "copied 4 of 5 documents",
+		},
+		{
+			name: "documents failed",
+			reindexed: func(total int) map[string]interface{} {
+				return map[string]interface{}{"total": total, "created": total - 1, "failures": []interface{}{map[string]interface{}{"id": "7"}}}
+			},
+			want: "1 documents failed",
+		},
+		{
+			name: "timed out",
+			reindexed: func(total int) map[string]interface{} {
+				return map[string]interface{}{"total": total, "created": total, "timed_out": true}
+			},
+			want: "timed out",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeCluster()
+			f.indices["products"] = 5
+			f.reindexed = tt.reindexed
+
+			err := Run(context.Background(), newClient(t, f), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+			if f.indices["products"] != 5 {
+				t.Error("the legacy index was deleted")
+			}
+			if _, ok := f.aliases["products"]; ok {
+				t.Error("the alias was swapped")
+			}
+			if _, ok := f.versions["products"]; ok {
+				t.Error("the version was recorded")
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginAttempt counts consecutive failures for one throttle key, such as a
+// username or a client IP. The counter starts over once ExpiresAt passes.
+type LoginAttempt struct {
+	ID          string    `json:"id"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
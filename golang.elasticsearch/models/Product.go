@@ -5,17 +5,52 @@ import (
 )
 
 type Product struct {
-	ID             string    `json:"id"`
-	Category       string    `json:"category"`
-	Descriptions   string    `json:"descriptions"`
-	Qty            float64   `json:"qty"`
-	Unit           string    `json:"unit"`
-	Costprice      float64   `json:"costprice"`
-	Sellprice      float64   `json:"sellprice"`
-	Saleprice      float64   `json:"saleprice"`
-	Productpicture *string   `json:"productpicture"`
-	Alertstocks    float64   `json:"alertstocks"`
-	Criticalstocks float64   `json:"criticalstocks"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             string         `json:"id"`
+	Sku            string         `json:"sku,omitempty"`
+	Category       string         `json:"category"`
+	Descriptions   string         `json:"descriptions"`
+	Qty            float64        `json:"qty"`
+	Unit           string         `json:"unit"`
+	Costprice      float64        `json:"costprice"`
+	Sellprice      float64        `json:"sellprice"`
+	Saleprice      float64        `json:"saleprice"`
+	Productpicture *string        `json:"productpicture"`
+	Images         *ProductImages `json:"images,omitempty"`
+	Alertstocks    float64        `json:"alertstocks"`
+	Criticalstocks float64        `json:"criticalstocks"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// ProductImages are the blob store keys of an uploaded product picture,
+// re-encoded without its metadata in three sizes. They take the place of
+// Productpicture, a file name in assets/products, once set.
+type ProductImages struct {
+	Original  string `json:"original"`
+	Medium    string `json:"medium"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+// Keys lists the stored blobs.
+func (i *ProductImages) Keys() []string {
+	return []string{i.Original, i.Medium, i.Thumbnail}
+}
+
+// Stock levels of a product, from best to worst.
+const (
+	StockOK       = "ok"
+	StockLow      = "low"
+	StockCritical = "critical"
+)
+
+// StockLevel grades qty against the product thresholds. A threshold of zero
+// means it was never set and is ignored.
+func (p *Product) StockLevel(qty float64) string {
+	switch {
+	case p.Criticalstocks > 0 && qty <= p.Criticalstocks:
+		return StockCritical
+	case p.Alertstocks > 0 && qty <= p.Alertstocks:
+		return StockLow
+	}
+	return StockOK
 }
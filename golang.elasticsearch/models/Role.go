@@ -1,6 +1,46 @@
 package models
 
+import (
+	"encoding/json"
+	"slices"
+)
+
+const (
+	RoleUser  = "ROLE_USER"
+	RoleAdmin = "ROLE_ADMIN"
+)
+
+// KnownRoles are the roles that can be granted through the admin API.
+var KnownRoles = []string{RoleUser, RoleAdmin}
+
 type Role struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
 }
+
+// Roles is the list of role names on a user. Older documents store a single
+// role as a plain string, so both forms are accepted when decoding.
+type Roles []string
+
+func (r *Roles) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		if single == "" {
+			*r = nil
+		} else {
+			*r = Roles{single}
+		}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*r = list
+	return nil
+}
+
+func (r Roles) Has(role string) bool {
+	return slices.Contains(r, role)
+}
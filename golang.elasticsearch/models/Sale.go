@@ -2,8 +2,24 @@ package models
 
 import "time"
 
+// Sale is one sales transaction. Amount is the total of the line items,
+// computed when the sale is posted. Sales recorded before line items existed
+// only carry Amount and Salesdate.
 type Sale struct {
-	ID        string    `json:"id"`
-	Amount    float64   `json:"amount"`
-	Salesdate time.Time `json:"salesdate"`
+	ID        string     `json:"id"`
+	Amount    float64    `json:"amount"`
+	Salesdate time.Time  `json:"salesdate"`
+	Items     []SaleItem `json:"items"`
+	UserID    string     `json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// SaleItem is a product sold at the price it had when the sale was posted.
+type SaleItem struct {
+	ProductID    string  `json:"product_id"`
+	Descriptions string  `json:"descriptions"`
+	Category     string  `json:"category"`
+	Qty          float64 `json:"qty"`
+	Unitprice    float64 `json:"unitprice"`
+	Amount       float64 `json:"amount"`
 }
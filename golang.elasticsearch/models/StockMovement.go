@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	MovementReceipt    = "receipt"
+	MovementIssue      = "issue"
+	MovementAdjustment = "adjustment"
+	MovementReturn     = "return"
+)
+
+// StockMovement is one entry of the stock ledger. Quantity is the amount
+// received, issued or returned; for an adjustment it is the signed correction.
+// Balance is the product quantity once the movement was applied.
+type StockMovement struct {
+	ID        string    `json:"id"`
+	ProductID string    `json:"product_id"`
+	Type      string    `json:"type"`
+	Quantity  float64   `json:"quantity"`
+	Balance   float64   `json:"balance"`
+	Reference string    `json:"reference"`
+	Note      string    `json:"note"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delta is the change the movement makes to the product quantity.
+func (m *StockMovement) Delta() float64 {
+	if m.Type == MovementIssue {
+		return -m.Quantity
+	}
+	return m.Quantity
+}
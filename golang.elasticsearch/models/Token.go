@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RefreshToken is stored under the SHA-256 of the token handed to the client.
+// Every refresh token issued for one login shares the same Family, which is
+// also the session id carried in the access tokens, and the same FamilyStart,
+// the time of that login.
+type RefreshToken struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Family      string    `json:"family"`
+	FamilyStart time.Time `json:"family_start"`
+	Used        bool      `json:"used"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RevokedToken blocks a token id (jti) or a whole session id until it expires.
+type RevokedToken struct {
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,24 +1,57 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
+const (
+	MailVerifyEmail   = "verify_email"
+	MailResetPassword = "reset_password"
+)
+
 type User struct {
-	ID          string    `json:"id"`
-	Lastname    string    `json:"lastname"`
-	Firstname   string    `json:"firstname"`
-	Email       string    `json:"email"`
-	Mobile      string    `json:"mobile"`
-	Username    string    `json:"username"`
-	Password    string    `json:"password"`
-	Roles       string    `json:"roles"`
-	Isactivated bool      `json:"isactivated"`
-	Isblocked   bool      `json:"isblocked"`
-	Userpicture string    `json:"userpicture"`
-	Mailtoken   float64   `json:"mailtoken"`
-	Secret      *string   `json:"secret"`
-	Qrcodeurl   *string   `json:"qrcodeurl"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID               string     `json:"id"`
+	Lastname         string     `json:"lastname"`
+	Firstname        string     `json:"firstname"`
+	Email            string     `json:"email"`
+	Mobile           string     `json:"mobile"`
+	Username         string     `json:"username"`
+	Password         string     `json:"password"`
+	Roles            Roles      `json:"roles"`
+	Isactivated      bool       `json:"isactivated"`
+	Isblocked        bool       `json:"isblocked"`
+	Userpicture      string     `json:"userpicture"`
+	Mailtoken        MailToken  `json:"mailtoken"`
+	Mailtokenpurpose string     `json:"mailtokenpurpose"`
+	Mailtokenexpires *time.Time `json:"mailtokenexpires"`
+	Secret           *string    `json:"secret"`
+	Qrcodeurl        *string    `json:"qrcodeurl"`
+	Totpstep         int64      `json:"totpstep"`
+	Pendingsecret    *string    `json:"pendingsecret"`
+	Pendingqrcodeurl *string    `json:"pendingqrcodeurl"`
+	Recoverycodes    []string   `json:"recoverycodes"`
+	Lockeduntil      *time.Time `json:"lockeduntil"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Locked reports whether the account is blocked at the given time. A block
+// without Lockeduntil was set by an administrator and does not expire.
+func (u *User) Locked(now time.Time) bool {
+	return u.Isblocked && (u.Lockeduntil == nil || now.Before(*u.Lockeduntil))
+}
+
+// MailToken is the hash of the last token mailed to the user. Older documents
+// hold a numeric placeholder, which decodes as no token.
+type MailToken string
+
+func (t *MailToken) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		*t = ""
+		return nil
+	}
+	*t = MailToken(s)
+	return nil
 }
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"golang.elasticsearch/models"
+)
+
+// AttemptStore counts failed login and OTP attempts per throttle key.
+type AttemptStore interface {
+	// Get returns the live counter for key, or a zero attempt when there is
+	// none or it has expired.
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// Fail adds one failure to key and keeps the counter for window after it.
+	Fail(ctx context.Context, key string, window time.Duration) (*models.LoginAttempt, error)
+	Reset(ctx context.Context, keys ...string) error
+	// Cleanup drops counters that have expired.
+	Cleanup(ctx context.Context, now time.Time) error
+}
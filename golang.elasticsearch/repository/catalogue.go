@@ -0,0 +1,57 @@
+package repository
+
+import "golang.elasticsearch/models"
+
+// Catalogue sort orders. Relevance is the default.
+const (
+	SortRelevance = "relevance"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortNameAsc   = "name_asc"
+	SortNameDesc  = "name_desc"
+	SortNewest    = "newest"
+)
+
+// CatalogueQuery is a storefront product search. Text is matched against the
+// descriptions and category, tolerating typos. Prices filter on the sell
+// price; nil bounds and an empty Categories leave those filters off.
+type CatalogueQuery struct {
+	Text       string
+	Categories []string
+	MinPrice   *float64
+	MaxPrice   *float64
+	InStock    bool
+	Sort       string
+}
+
+// CatalogueHit is a matching product with the matched parts of its fields
+// wrapped in <em> tags.
+type CatalogueHit struct {
+	Product    models.Product
+	Highlights map[string][]string
+}
+
+// Facet is one value of a field with the number of matching products.
+type Facet struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Suggestion is a product offered while the search text is being typed.
+type Suggestion struct {
+	ID             string                `json:"id"`
+	Descriptions   string                `json:"descriptions"`
+	Category       string                `json:"category"`
+	Productpicture *string               `json:"productpicture"`
+	Images         *models.ProductImages `json:"images,omitempty"`
+}
+
+// CatalogueResult holds one page of hits. The category facets count every
+// product matching the other filters, so picking a category does not hide
+// the others. NextCursor is empty on the last page.
+type CatalogueResult struct {
+	Hits       []CatalogueHit
+	Total      int64
+	NextCursor string
+	Categories []Facet
+}
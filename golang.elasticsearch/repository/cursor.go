@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// DefaultPageSize is used when a PageQuery leaves Size at zero.
+const DefaultPageSize = 20
+
+// ErrInvalidCursor is returned for a cursor that is malformed, was handed out
+// for another query, or whose point in time has expired.
+var ErrInvalidCursor = errors.New("invalid or expired cursor")
+
+// PageQuery asks for one page of a listing. An empty Cursor starts at the
+// first page; the NextCursor of that page gives the one after it.
+type PageQuery struct {
+	Cursor string
+	Size   int
+}
+
+func (p PageQuery) size() int {
+	if p.Size <= 0 {
+		return DefaultPageSize
+	}
+	return p.Size
+}
+
+// Page is one page of a listing. Total counts every match and NextCursor is
+// empty on the last page.
+type Page[T any] struct {
+	Items      []T
+	Total      int64
+	NextCursor string
+}
+
+// cursor is what an opaque cursor token holds. Query fingerprints the query
+// it was issued for, so a cursor cannot be replayed against other filters,
+// and the token is signed so that none of it can be altered.
+type cursor struct {
+	Query string `json:"q"`
+	// Elasticsearch resumes after the sort values of the last hit, inside
+	// the point in time opened for the first page
+	PIT   string            `json:"p,omitempty"`
+	After []json.RawMessage `json:"a,omitempty"`
+	// The in-memory stores resume at an offset
+	Offset int `json:"o,omitempty"`
+}
+
+func fingerprint(query interface{}) (string, error) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:8]), nil
+}
+
+// cursorKey signs cursor tokens, which carry the point in time id and sort
+// values sent back to Elasticsearch. Until SetCursorKey is called it is a
+// random key, so cursors only hold within the process that issued them.
+var cursorKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// SetCursorKey sets the key cursors are signed with, so that every instance
+// of the server accepts the cursors of the others. Call it before serving.
+func SetCursorKey(key []byte) {
+	cursorKey = key
+}
+
+func cursorMAC(payload string) string {
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeCursor(c cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + cursorMAC(payload), nil
+}
+
+// decodeCursor reads a cursor token issued for the query with fingerprint.
+// Tokens not signed with the cursor key are refused before being read.
+func decodeCursor(token, fingerprint string) (cursor, error) {
+	payload, mac, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(cursorMAC(payload))) {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.Query != fingerprint {
+		return cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMemoryPageWalk(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7}
+	query := ProductQuery{Text: "widget"}
+
+	var seen []int
+	p := PageQuery{Size: 3}
+	for pages := 0; ; pages++ {
+		if pages > len(items) {
+			t.Fatal("the cursor never reached the last page")
+		}
+		page, err := memoryPage(items, query, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != int64(len(items)) {
+			t.Errorf("total = %d, want %d", page.Total, len(items))
+		}
+		seen = append(seen, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		p.Cursor = page.NextCursor
+	}
+	if !slices.Equal(seen, items) {
+		t.Errorf("pages gave %v, want %v", seen, items)
+	}
+}
+
+func TestMemoryPageExactFit(t *testing.T) {
+	page, err := memoryPage([]int{1, 2}, ProductQuery{}, PageQuery{Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.NextCursor != "" {
+		t.Error("a full last page still has a next cursor")
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	items := []int{1, 2, 3}
+	first, err := memoryPage(items, ProductQuery{Text: "widget"}, PageQuery{Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		query  ProductQuery
+		cursor string
+	}{
+		{"malformed", ProductQuery{Text: "widget"}, "not a cursor"},
+		{"not json", ProductQuery{Text: "widget"}, "bm90IGpzb24"},
+		{"other query", ProductQuery{Text: "gadget"}, first.NextCursor},
+		{"unsigned", ProductQuery{Text: "widget"}, strings.Split(first.NextCursor, ".")[0]},
+		{"altered", ProductQuery{Text: "widget"}, alterCursor(t, first.NextCursor)},
+		{"other key", ProductQuery{Text: "widget"}, signedWith(t, []byte("another key"), items)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := memoryPage(items, tt.query, PageQuery{Cursor: tt.cursor, Size: 1})
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+// alterCursor moves the offset of a cursor, keeping its signature.
+func alterCursor(t *testing.T, token string) string {
+	t.Helper()
+	payload, mac, _ := strings.Cut(token, ".")
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	c.Offset = 2
+	if data, err = json.Marshal(c); err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." + mac
+}
+
+// signedWith is the first next cursor of items as another server would
+// sign it.
+func signedWith(t *testing.T, key []byte, items []int) string {
+	t.Helper()
+	own := cursorKey
+	SetCursorKey(key)
+	defer SetCursorKey(own)
+
+	page, err := memoryPage(items, ProductQuery{Text: "widget"}, PageQuery{Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.NextCursor
+}
+
+func TestCursorKeyShared(t *testing.T) {
+	own := cursorKey
+	defer SetCursorKey(own)
+
+	items := []int{1, 2, 3}
+	token := signedWith(t, []byte("shared key"), items)
+	SetCursorKey([]byte("shared key"))
+	page, err := memoryPage(items, ProductQuery{Text: "widget"}, PageQuery{Cursor: token, Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(page.Items, []int{2}) {
+		t.Errorf("page = %v, want [2]", page.Items)
+	}
+}
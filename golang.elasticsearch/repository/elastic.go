@@ -0,0 +1,459 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// esIndex wraps the raw document calls shared by the Elasticsearch repositories.
+type esIndex struct {
+	client *elasticsearch.Client
+	name   string
+}
+
+type esHit struct {
+	ID          string              `json:"_id"`
+	SeqNo       int64               `json:"_seq_no"`
+	PrimaryTerm int64               `json:"_primary_term"`
+	Source      json.RawMessage     `json:"_source"`
+	Highlight   map[string][]string `json:"highlight"`
+	Sort        []json.RawMessage   `json:"sort"`
+}
+
+// errVersionConflict means a conditional write lost against a concurrent one.
+var errVersionConflict = errors.New("document changed concurrently")
+
+// errExists means putNew found the id already taken.
+var errExists = errors.New("document already exists")
+
+// maxWriteAttempts bounds the read-modify-write retries of a conditional
+// write before it gives up with ErrConflict.
+const maxWriteAttempts = 5
+
+func (x esIndex) get(ctx context.Context, id string, dst interface{}) error {
+	_, err := x.getVersioned(ctx, id, dst)
+	return err
+}
+
+// getVersioned also returns the hit metadata, whose sequence number and
+// primary term make a later updateIf conditional on this read.
+func (x esIndex) getVersioned(ctx context.Context, id string, dst interface{}) (esHit, error) {
+	res, err := x.client.Get(x.name, id, x.client.Get.WithContext(ctx))
+	if err != nil {
+		return esHit{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return esHit{}, ErrNotFound
+	}
+	if res.IsError() {
+		return esHit{}, fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r esHit
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return esHit{}, err
+	}
+	return r, json.Unmarshal(r.Source, dst)
+}
+
+func (x esIndex) search(ctx context.Context, query map[string]interface{}) ([]esHit, int64, error) {
+	hits, total, _, err := x.searchAggs(ctx, query)
+	return hits, total, err
+}
+
+// searchAggs is search for queries that also ask for aggregations.
+func (x esIndex) searchAggs(ctx context.Context, query map[string]interface{}) ([]esHit, int64, json.RawMessage, error) {
+	r, err := x.runSearch(ctx, query)
+	if err != nil || r == nil {
+		return nil, 0, nil, err
+	}
+	return r.Hits.Hits, r.Hits.Total.Value, r.Aggregations, nil
+}
+
+type esSearchResponse struct {
+	PitID string `json:"pit_id"`
+	Hits  struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []esHit `json:"hits"`
+	} `json:"hits"`
+	Aggregations json.RawMessage `json:"aggregations"`
+}
+
+// runSearch sends query to the index, or to the point in time it names. A
+// missing index or point in time gives a nil response.
+func (x esIndex) runSearch(ctx context.Context, query map[string]interface{}) (*esSearchResponse, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, err
+	}
+
+	opts := []func(*esapi.SearchRequest){
+		x.client.Search.WithContext(ctx),
+		x.client.Search.WithBody(&buf),
+		x.client.Search.WithTrackTotalHits(true),
+	}
+	// A point in time already fixes the index
+	if _, ok := query["pit"]; !ok {
+		opts = append(opts, x.client.Search.WithIndex(x.name))
+	}
+	res, err := x.client.Search(opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// A missing index simply means nothing has been stored yet.
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r esSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// cursorKeepAlive is how long a point in time stays open waiting for the
+// next page to be asked for.
+const cursorKeepAlive = "5m"
+
+// paginate runs query one page at a time inside a point in time, so pages
+// do not shift while documents are written. The query must have a sort; the
+// point in time adds a tiebreaker that makes it total.
+func (x esIndex) paginate(ctx context.Context, query map[string]interface{}, p PageQuery) ([]esHit, int64, string, error) {
+	hits, total, next, _, err := x.paginateAggs(ctx, query, p)
+	return hits, total, next, err
+}
+
+// paginateAggs is paginate for queries that also ask for aggregations. They
+// are computed again for every page.
+func (x esIndex) paginateAggs(ctx context.Context, query map[string]interface{}, p PageQuery) ([]esHit, int64, string, json.RawMessage, error) {
+	fp, err := fingerprint(query)
+	if err != nil {
+		return nil, 0, "", nil, err
+	}
+
+	var c cursor
+	if p.Cursor != "" {
+		if c, err = decodeCursor(p.Cursor, fp); err != nil {
+			return nil, 0, "", nil, err
+		}
+	} else {
+		c.Query = fp
+		if c.PIT, err = x.openPIT(ctx); err != nil || c.PIT == "" {
+			return nil, 0, "", nil, err
+		}
+	}
+
+	// One hit more than asked tells whether there is a next page
+	size := p.size()
+	query["size"] = size + 1
+	query["pit"] = map[string]interface{}{"id": c.PIT, "keep_alive": cursorKeepAlive}
+	if len(c.After) > 0 {
+		query["search_after"] = c.After
+	}
+
+	r, err := x.runSearch(ctx, query)
+	if err != nil {
+		return nil, 0, "", nil, err
+	}
+	if r == nil {
+		return nil, 0, "", nil, ErrInvalidCursor
+	}
+
+	hits := r.Hits.Hits
+	if len(hits) <= size {
+		x.closePIT(ctx, r.PitID)
+		return hits, r.Hits.Total.Value, "", r.Aggregations, nil
+	}
+	hits = hits[:size]
+	next, err := encodeCursor(cursor{Query: fp, PIT: r.PitID, After: hits[size-1].Sort})
+	if err != nil {
+		return nil, 0, "", nil, err
+	}
+	return hits, r.Hits.Total.Value, next, r.Aggregations, nil
+}
+
+// openPIT opens a point in time on the index, or returns "" when the index
+// does not exist yet.
+func (x esIndex) openPIT(ctx context.Context) (string, error) {
+	res, err := x.client.OpenPointInTime([]string{x.name}, cursorKeepAlive,
+		x.client.OpenPointInTime.WithContext(ctx),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return "", nil
+	}
+	if res.IsError() {
+		return "", fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+// closePIT releases a point in time once the last page is read. Failing is
+// harmless, it expires after cursorKeepAlive anyway.
+func (x esIndex) closePIT(ctx context.Context, id string) {
+	if id == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]string{"id": id})
+	res, err := x.client.ClosePointInTime(
+		x.client.ClosePointInTime.WithContext(ctx),
+		x.client.ClosePointInTime.WithBody(bytes.NewReader(body)),
+	)
+	if err == nil {
+		res.Body.Close()
+	}
+}
+
+// aggregate runs a search for aggregations only and returns them raw. A
+// missing index gives nil.
+func (x esIndex) aggregate(ctx context.Context, query map[string]interface{}) (json.RawMessage, error) {
+	query["size"] = 0
+	_, _, aggs, err := x.searchAggs(ctx, query)
+	return aggs, err
+}
+
+func (x esIndex) create(ctx context.Context, doc interface{}) (string, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := x.client.Index(
+		x.name,
+		bytes.NewReader(data),
+		x.client.Index.WithContext(ctx),
+		x.client.Index.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return "", fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r struct {
+		ID string `json:"_id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+func (x esIndex) update(ctx context.Context, id string, fields map[string]interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"doc": fields})
+	if err != nil {
+		return err
+	}
+
+	res, err := x.client.Update(
+		x.name,
+		id,
+		bytes.NewReader(payload),
+		x.client.Update.WithContext(ctx),
+		x.client.Update.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return ErrNotFound
+	}
+	if res.IsError() {
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+	return nil
+}
+
+// updateIf applies a partial update only if the document has not changed
+// since it was read as hit, returning errVersionConflict otherwise.
+func (x esIndex) updateIf(ctx context.Context, hit esHit, fields map[string]interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"doc": fields})
+	if err != nil {
+		return err
+	}
+
+	res, err := x.client.Update(
+		x.name,
+		hit.ID,
+		bytes.NewReader(payload),
+		x.client.Update.WithContext(ctx),
+		x.client.Update.WithIfSeqNo(int(hit.SeqNo)),
+		x.client.Update.WithIfPrimaryTerm(int(hit.PrimaryTerm)),
+		x.client.Update.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == 404:
+		return ErrNotFound
+	case res.StatusCode == 409:
+		return errVersionConflict
+	case res.IsError():
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+	return nil
+}
+
+func (x esIndex) delete(ctx context.Context, id string) error {
+	res, err := x.client.Delete(
+		x.name,
+		id,
+		x.client.Delete.WithContext(ctx),
+		x.client.Delete.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return ErrNotFound
+	}
+	if res.IsError() {
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+	return nil
+}
+
+// page adds from/size to a query body when they are set.
+func page(query map[string]interface{}, from, size int) map[string]interface{} {
+	if from > 0 {
+		query["from"] = from
+	}
+	if size > 0 {
+		query["size"] = size
+	}
+	return query
+}
+
+// sortClause turns "field:asc" pairs into an Elasticsearch sort array.
+func sortClause(fields []string) []interface{} {
+	var sort []interface{}
+	for _, f := range fields {
+		name, order, found := strings.Cut(f, ":")
+		if !found {
+			order = "asc"
+		}
+		sort = append(sort, map[string]interface{}{
+			name: map[string]interface{}{"order": order},
+		})
+	}
+	return sort
+}
+
+// put indexes a document under a caller chosen id.
+func (x esIndex) put(ctx context.Context, id string, doc interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	res, err := x.client.Index(
+		x.name,
+		bytes.NewReader(data),
+		x.client.Index.WithContext(ctx),
+		x.client.Index.WithDocumentID(id),
+		x.client.Index.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+	return nil
+}
+
+// putNew indexes a document under a caller chosen id unless one is stored
+// there already, in which case it returns errExists. The check and the write
+// are one operation.
+func (x esIndex) putNew(ctx context.Context, id string, doc interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	res, err := x.client.Create(
+		x.name,
+		id,
+		bytes.NewReader(data),
+		x.client.Create.WithContext(ctx),
+		x.client.Create.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 409 {
+		return errExists
+	}
+	if res.IsError() {
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+	return nil
+}
+
+func (x esIndex) deleteByQuery(ctx context.Context, query map[string]interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return err
+	}
+
+	res, err := x.client.DeleteByQuery(
+		[]string{x.name},
+		&buf,
+		x.client.DeleteByQuery.WithContext(ctx),
+		x.client.DeleteByQuery.WithConflicts("proceed"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil
+	}
+	if res.IsError() {
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"golang.elasticsearch/models"
+)
+
+type esAttemptStore struct {
+	index esIndex
+}
+
+// NewElasticAttemptStore returns an AttemptStore backed by the named index,
+// "login_attempts" by default.
+func NewElasticAttemptStore(client *elasticsearch.Client, name string) AttemptStore {
+	return &esAttemptStore{index: esIndex{client: client, name: name}}
+}
+
+// docID escapes the key, which may hold an email address or an IPv6 address,
+// so it can be used in the document URL.
+func docID(key string) string {
+	return url.PathEscape(key)
+}
+
+func (s *esAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := s.index.get(ctx, docID(key), &attempt)
+	if errors.Is(err, ErrNotFound) || (err == nil && attempt.ExpiresAt.Before(time.Now())) {
+		return &models.LoginAttempt{ID: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// incrementScript bumps the counter in place, starting over when the previous
+// window has run out, so concurrent failures are all counted.
+const incrementScript = `
+if (ctx._source.expires_at == null || ZonedDateTime.parse(ctx._source.expires_at).toInstant().toEpochMilli() < params.now_millis) {
+  ctx._source.failures = 0;
+}
+ctx._source.id = params.id;
+ctx._source.failures += 1;
+ctx._source.last_failure = params.now;
+ctx._source.expires_at = params.expires_at;
+`
+
+func (s *esAttemptStore) Fail(ctx context.Context, key string, window time.Duration) (*models.LoginAttempt, error) {
+	now := time.Now().UTC()
+	payload, err := json.Marshal(map[string]interface{}{
+		"scripted_upsert": true,
+		"upsert":          map[string]interface{}{},
+		"script": map[string]interface{}{
+			"lang":   "painless",
+			"source": incrementScript,
+			"params": map[string]interface{}{
+				"id":         key,
+				"now":        now,
+				"now_millis": now.UnixMilli(),
+				"expires_at": now.Add(window),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.index.client
+	res, err := client.Update(
+		s.index.name,
+		docID(key),
+		bytes.NewReader(payload),
+		client.Update.WithContext(ctx),
+		client.Update.WithRefresh("wait_for"),
+		client.Update.WithRetryOnConflict(3),
+		client.Update.WithSource("true"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r struct {
+		Get struct {
+			Source models.LoginAttempt `json:"_source"`
+		} `json:"get"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r.Get.Source, nil
+}
+
+func (s *esAttemptStore) Reset(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := s.index.delete(ctx, docID(key)); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *esAttemptStore) Cleanup(ctx context.Context, now time.Time) error {
+	return s.index.deleteByQuery(ctx, map[string]interface{}{
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{
+				"expires_at": map[string]interface{}{"lt": now},
+			},
+		},
+	})
+}
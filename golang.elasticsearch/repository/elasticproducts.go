@@ -0,0 +1,413 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"golang.elasticsearch/models"
+)
+
+type esProductRepository struct {
+	index esIndex
+}
+
+// NewElasticProductRepository returns a ProductRepository backed by the named
+// index, "products" by default.
+func NewElasticProductRepository(client *elasticsearch.Client, name string) ProductRepository {
+	return &esProductRepository{index: esIndex{client: client, name: name}}
+}
+
+func (r *esProductRepository) Get(ctx context.Context, id string) (*models.Product, error) {
+	var product models.Product
+	if err := r.index.get(ctx, id, &product); err != nil {
+		return nil, err
+	}
+	product.ID = id
+	return &product, nil
+}
+
+func (r *esProductRepository) Search(ctx context.Context, q ProductQuery) ([]models.Product, int64, error) {
+	hits, total, err := r.index.search(ctx, page(productSearch(q), q.From, q.Size))
+	if err != nil {
+		return nil, 0, err
+	}
+	products, err := productsFromHits(hits)
+	return products, total, err
+}
+
+// List pages through the products matching q, in q.Sort order or by
+// descriptions.
+func (r *esProductRepository) List(ctx context.Context, q ProductQuery, p PageQuery) (*Page[models.Product], error) {
+	if len(q.Sort) == 0 {
+		q.Sort = []string{"descriptions.keyword:asc"}
+	}
+	hits, total, next, err := r.index.paginate(ctx, productSearch(q), p)
+	if err != nil {
+		return nil, err
+	}
+	products, err := productsFromHits(hits)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.Product]{Items: products, Total: total, NextCursor: next}, nil
+}
+
+// productSearch is the query body for q, without paging.
+func productSearch(q ProductQuery) map[string]interface{} {
+	match := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if q.Text != "" {
+		match = map[string]interface{}{
+			"match": map[string]interface{}{"descriptions": q.Text},
+		}
+	}
+	if q.Level != "" {
+		match = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":   match,
+				"filter": stockLevelFilter(q.Level),
+			},
+		}
+	}
+
+	query := map[string]interface{}{"query": match}
+	if len(q.Sort) > 0 {
+		query["sort"] = sortClause(q.Sort)
+	}
+	return query
+}
+
+func productsFromHits(hits []esHit) ([]models.Product, error) {
+	products := make([]models.Product, 0, len(hits))
+	for _, hit := range hits {
+		var product models.Product
+		if err := json.Unmarshal(hit.Source, &product); err != nil {
+			return nil, err
+		}
+		product.ID = hit.ID
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+// maxFacets caps the category facets returned by a catalogue search.
+const maxFacets = 50
+
+var catalogueSorts = map[string][]string{
+	SortPriceAsc:  {"sellprice:asc"},
+	SortPriceDesc: {"sellprice:desc"},
+	SortNameAsc:   {"descriptions.keyword:asc"},
+	SortNameDesc:  {"descriptions.keyword:desc"},
+	SortNewest:    {"created_at:desc"},
+}
+
+func (r *esProductRepository) Catalogue(ctx context.Context, q CatalogueQuery, p PageQuery) (*CatalogueResult, error) {
+	must, filter := catalogueClauses(q)
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"must": must, "filter": filter},
+		},
+		"aggs": map[string]interface{}{
+			"categories": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "category.keyword", "size": maxFacets},
+			},
+		},
+		"highlight": map[string]interface{}{
+			"pre_tags":  []string{"<em>"},
+			"post_tags": []string{"</em>"},
+			"fields": map[string]interface{}{
+				"descriptions": map[string]interface{}{},
+				"category":     map[string]interface{}{},
+			},
+		},
+	}
+	// As a post filter the category choice narrows the hits but not the facets
+	if len(q.Categories) > 0 {
+		query["post_filter"] = categoryFilter(q.Categories)
+	}
+	sort, ok := catalogueSorts[q.Sort]
+	if !ok {
+		sort = []string{"_score:desc"}
+	}
+	query["sort"] = sortClause(sort)
+
+	hits, total, next, raw, err := r.index.paginateAggs(ctx, query, p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CatalogueResult{Hits: make([]CatalogueHit, 0, len(hits)), Total: total, NextCursor: next, Categories: []Facet{}}
+	for _, hit := range hits {
+		var product models.Product
+		if err := json.Unmarshal(hit.Source, &product); err != nil {
+			return nil, err
+		}
+		product.ID = hit.ID
+		result.Hits = append(result.Hits, CatalogueHit{Product: product, Highlights: hit.Highlight})
+	}
+
+	if raw != nil {
+		var aggs struct {
+			Categories struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int64  `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"categories"`
+		}
+		if err := json.Unmarshal(raw, &aggs); err != nil {
+			return nil, err
+		}
+		for _, b := range aggs.Categories.Buckets {
+			result.Categories = append(result.Categories, Facet{Value: b.Key, Count: b.DocCount})
+		}
+	}
+	return result, nil
+}
+
+// eachPageSize is how many products Each reads per request.
+const eachPageSize = 1000
+
+func (r *esProductRepository) Each(ctx context.Context, q CatalogueQuery, fn func(models.Product) error) error {
+	sort, ok := catalogueSorts[q.Sort]
+	if !ok {
+		sort = []string{"_score:desc"}
+	}
+
+	p := PageQuery{Size: eachPageSize}
+	for {
+		must, filter := catalogueClauses(q)
+		if len(q.Categories) > 0 {
+			filter = append(filter, categoryFilter(q.Categories))
+		}
+		query := map[string]interface{}{
+			"query": map[string]interface{}{
+				"bool": map[string]interface{}{"must": must, "filter": filter},
+			},
+			"sort": sortClause(sort),
+		}
+
+		hits, _, next, err := r.index.paginate(ctx, query, p)
+		if err != nil {
+			return err
+		}
+		products, err := productsFromHits(hits)
+		if err != nil {
+			return err
+		}
+		for _, product := range products {
+			if err := fn(product); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		p.Cursor = next
+	}
+}
+
+// catalogueClauses are the scoring and filtering clauses of q, except for the
+// category which Catalogue applies separately.
+func catalogueClauses(q CatalogueQuery) ([]interface{}, []interface{}) {
+	must := []interface{}{}
+	if q.Text != "" {
+		must = append(must, map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":     q.Text,
+				"fields":    []string{"descriptions^2", "category"},
+				"fuzziness": "AUTO",
+			},
+		})
+	}
+
+	filter := []interface{}{}
+	price := map[string]interface{}{}
+	if q.MinPrice != nil {
+		price["gte"] = *q.MinPrice
+	}
+	if q.MaxPrice != nil {
+		price["lte"] = *q.MaxPrice
+	}
+	if len(price) > 0 {
+		filter = append(filter, map[string]interface{}{
+			"range": map[string]interface{}{"sellprice": price},
+		})
+	}
+	if q.InStock {
+		filter = append(filter, map[string]interface{}{
+			"range": map[string]interface{}{"qty": map[string]interface{}{"gt": 0}},
+		})
+	}
+	return must, filter
+}
+
+func categoryFilter(categories []string) map[string]interface{} {
+	return map[string]interface{}{
+		"terms": map[string]interface{}{"category.keyword": categories},
+	}
+}
+
+func (r *esProductRepository) Suggest(ctx context.Context, prefix string, size int) ([]Suggestion, error) {
+	// bool_prefix over the search_as_you_type subfields matches the last word
+	// as a prefix and scores words typed in order higher through the shingles
+	fields := []string{
+		"descriptions.suggest^2", "descriptions.suggest._2gram^2", "descriptions.suggest._3gram^2",
+		"category.suggest", "category.suggest._2gram", "category.suggest._3gram",
+	}
+	query := map[string]interface{}{
+		"_source": []string{"descriptions", "category", "productpicture", "images"},
+		"query": map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  prefix,
+				"type":   "bool_prefix",
+				"fields": fields,
+			},
+		},
+	}
+
+	hits, _, err := r.index.search(ctx, page(query, 0, size))
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]Suggestion, 0, len(hits))
+	for _, hit := range hits {
+		var s Suggestion
+		if err := json.Unmarshal(hit.Source, &s); err != nil {
+			return nil, err
+		}
+		s.ID = hit.ID
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, nil
+}
+
+func (r *esProductRepository) BySKU(ctx context.Context, skus []string) (map[string]models.Product, error) {
+	found := map[string]models.Product{}
+	if len(skus) == 0 {
+		return found, nil
+	}
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"terms": map[string]interface{}{"sku": skus},
+		},
+	}
+	hits, _, err := r.index.search(ctx, page(query, 0, len(skus)))
+	if err != nil {
+		return nil, err
+	}
+	products, err := productsFromHits(hits)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		found[product.Sku] = product
+	}
+	return found, nil
+}
+
+func (r *esProductRepository) Bulk(ctx context.Context) (ProductBulk, error) {
+	indexer, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:  r.index.client,
+		Index:   r.index.name,
+		Refresh: "wait_for",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &esProductBulk{indexer: indexer}, nil
+}
+
+// esProductBulk feeds a BulkIndexer, which flushes in the background.
+type esProductBulk struct {
+	indexer esutil.BulkIndexer
+}
+
+func (b *esProductBulk) Create(ctx context.Context, product models.Product, done func(id string, err error)) error {
+	body, err := json.Marshal(product)
+	if err != nil {
+		return err
+	}
+	return b.add(ctx, "create", "", body, done)
+}
+
+func (b *esProductBulk) Update(ctx context.Context, id string, fields map[string]interface{}, done func(id string, err error)) error {
+	body, err := json.Marshal(map[string]interface{}{"doc": fields})
+	if err != nil {
+		return err
+	}
+	return b.add(ctx, "update", id, body, done)
+}
+
+func (b *esProductBulk) add(ctx context.Context, action, id string, body []byte, done func(id string, err error)) error {
+	return b.indexer.Add(ctx, esutil.BulkIndexerItem{
+		Action:     action,
+		DocumentID: id,
+		Body:       bytes.NewReader(body),
+		OnSuccess: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem) {
+			done(res.DocumentID, nil)
+		},
+		OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+			switch {
+			case err != nil:
+			case res.Status == 404:
+				err = ErrNotFound
+			default:
+				err = fmt.Errorf("error response from ES: %s: %s", res.Error.Type, res.Error.Reason)
+			}
+			done(id, err)
+		},
+	})
+}
+
+func (b *esProductBulk) Close(ctx context.Context) error {
+	return b.indexer.Close(ctx)
+}
+
+func (r *esProductRepository) Create(ctx context.Context, product *models.Product) (string, error) {
+	id, err := r.index.create(ctx, product)
+	if err != nil {
+		return "", err
+	}
+	product.ID = id
+	return id, nil
+}
+
+func (r *esProductRepository) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	return r.index.update(ctx, id, fields)
+}
+
+func (r *esProductRepository) Delete(ctx context.Context, id string) error {
+	return r.index.delete(ctx, id)
+}
+
+// stockLevelScript mirrors models.Product.StockLevel.
+const stockLevelScript = `
+double qty = doc['qty'].size() == 0 ? 0 : doc['qty'].value;
+double critical = doc['criticalstocks'].size() == 0 ? 0 : doc['criticalstocks'].value;
+double alert = doc['alertstocks'].size() == 0 ? 0 : doc['alertstocks'].value;
+String level = 'ok';
+if (critical > 0 && qty <= critical) {
+  level = 'critical';
+} else if (alert > 0 && qty <= alert) {
+  level = 'low';
+}
+return level == params.level;
+`
+
+// stockLevelFilter compares each product quantity with its own thresholds,
+// which a plain range query cannot do.
+func stockLevelFilter(level string) map[string]interface{} {
+	return map[string]interface{}{
+		"script": map[string]interface{}{
+			"script": map[string]interface{}{
+				"source": stockLevelScript,
+				"params": map[string]interface{}{"level": level},
+			},
+		},
+	}
+}
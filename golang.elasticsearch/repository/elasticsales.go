@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"golang.elasticsearch/models"
+)
+
+type esSalesRepository struct {
+	index esIndex
+}
+
+// NewElasticSalesRepository returns a SalesRepository backed by the named
+// index, "sales" by default.
+func NewElasticSalesRepository(client *elasticsearch.Client, name string) SalesRepository {
+	return &esSalesRepository{index: esIndex{client: client, name: name}}
+}
+
+func (r *esSalesRepository) Get(ctx context.Context, id string) (*models.Sale, error) {
+	var sale models.Sale
+	if err := r.index.get(ctx, id, &sale); err != nil {
+		return nil, err
+	}
+	sale.ID = id
+	return &sale, nil
+}
+
+func (r *esSalesRepository) Search(ctx context.Context, q SalesQuery) ([]models.Sale, int64, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+		"sort":  sortClause([]string{"salesdate:asc"}),
+	}
+
+	hits, total, err := r.index.search(ctx, page(query, 0, q.Size))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sales := make([]models.Sale, 0, len(hits))
+	for _, hit := range hits {
+		var sale models.Sale
+		if err := json.Unmarshal(hit.Source, &sale); err != nil {
+			return nil, 0, err
+		}
+		sale.ID = hit.ID
+		sales = append(sales, sale)
+	}
+	return sales, total, nil
+}
+
+// maxCategories caps the categories listed in each bucket of a summary.
+const maxCategories = 100
+
+func (r *esSalesRepository) Summarize(ctx context.Context, q SalesSummaryQuery) ([]SalesBucket, error) {
+	if err := q.check(); err != nil {
+		return nil, err
+	}
+	histogram := map[string]interface{}{
+		"field":             "salesdate",
+		"calendar_interval": q.Interval,
+		"min_doc_count":     0,
+	}
+
+	dates := map[string]interface{}{}
+	if !q.From.IsZero() {
+		dates["gte"] = q.From.Format(time.RFC3339)
+	}
+	if !q.To.IsZero() {
+		dates["lt"] = q.To.Format(time.RFC3339)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() {
+		histogram["extended_bounds"] = map[string]interface{}{
+			"min": q.From.UnixMilli(),
+			"max": q.To.UnixMilli() - 1,
+		}
+	}
+
+	aggs := map[string]interface{}{
+		"amount": map[string]interface{}{"sum": map[string]interface{}{"field": "amount"}},
+	}
+	if q.ByCategory {
+		aggs["items"] = map[string]interface{}{
+			"nested": map[string]interface{}{"path": "items"},
+			"aggs": map[string]interface{}{
+				"categories": map[string]interface{}{
+					"terms": map[string]interface{}{
+						"field":   "items.category",
+						"missing": Uncategorized,
+						"size":    maxCategories,
+					},
+					"aggs": map[string]interface{}{
+						"amount": map[string]interface{}{"sum": map[string]interface{}{"field": "items.amount"}},
+					},
+				},
+			},
+		}
+	}
+
+	match := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if len(dates) > 0 {
+		match = map[string]interface{}{
+			"range": map[string]interface{}{"salesdate": dates},
+		}
+	}
+
+	query := map[string]interface{}{
+		"query": match,
+		"aggs": map[string]interface{}{
+			"sales": map[string]interface{}{
+				"date_histogram": histogram,
+				"aggs":           aggs,
+			},
+		},
+	}
+
+	raw, err := r.index.aggregate(ctx, query)
+	if err != nil || raw == nil {
+		return []SalesBucket{}, err
+	}
+
+	var result struct {
+		Sales struct {
+			Buckets []struct {
+				Key      int64 `json:"key"`
+				DocCount int64 `json:"doc_count"`
+				Amount   struct {
+					Value float64 `json:"value"`
+				} `json:"amount"`
+				Items struct {
+					Categories struct {
+						Buckets []struct {
+							Key    string `json:"key"`
+							Amount struct {
+								Value float64 `json:"value"`
+							} `json:"amount"`
+						} `json:"buckets"`
+					} `json:"categories"`
+				} `json:"items"`
+			} `json:"buckets"`
+		} `json:"sales"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]SalesBucket, 0, len(result.Sales.Buckets))
+	for _, b := range result.Sales.Buckets {
+		bucket := SalesBucket{
+			Start:  time.UnixMilli(b.Key).UTC(),
+			Amount: RoundCents(b.Amount.Value),
+			Count:  b.DocCount,
+		}
+		if q.ByCategory {
+			bucket.Categories = map[string]float64{}
+			for _, c := range b.Items.Categories.Buckets {
+				bucket.Categories[c.Key] = RoundCents(c.Amount.Value)
+			}
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+func (r *esSalesRepository) Create(ctx context.Context, sale *models.Sale) (string, error) {
+	id, err := r.index.create(ctx, sale)
+	if err != nil {
+		return "", err
+	}
+	sale.ID = id
+	return id, nil
+}
+
+func (r *esSalesRepository) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	return r.index.update(ctx, id, fields)
+}
+
+func (r *esSalesRepository) Delete(ctx context.Context, id string) error {
+	return r.index.delete(ctx, id)
+}
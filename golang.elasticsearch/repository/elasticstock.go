@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"golang.elasticsearch/models"
+)
+
+type esStockRepository struct {
+	products  esIndex
+	movements esIndex
+}
+
+// NewElasticStockRepository returns a StockRepository that updates the
+// products index and records movements in the movements index,
+// "stock_movements" by default.
+func NewElasticStockRepository(client *elasticsearch.Client, products string, movements string) StockRepository {
+	return &esStockRepository{
+		products:  esIndex{client: client, name: products},
+		movements: esIndex{client: client, name: movements},
+	}
+}
+
+func (r *esStockRepository) Move(ctx context.Context, m *models.StockMovement) error {
+	balance, err := r.apply(ctx, m.ProductID, m.Delta(), m.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	m.Balance = balance
+	id, err := r.movements.create(ctx, m)
+	if err != nil {
+		// Without its ledger entry the quantity change has to be taken back
+		if _, undoErr := r.apply(ctx, m.ProductID, -m.Delta(), time.Now().UTC()); undoErr != nil {
+			log.Printf("Error reverting stock of product %s by %v: %s", m.ProductID, -m.Delta(), undoErr)
+		}
+		return err
+	}
+	m.ID = id
+	return nil
+}
+
+// apply adds delta to the product quantity with a conditional write, reading
+// the product again whenever another writer got there first.
+func (r *esStockRepository) apply(ctx context.Context, productID string, delta float64, at time.Time) (float64, error) {
+	for attempt := 0; attempt < maxWriteAttempts; attempt++ {
+		var product models.Product
+		hit, err := r.products.getVersioned(ctx, productID, &product)
+		if err != nil {
+			return 0, err
+		}
+
+		balance := product.Qty + delta
+		if balance < 0 {
+			return 0, ErrInsufficientStock
+		}
+
+		err = r.products.updateIf(ctx, hit, map[string]interface{}{
+			"qty":        balance,
+			"updated_at": at,
+		})
+		if errors.Is(err, errVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return balance, nil
+	}
+	return 0, ErrConflict
+}
+
+func (r *esStockRepository) History(ctx context.Context, q StockQuery, p PageQuery) (*Page[models.StockMovement], error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"product_id": q.ProductID},
+		},
+		"sort": sortClause([]string{"created_at:desc"}),
+	}
+
+	hits, total, next, err := r.movements.paginate(ctx, query, p)
+	if err != nil {
+		return nil, err
+	}
+
+	movements := make([]models.StockMovement, 0, len(hits))
+	for _, hit := range hits {
+		var m models.StockMovement
+		if err := json.Unmarshal(hit.Source, &m); err != nil {
+			return nil, err
+		}
+		m.ID = hit.ID
+		movements = append(movements, m)
+	}
+	return &Page[models.StockMovement]{Items: movements, Total: total, NextCursor: next}, nil
+}
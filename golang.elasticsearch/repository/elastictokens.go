@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"golang.elasticsearch/models"
+)
+
+// maxSessions bounds how many sessions of one user Sessions returns.
+const maxSessions = 10000
+
+type esTokenStore struct {
+	refresh esIndex
+	revoked esIndex
+}
+
+// NewElasticTokenStore returns a TokenStore backed by the refresh and revoked
+// indices, "refresh_tokens" and "revoked_tokens" by default.
+func NewElasticTokenStore(client *elasticsearch.Client, refresh string, revoked string) TokenStore {
+	return &esTokenStore{
+		refresh: esIndex{client: client, name: refresh},
+		revoked: esIndex{client: client, name: revoked},
+	}
+}
+
+func (s *esTokenStore) SaveRefresh(ctx context.Context, token *models.RefreshToken) error {
+	return s.refresh.put(ctx, token.ID, token)
+}
+
+func (s *esTokenStore) GetRefresh(ctx context.Context, id string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := s.refresh.get(ctx, id, &token); err != nil {
+		return nil, err
+	}
+	token.ID = id
+	return &token, nil
+}
+
+func (s *esTokenStore) UseRefresh(ctx context.Context, id string) error {
+	// The script makes the check-and-set atomic: a second caller gets a noop.
+	body := `{"script":{"lang":"painless","source":"if (ctx._source.used == true) { ctx.op = 'noop' } else { ctx._source.used = true }"}}`
+
+	res, err := s.refresh.client.Update(
+		s.refresh.name,
+		id,
+		strings.NewReader(body),
+		s.refresh.client.Update.WithContext(ctx),
+		s.refresh.client.Update.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return ErrNotFound
+	}
+	if res.IsError() {
+		return fmt.Errorf("error response from ES: %s", res.String())
+	}
+
+	var r struct {
+		Result string `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return err
+	}
+	if r.Result == "noop" {
+		return ErrTokenReused
+	}
+	return nil
+}
+
+func (s *esTokenStore) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	return s.revoked.put(ctx, id, models.RevokedToken{
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+func (s *esTokenStore) IsRevoked(ctx context.Context, ids ...string) (bool, error) {
+	var values []string
+	for _, id := range ids {
+		if id != "" {
+			values = append(values, id)
+		}
+	}
+	if len(values) == 0 {
+		return false, nil
+	}
+
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{"values": values},
+		},
+	}
+	_, total, err := s.revoked.search(ctx, query)
+	return total > 0, err
+}
+
+// UseOnce keeps used ids with the revocations, so Cleanup drops them too.
+func (s *esTokenStore) UseOnce(ctx context.Context, id string, expiresAt time.Time) error {
+	err := s.revoked.putNew(ctx, id, models.RevokedToken{
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+	})
+	if errors.Is(err, errExists) {
+		return ErrTokenReused
+	}
+	return err
+}
+
+func (s *esTokenStore) Sessions(ctx context.Context, userID string) (map[string]time.Time, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+					map[string]interface{}{"range": map[string]interface{}{
+						"expires_at": map[string]interface{}{"gt": time.Now().UTC()},
+					}},
+				},
+			},
+		},
+		"aggs": map[string]interface{}{
+			"families": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "family", "size": maxSessions},
+				"aggs": map[string]interface{}{
+					"expires_at": map[string]interface{}{"max": map[string]interface{}{"field": "expires_at"}},
+				},
+			},
+		},
+	}
+
+	sessions := make(map[string]time.Time)
+	raw, err := s.refresh.aggregate(ctx, query)
+	if err != nil || raw == nil {
+		return sessions, err
+	}
+
+	var result struct {
+		Families struct {
+			Buckets []struct {
+				Key       string `json:"key"`
+				ExpiresAt struct {
+					Value float64 `json:"value"`
+				} `json:"expires_at"`
+			} `json:"buckets"`
+		} `json:"families"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	for _, b := range result.Families.Buckets {
+		sessions[b.Key] = time.UnixMilli(int64(b.ExpiresAt.Value)).UTC()
+	}
+	return sessions, nil
+}
+
+func (s *esTokenStore) Cleanup(ctx context.Context, now time.Time) error {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{
+				"expires_at": map[string]interface{}{"lt": now},
+			},
+		},
+	}
+	if err := s.refresh.deleteByQuery(ctx, query); err != nil {
+		return err
+	}
+	return s.revoked.deleteByQuery(ctx, query)
+}
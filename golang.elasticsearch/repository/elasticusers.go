@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"golang.elasticsearch/models"
+)
+
+type esUserRepository struct {
+	index esIndex
+}
+
+// NewElasticUserRepository returns a UserRepository backed by the named
+// index, "users" by default.
+func NewElasticUserRepository(client *elasticsearch.Client, name string) UserRepository {
+	return &esUserRepository{index: esIndex{client: client, name: name}}
+}
+
+func (r *esUserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	if err := r.index.get(ctx, id, &user); err != nil {
+		return nil, err
+	}
+	user.ID = id
+	return &user, nil
+}
+
+func (r *esUserRepository) Search(ctx context.Context, q UserQuery) ([]models.User, int64, error) {
+	hits, total, err := r.index.search(ctx, page(userSearch(q), q.From, q.Size))
+	if err != nil {
+		return nil, 0, err
+	}
+	users, err := usersFromHits(hits)
+	return users, total, err
+}
+
+// List pages through the users matching q by username.
+func (r *esUserRepository) List(ctx context.Context, q UserQuery, p PageQuery) (*Page[models.User], error) {
+	query := userSearch(q)
+	query["sort"] = sortClause([]string{"username:asc"})
+
+	hits, total, next, err := r.index.paginate(ctx, query, p)
+	if err != nil {
+		return nil, err
+	}
+	users, err := usersFromHits(hits)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.User]{Items: users, Total: total, NextCursor: next}, nil
+}
+
+// userSearch is the query body for q, without paging.
+func userSearch(q UserQuery) map[string]interface{} {
+	var filters []interface{}
+	if q.Username != "" {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"username": q.Username},
+		})
+	}
+	if q.Email != "" {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"email": q.Email},
+		})
+	}
+	if q.Mailtoken != "" {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"mailtoken": q.Mailtoken},
+		})
+	}
+
+	if q.Role != "" {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"roles": q.Role},
+		})
+	}
+
+	return map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filters},
+		},
+	}
+}
+
+func usersFromHits(hits []esHit) ([]models.User, error) {
+	users := make([]models.User, 0, len(hits))
+	for _, hit := range hits {
+		var user models.User
+		if err := json.Unmarshal(hit.Source, &user); err != nil {
+			return nil, err
+		}
+		user.ID = hit.ID
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (r *esUserRepository) Create(ctx context.Context, user *models.User) (string, error) {
+	id, err := r.index.create(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	user.ID = id
+	return id, nil
+}
+
+func (r *esUserRepository) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	return r.index.update(ctx, id, fields)
+}
+
+func (r *esUserRepository) Modify(ctx context.Context, id string, change func(*models.User) (map[string]interface{}, error)) error {
+	for attempt := 0; attempt < maxWriteAttempts; attempt++ {
+		var user models.User
+		hit, err := r.index.getVersioned(ctx, id, &user)
+		if err != nil {
+			return err
+		}
+		user.ID = id
+
+		fields, err := change(&user)
+		if err != nil || fields == nil {
+			return err
+		}
+
+		err = r.index.updateIf(ctx, hit, fields)
+		if errors.Is(err, errVersionConflict) {
+			continue
+		}
+		return err
+	}
+	return ErrConflict
+}
+
+func (r *esUserRepository) Delete(ctx context.Context, id string) error {
+	return r.index.delete(ctx, id)
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+// memoryStore keeps documents in insertion order so in-memory searches are
+// deterministic. It backs the in-memory repositories used without a cluster.
+type memoryStore[T any] struct {
+	mu   sync.RWMutex
+	seq  int
+	ids  []string
+	docs map[string]T
+}
+
+func newMemoryStore[T any]() *memoryStore[T] {
+	return &memoryStore[T]{docs: make(map[string]T)}
+}
+
+func (s *memoryStore[T]) get(id string) (T, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return doc, ErrNotFound
+	}
+	return doc, nil
+}
+
+// all returns a snapshot of the stored documents with their ids.
+func (s *memoryStore[T]) all() ([]string, []T) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, len(s.ids))
+	docs := make([]T, len(s.ids))
+	copy(ids, s.ids)
+	for i, id := range s.ids {
+		docs[i] = s.docs[id]
+	}
+	return ids, docs
+}
+
+func (s *memoryStore[T]) create(doc T, setID func(*T, string)) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	id := strconv.Itoa(s.seq)
+	setID(&doc, id)
+	s.ids = append(s.ids, id)
+	s.docs[id] = doc
+	return id
+}
+
+// update applies a partial document the same way an Elasticsearch "doc"
+// update does: top level fields are replaced, the rest are kept.
+func (s *memoryStore[T]) update(id string, fields map[string]interface{}) error {
+	return s.modify(id, func(T) (map[string]interface{}, error) { return fields, nil })
+}
+
+// modify is update with fields computed by change from the stored document,
+// holding the lock so nothing is written in between. A nil map from change
+// leaves the document as it is.
+func (s *memoryStore[T]) modify(id string, change func(T) (map[string]interface{}, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	fields, err := change(doc)
+	if err != nil || fields == nil {
+		return err
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var merged map[string]interface{}
+	if err := json.Unmarshal(raw, &merged); err != nil {
+		return err
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	if raw, err = json.Marshal(merged); err != nil {
+		return err
+	}
+
+	var updated T
+	if err := json.Unmarshal(raw, &updated); err != nil {
+		return err
+	}
+	s.docs[id] = updated
+	return nil
+}
+
+func (s *memoryStore[T]) delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.docs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.docs, id)
+	for i, v := range s.ids {
+		if v == id {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// window slices a result set the way from/size does.
+func window[T any](items []T, from, size int) []T {
+	if from >= len(items) {
+		return []T{}
+	}
+	items = items[from:]
+	if size > 0 && size < len(items) {
+		items = items[:size]
+	}
+	return items
+}
+
+// memoryPage pages through items with cursors holding an offset, tied to
+// the query q that produced items.
+func memoryPage[T any](items []T, q interface{}, p PageQuery) (*Page[T], error) {
+	fp, err := fingerprint(q)
+	if err != nil {
+		return nil, err
+	}
+	from := 0
+	if p.Cursor != "" {
+		c, err := decodeCursor(p.Cursor, fp)
+		if err != nil {
+			return nil, err
+		}
+		from = c.Offset
+	}
+
+	size := p.size()
+	page := &Page[T]{Items: window(items, from, size), Total: int64(len(items))}
+	if from+size < len(items) {
+		if page.NextCursor, err = encodeCursor(cursor{Query: fp, Offset: from + size}); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
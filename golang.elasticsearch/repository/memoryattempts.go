@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.elasticsearch/models"
+)
+
+type memoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+// NewMemoryAttemptStore returns an AttemptStore that needs no cluster.
+func NewMemoryAttemptStore() AttemptStore {
+	return &memoryAttemptStore{attempts: make(map[string]models.LoginAttempt)}
+}
+
+func (s *memoryAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.ExpiresAt.Before(time.Now()) {
+		return &models.LoginAttempt{ID: key}, nil
+	}
+	return &attempt, nil
+}
+
+func (s *memoryAttemptStore) Fail(ctx context.Context, key string, window time.Duration) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.ExpiresAt.Before(now) {
+		attempt = models.LoginAttempt{ID: key}
+	}
+	attempt.Failures++
+	attempt.LastFailure = now
+	attempt.ExpiresAt = now.Add(window)
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (s *memoryAttemptStore) Reset(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.attempts, key)
+	}
+	return nil
+}
+
+func (s *memoryAttemptStore) Cleanup(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, attempt := range s.attempts {
+		if attempt.ExpiresAt.Before(now) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.elasticsearch/models"
+)
+
+type memoryProductRepository struct {
+	store *memoryStore[models.Product]
+}
+
+// NewMemoryProductRepository returns a ProductRepository that needs no cluster.
+func NewMemoryProductRepository() ProductRepository {
+	return &memoryProductRepository{store: newMemoryStore[models.Product]()}
+}
+
+func (r *memoryProductRepository) Get(ctx context.Context, id string) (*models.Product, error) {
+	product, err := r.store.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *memoryProductRepository) Search(ctx context.Context, q ProductQuery) ([]models.Product, int64, error) {
+	products := r.matching(q)
+	return window(products, q.From, q.Size), int64(len(products)), nil
+}
+
+func (r *memoryProductRepository) List(ctx context.Context, q ProductQuery, p PageQuery) (*Page[models.Product], error) {
+	if len(q.Sort) == 0 {
+		q.Sort = []string{"descriptions.keyword:asc"}
+	}
+	q.From, q.Size = 0, 0
+	return memoryPage(r.matching(q), q, p)
+}
+
+// matching filters and sorts the products for q, without paging.
+func (r *memoryProductRepository) matching(q ProductQuery) []models.Product {
+	_, docs := r.store.all()
+	text := strings.ToLower(q.Text)
+
+	products := []models.Product{}
+	for _, product := range docs {
+		if text != "" && !strings.Contains(strings.ToLower(product.Descriptions), text) {
+			continue
+		}
+		if q.Level != "" && product.StockLevel(product.Qty) != q.Level {
+			continue
+		}
+		products = append(products, product)
+	}
+
+	for _, s := range q.Sort {
+		if strings.HasPrefix(s, "descriptions") {
+			desc := strings.HasSuffix(s, ":desc")
+			sort.SliceStable(products, func(i, j int) bool {
+				if desc {
+					return products[i].Descriptions > products[j].Descriptions
+				}
+				return products[i].Descriptions < products[j].Descriptions
+			})
+		}
+	}
+	return products
+}
+
+// Catalogue matches on substrings rather than with fuzziness, which is close
+// enough for tests.
+func (r *memoryProductRepository) Catalogue(ctx context.Context, q CatalogueQuery, p PageQuery) (*CatalogueResult, error) {
+	hits, counts := r.catalogueMatches(q)
+
+	facets := []Facet{}
+	for value, count := range counts {
+		facets = append(facets, Facet{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	if len(facets) > maxFacets {
+		facets = facets[:maxFacets]
+	}
+
+	page, err := memoryPage(hits, q, p)
+	if err != nil {
+		return nil, err
+	}
+	return &CatalogueResult{Hits: page.Items, Total: page.Total, NextCursor: page.NextCursor, Categories: facets}, nil
+}
+
+func (r *memoryProductRepository) Each(ctx context.Context, q CatalogueQuery, fn func(models.Product) error) error {
+	hits, _ := r.catalogueMatches(q)
+	for _, hit := range hits {
+		if err := fn(hit.Product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// catalogueMatches are the sorted hits for q, with the category counts
+// taken before the category filter.
+func (r *memoryProductRepository) catalogueMatches(q CatalogueQuery) ([]CatalogueHit, map[string]int64) {
+	_, docs := r.store.all()
+	text := strings.ToLower(q.Text)
+
+	counts := map[string]int64{}
+	hits := []CatalogueHit{}
+	for _, product := range docs {
+		highlights := map[string][]string{}
+		if text != "" {
+			for field, value := range map[string]string{"descriptions": product.Descriptions, "category": product.Category} {
+				if marked, ok := highlight(value, text); ok {
+					highlights[field] = []string{marked}
+				}
+			}
+			if len(highlights) == 0 {
+				continue
+			}
+		}
+		if q.MinPrice != nil && product.Sellprice < *q.MinPrice {
+			continue
+		}
+		if q.MaxPrice != nil && product.Sellprice > *q.MaxPrice {
+			continue
+		}
+		if q.InStock && product.Qty <= 0 {
+			continue
+		}
+		if product.Category != "" {
+			counts[product.Category]++
+		}
+		if len(q.Categories) > 0 && !slices.Contains(q.Categories, product.Category) {
+			continue
+		}
+		if len(highlights) == 0 {
+			highlights = nil
+		}
+		hits = append(hits, CatalogueHit{Product: product, Highlights: highlights})
+	}
+
+	less := map[string]func(a, b models.Product) bool{
+		SortPriceAsc:  func(a, b models.Product) bool { return a.Sellprice < b.Sellprice },
+		SortPriceDesc: func(a, b models.Product) bool { return a.Sellprice > b.Sellprice },
+		SortNameAsc:   func(a, b models.Product) bool { return a.Descriptions < b.Descriptions },
+		SortNameDesc:  func(a, b models.Product) bool { return a.Descriptions > b.Descriptions },
+		SortNewest:    func(a, b models.Product) bool { return a.CreatedAt.After(b.CreatedAt) },
+	}[q.Sort]
+	if less != nil {
+		sort.SliceStable(hits, func(i, j int) bool { return less(hits[i].Product, hits[j].Product) })
+	}
+	return hits, counts
+}
+
+// highlight wraps the first case-insensitive occurrence of text in value.
+func highlight(value, text string) (string, bool) {
+	i := strings.Index(strings.ToLower(value), text)
+	if i < 0 {
+		return "", false
+	}
+	return value[:i] + "<em>" + value[i:i+len(text)] + "</em>" + value[i+len(text):], true
+}
+
+// Suggest needs every word of prefix to start a word of the descriptions or
+// the category. Description matches rank first, then shorter descriptions.
+func (r *memoryProductRepository) Suggest(ctx context.Context, prefix string, size int) ([]Suggestion, error) {
+	_, docs := r.store.all()
+	terms := strings.Fields(strings.ToLower(prefix))
+
+	type ranked struct {
+		suggestion Suggestion
+		inDesc     bool
+	}
+	matches := []ranked{}
+	for _, product := range docs {
+		inDesc := startsWords(product.Descriptions, terms)
+		if len(terms) == 0 || !inDesc && !startsWords(product.Category, terms) {
+			continue
+		}
+		matches = append(matches, ranked{Suggestion{
+			ID:             product.ID,
+			Descriptions:   product.Descriptions,
+			Category:       product.Category,
+			Productpicture: product.Productpicture,
+			Images:         product.Images,
+		}, inDesc})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.inDesc != b.inDesc {
+			return a.inDesc
+		}
+		if len(a.suggestion.Descriptions) != len(b.suggestion.Descriptions) {
+			return len(a.suggestion.Descriptions) < len(b.suggestion.Descriptions)
+		}
+		return a.suggestion.Descriptions < b.suggestion.Descriptions
+	})
+
+	suggestions := make([]Suggestion, 0, len(matches))
+	for _, m := range window(matches, 0, size) {
+		suggestions = append(suggestions, m.suggestion)
+	}
+	return suggestions, nil
+}
+
+// startsWords reports whether every term is the start of a word of value.
+func startsWords(value string, terms []string) bool {
+	words := strings.Fields(strings.ToLower(value))
+	for _, term := range terms {
+		if !slices.ContainsFunc(words, func(w string) bool { return strings.HasPrefix(w, term) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *memoryProductRepository) BySKU(ctx context.Context, skus []string) (map[string]models.Product, error) {
+	_, docs := r.store.all()
+	found := map[string]models.Product{}
+	for _, product := range docs {
+		if product.Sku != "" && slices.Contains(skus, product.Sku) {
+			found[product.Sku] = product
+		}
+	}
+	return found, nil
+}
+
+// Bulk writes straight through, calling done before Create and Update return.
+func (r *memoryProductRepository) Bulk(ctx context.Context) (ProductBulk, error) {
+	return memoryProductBulk{r}, nil
+}
+
+type memoryProductBulk struct {
+	repo *memoryProductRepository
+}
+
+func (b memoryProductBulk) Create(ctx context.Context, product models.Product, done func(id string, err error)) error {
+	id, err := b.repo.Create(ctx, &product)
+	done(id, err)
+	return nil
+}
+
+func (b memoryProductBulk) Update(ctx context.Context, id string, fields map[string]interface{}, done func(id string, err error)) error {
+	done(id, b.repo.Update(ctx, id, fields))
+	return nil
+}
+
+func (b memoryProductBulk) Close(ctx context.Context) error {
+	return nil
+}
+
+func (r *memoryProductRepository) Create(ctx context.Context, product *models.Product) (string, error) {
+	id := r.store.create(*product, func(p *models.Product, id string) { p.ID = id })
+	product.ID = id
+	return id, nil
+}
+
+func (r *memoryProductRepository) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	return r.store.update(id, fields)
+}
+
+func (r *memoryProductRepository) Delete(ctx context.Context, id string) error {
+	return r.store.delete(id)
+}
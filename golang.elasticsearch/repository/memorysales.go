@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"golang.elasticsearch/models"
+)
+
+type memorySalesRepository struct {
+	store *memoryStore[models.Sale]
+}
+
+// NewMemorySalesRepository returns a SalesRepository that needs no cluster.
+func NewMemorySalesRepository() SalesRepository {
+	return &memorySalesRepository{store: newMemoryStore[models.Sale]()}
+}
+
+func (r *memorySalesRepository) Get(ctx context.Context, id string) (*models.Sale, error) {
+	sale, err := r.store.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+func (r *memorySalesRepository) Search(ctx context.Context, q SalesQuery) ([]models.Sale, int64, error) {
+	_, sales := r.store.all()
+	sort.SliceStable(sales, func(i, j int) bool {
+		return sales[i].Salesdate.Before(sales[j].Salesdate)
+	})
+	return window(sales, 0, q.Size), int64(len(sales)), nil
+}
+
+func (r *memorySalesRepository) Summarize(ctx context.Context, q SalesSummaryQuery) ([]SalesBucket, error) {
+	if err := q.check(); err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	_, sales := r.store.all()
+
+	totals := map[time.Time]*SalesBucket{}
+	var first, last time.Time
+	for _, sale := range sales {
+		if !q.From.IsZero() && sale.Salesdate.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !sale.Salesdate.Before(q.To) {
+			continue
+		}
+
+		start := intervalStart(sale.Salesdate, q.Interval, loc)
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+
+		bucket, ok := totals[start]
+		if !ok {
+			bucket = &SalesBucket{Start: start, Categories: map[string]float64{}}
+			totals[start] = bucket
+		}
+		bucket.Amount += sale.Amount
+		bucket.Count++
+		for _, item := range sale.Items {
+			category := item.Category
+			if category == "" {
+				category = Uncategorized
+			}
+			bucket.Categories[category] += item.Amount
+		}
+	}
+
+	// Like min_doc_count 0, intervals without sales still get a bucket
+	if !q.From.IsZero() && !q.To.IsZero() {
+		first = intervalStart(q.From, q.Interval, loc)
+		last = intervalStart(q.To.Add(-time.Nanosecond), q.Interval, loc)
+	}
+	buckets := []SalesBucket{}
+	if first.IsZero() {
+		return buckets, nil
+	}
+	for start := first; !start.After(last); start = nextInterval(start, q.Interval) {
+		bucket := SalesBucket{Start: start}
+		if total, ok := totals[start]; ok {
+			bucket = *total
+		}
+		bucket.Amount = RoundCents(bucket.Amount)
+		if q.ByCategory {
+			categories := map[string]float64{}
+			for name, amount := range bucket.Categories {
+				categories[name] = RoundCents(amount)
+			}
+			bucket.Categories = categories
+		} else {
+			bucket.Categories = nil
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+func (r *memorySalesRepository) Create(ctx context.Context, sale *models.Sale) (string, error) {
+	id := r.store.create(*sale, func(s *models.Sale, id string) { s.ID = id })
+	sale.ID = id
+	return id, nil
+}
+
+func (r *memorySalesRepository) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	return r.store.update(id, fields)
+}
+
+func (r *memorySalesRepository) Delete(ctx context.Context, id string) error {
+	return r.store.delete(id)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"golang.elasticsearch/models"
+)
+
+type memoryStockRepository struct {
+	mu        sync.Mutex
+	products  ProductRepository
+	movements *memoryStore[models.StockMovement]
+}
+
+// NewMemoryStockRepository returns a StockRepository that needs no cluster.
+// It changes quantities through the given product repository.
+func NewMemoryStockRepository(products ProductRepository) StockRepository {
+	return &memoryStockRepository{
+		products:  products,
+		movements: newMemoryStore[models.StockMovement](),
+	}
+}
+
+func (r *memoryStockRepository) Move(ctx context.Context, m *models.StockMovement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, err := r.products.Get(ctx, m.ProductID)
+	if err != nil {
+		return err
+	}
+
+	balance := product.Qty + m.Delta()
+	if balance < 0 {
+		return ErrInsufficientStock
+	}
+	err = r.products.Update(ctx, m.ProductID, map[string]interface{}{
+		"qty":        balance,
+		"updated_at": m.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.Balance = balance
+	m.ID = r.movements.create(*m, func(doc *models.StockMovement, id string) { doc.ID = id })
+	return nil
+}
+
+func (r *memoryStockRepository) History(ctx context.Context, q StockQuery, p PageQuery) (*Page[models.StockMovement], error) {
+	_, docs := r.movements.all()
+
+	movements := []models.StockMovement{}
+	for i := len(docs) - 1; i >= 0; i-- {
+		if docs[i].ProductID == q.ProductID {
+			movements = append(movements, docs[i])
+		}
+	}
+	sort.SliceStable(movements, func(i, j int) bool {
+		return movements[i].CreatedAt.After(movements[j].CreatedAt)
+	})
+	return memoryPage(movements, q, p)
+}
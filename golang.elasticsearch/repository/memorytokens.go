@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.elasticsearch/models"
+)
+
+type memoryTokenStore struct {
+	mu      sync.Mutex
+	refresh map[string]models.RefreshToken
+	revoked map[string]time.Time
+}
+
+// NewMemoryTokenStore returns a TokenStore that needs no cluster.
+func NewMemoryTokenStore() TokenStore {
+	return &memoryTokenStore{
+		refresh: make(map[string]models.RefreshToken),
+		revoked: make(map[string]time.Time),
+	}
+}
+
+func (s *memoryTokenStore) SaveRefresh(ctx context.Context, token *models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refresh[token.ID] = *token
+	return nil
+}
+
+func (s *memoryTokenStore) GetRefresh(ctx context.Context, id string) (*models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refresh[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &token, nil
+}
+
+func (s *memoryTokenStore) UseRefresh(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refresh[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if token.Used {
+		return ErrTokenReused
+	}
+	token.Used = true
+	s.refresh[id] = token
+	return nil
+}
+
+func (s *memoryTokenStore) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoked[id] = expiresAt
+	return nil
+}
+
+func (s *memoryTokenStore) IsRevoked(ctx context.Context, ids ...string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := s.revoked[id]; ok && id != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryTokenStore) UseOnce(ctx context.Context, id string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.revoked[id]; ok {
+		return ErrTokenReused
+	}
+	s.revoked[id] = expiresAt
+	return nil
+}
+
+func (s *memoryTokenStore) Sessions(ctx context.Context, userID string) (map[string]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sessions := make(map[string]time.Time)
+	for _, token := range s.refresh {
+		if token.UserID != userID || !token.ExpiresAt.After(now) {
+			continue
+		}
+		if token.ExpiresAt.After(sessions[token.Family]) {
+			sessions[token.Family] = token.ExpiresAt
+		}
+	}
+	return sessions, nil
+}
+
+func (s *memoryTokenStore) Cleanup(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.refresh {
+		if token.ExpiresAt.Before(now) {
+			delete(s.refresh, id)
+		}
+	}
+	for id, expiresAt := range s.revoked {
+		if expiresAt.Before(now) {
+			delete(s.revoked, id)
+		}
+	}
+	return nil
+}
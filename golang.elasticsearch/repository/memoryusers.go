@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"golang.elasticsearch/models"
+)
+
+type memoryUserRepository struct {
+	store *memoryStore[models.User]
+}
+
+// NewMemoryUserRepository returns a UserRepository that needs no cluster.
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{store: newMemoryStore[models.User]()}
+}
+
+func (r *memoryUserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	user, err := r.store.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) Search(ctx context.Context, q UserQuery) ([]models.User, int64, error) {
+	users := r.matching(q)
+	return window(users, q.From, q.Size), int64(len(users)), nil
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, q UserQuery, p PageQuery) (*Page[models.User], error) {
+	users := r.matching(q)
+	sort.SliceStable(users, func(i, j int) bool {
+		return strings.ToLower(users[i].Username) < strings.ToLower(users[j].Username)
+	})
+	q.From, q.Size = 0, 0
+	return memoryPage(users, q, p)
+}
+
+// matching filters the users for q, without paging.
+func (r *memoryUserRepository) matching(q UserQuery) []models.User {
+	_, docs := r.store.all()
+
+	users := []models.User{}
+	for _, user := range docs {
+		if q.Username != "" && !strings.EqualFold(user.Username, q.Username) {
+			continue
+		}
+		if q.Email != "" && !strings.EqualFold(user.Email, q.Email) {
+			continue
+		}
+		if q.Mailtoken != "" && string(user.Mailtoken) != q.Mailtoken {
+			continue
+		}
+		if q.Role != "" && !user.Roles.Has(q.Role) {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) (string, error) {
+	id := r.store.create(*user, func(u *models.User, id string) { u.ID = id })
+	user.ID = id
+	return id, nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, id string, fields map[string]interface{}) error {
+	return r.store.update(id, fields)
+}
+
+func (r *memoryUserRepository) Modify(ctx context.Context, id string, change func(*models.User) (map[string]interface{}, error)) error {
+	return r.store.modify(id, func(user models.User) (map[string]interface{}, error) {
+		return change(&user)
+	})
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, id string) error {
+	return r.store.delete(id)
+}
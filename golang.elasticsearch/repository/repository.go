@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"golang.elasticsearch/models"
+)
+
+// ErrNotFound is returned when a document id does not exist in the index.
+var ErrNotFound = errors.New("document not found")
+
+// UserQuery filters users. Empty fields are ignored.
+type UserQuery struct {
+	Username string
+	Email    string
+	Role     string
+	// Mailtoken matches the hash of a token sent by email.
+	Mailtoken string
+	From      int
+	Size      int
+}
+
+// ProductQuery filters products. Text is matched against descriptions and
+// Level, when set, keeps the products whose Product.StockLevel equals it.
+type ProductQuery struct {
+	Text  string
+	Level string
+	From  int
+	Size  int
+	Sort  []string
+}
+
+// SalesQuery filters sales records.
+type SalesQuery struct {
+	Size int
+}
+
+type UserRepository interface {
+	Get(ctx context.Context, id string) (*models.User, error)
+	Search(ctx context.Context, q UserQuery) ([]models.User, int64, error)
+	// List pages through the users matching q, ignoring its From and Size.
+	List(ctx context.Context, q UserQuery, p PageQuery) (*Page[models.User], error)
+	Create(ctx context.Context, user *models.User) (string, error)
+	Update(ctx context.Context, id string, fields map[string]interface{}) error
+	// Modify writes the fields change returns for the stored user, only if
+	// the user did not change since it was read. Otherwise change is called
+	// again with the new state. A nil map from change writes nothing.
+	Modify(ctx context.Context, id string, change func(*models.User) (map[string]interface{}, error)) error
+	Delete(ctx context.Context, id string) error
+}
+
+type ProductRepository interface {
+	Get(ctx context.Context, id string) (*models.Product, error)
+	Search(ctx context.Context, q ProductQuery) ([]models.Product, int64, error)
+	// List pages through the products matching q, ignoring its From and Size.
+	List(ctx context.Context, q ProductQuery, p PageQuery) (*Page[models.Product], error)
+	// Catalogue returns one page of the products matching q.
+	Catalogue(ctx context.Context, q CatalogueQuery, p PageQuery) (*CatalogueResult, error)
+	// Each calls fn with every product matching q, in q.Sort order, and
+	// stops at the first error fn returns.
+	Each(ctx context.Context, q CatalogueQuery, fn func(models.Product) error) error
+	// BySKU returns the products having one of skus, keyed by SKU.
+	BySKU(ctx context.Context, skus []string) (map[string]models.Product, error)
+	// Bulk starts a batch of writes that are sent in bulk requests.
+	Bulk(ctx context.Context) (ProductBulk, error)
+	// Suggest ranks the products whose descriptions or category have words
+	// starting with the words of prefix, best match first.
+	Suggest(ctx context.Context, prefix string, size int) ([]Suggestion, error)
+	Create(ctx context.Context, product *models.Product) (string, error)
+	Update(ctx context.Context, id string, fields map[string]interface{}) error
+	Delete(ctx context.Context, id string) error
+}
+
+// ProductBulk queues product writes and reports on each once it is stored.
+// The done callbacks may run on other goroutines.
+type ProductBulk interface {
+	// Create queues a new product; done gets the id it was stored under.
+	Create(ctx context.Context, product models.Product, done func(id string, err error)) error
+	// Update queues a partial update of the product id.
+	Update(ctx context.Context, id string, fields map[string]interface{}, done func(id string, err error)) error
+	// Close sends what is still queued and waits until every done has run.
+	Close(ctx context.Context) error
+}
+
+type SalesRepository interface {
+	Get(ctx context.Context, id string) (*models.Sale, error)
+	Search(ctx context.Context, q SalesQuery) ([]models.Sale, int64, error)
+	// Summarize totals the sales per interval.
+	Summarize(ctx context.Context, q SalesSummaryQuery) ([]SalesBucket, error)
+	Create(ctx context.Context, sale *models.Sale) (string, error)
+	Update(ctx context.Context, id string, fields map[string]interface{}) error
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Intervals a sales summary can be bucketed by. They match the calendar
+// intervals of an Elasticsearch date_histogram.
+const (
+	IntervalDay     = "day"
+	IntervalWeek    = "week"
+	IntervalMonth   = "month"
+	IntervalQuarter = "quarter"
+	IntervalYear    = "year"
+)
+
+// Uncategorized is the category of line items sold before products' categories
+// were recorded on sales.
+const Uncategorized = "Uncategorized"
+
+// MaxSalesBuckets caps the intervals of a summary. With a category split
+// each holds up to maxCategories more buckets, which keeps the whole under
+// the search.max_buckets limit of Elasticsearch.
+const MaxSalesBuckets = 500
+
+// ErrTooManyBuckets is returned for a range holding more than MaxSalesBuckets
+// intervals.
+var ErrTooManyBuckets = errors.New("too many intervals in the range")
+
+// SalesSummaryQuery buckets sales by Interval. Sale dates are calendar days,
+// stored as midnight UTC, so the intervals are calendar ones too and From and
+// To are midnight UTC of a day. When set, they keep sales dated in
+// [From, To), and every interval of that range gets a bucket even without
+// sales.
+type SalesSummaryQuery struct {
+	From       time.Time
+	To         time.Time
+	Interval   string
+	ByCategory bool
+}
+
+// check refuses a range that would need more than MaxSalesBuckets buckets.
+func (q SalesSummaryQuery) check() error {
+	if q.From.IsZero() || q.To.IsZero() {
+		return nil
+	}
+	n := 0
+	for start := intervalStart(q.From, q.Interval, time.UTC); start.Before(q.To); start = nextInterval(start, q.Interval) {
+		if n++; n > MaxSalesBuckets {
+			return ErrTooManyBuckets
+		}
+	}
+	return nil
+}
+
+// SalesBucket totals the sales of one interval. Categories splits the line
+// item amounts by product category when the query asks for it; sales recorded
+// without line items only count towards Amount.
+type SalesBucket struct {
+	Start      time.Time          `json:"start"`
+	Amount     float64            `json:"amount"`
+	Count      int64              `json:"count"`
+	Categories map[string]float64 `json:"categories,omitempty"`
+}
+
+// intervalStart is the first instant of the interval holding t. Weeks start
+// on Monday, as they do in Elasticsearch.
+func intervalStart(t time.Time, interval string, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	switch interval {
+	case IntervalDay:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	case IntervalWeek:
+		day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case IntervalQuarter:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, loc)
+	case IntervalYear:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+}
+
+// nextInterval is the start of the interval following the one starting at t.
+func nextInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalDay:
+		return t.AddDate(0, 0, 1)
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case IntervalQuarter:
+		return t.AddDate(0, 3, 0)
+	case IntervalYear:
+		return t.AddDate(1, 0, 0)
+	}
+	return t.AddDate(0, 1, 0)
+}
+
+// RoundCents drops the floating point noise left by summing prices.
+func RoundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
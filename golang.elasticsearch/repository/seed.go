@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/utils"
+)
+
+// SeedAdmin makes sure at least one administrator exists. When nobody holds
+// ROLE_ADMIN an account is created under the given username. An existing
+// account of that name, which may have signed itself up, is only promoted
+// when promote is set. An empty password is replaced by a random one that is
+// logged once.
+func SeedAdmin(ctx context.Context, users UserRepository, username, email, password string, promote bool) error {
+	admins, _, err := users.Search(ctx, UserQuery{Role: models.RoleAdmin, Size: 1})
+	if err != nil {
+		return err
+	}
+	if len(admins) > 0 {
+		return nil
+	}
+
+	existing, _, err := users.Search(ctx, UserQuery{Username: username, Size: 1})
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		user := existing[0]
+		if !promote {
+			return fmt.Errorf("no administrator exists and the user %s is already taken; choose another ADMIN_USERNAME, or set ADMIN_PROMOTE_EXISTING=true to make that account an administrator", user.Username)
+		}
+		log.Printf("Granting %s to existing user %s", models.RoleAdmin, user.Username)
+		return users.Update(ctx, user.ID, map[string]interface{}{
+			"roles":      append(user.Roles, models.RoleAdmin),
+			"updated_at": time.Now().UTC(),
+		})
+	}
+
+	if password == "" {
+		buf := make([]byte, 12)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		password = hex.EncodeToString(buf)
+		log.Printf("Seeded admin %s with generated password %s, please change it.", username, password)
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = users.Create(ctx, &models.User{
+		Firstname:   "System",
+		Lastname:    "Administrator",
+		Email:       strings.ToLower(email),
+		Username:    username,
+		Password:    hash,
+		Roles:       models.Roles{models.RoleUser, models.RoleAdmin},
+		Isactivated: true,
+		Userpicture: "pix.png",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	return err
+}
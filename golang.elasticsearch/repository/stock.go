@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"golang.elasticsearch/models"
+)
+
+var (
+	// ErrInsufficientStock is returned when a movement would take the product
+	// quantity below zero.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrConflict is returned when a write kept losing against concurrent ones.
+	ErrConflict = errors.New("too many concurrent updates")
+)
+
+// StockQuery selects the movements of one product, listed newest first.
+type StockQuery struct {
+	ProductID string
+}
+
+// StockRepository keeps the stock ledger and the product quantity in step.
+type StockRepository interface {
+	// Move applies the movement to the product quantity and records it with
+	// the resulting balance.
+	Move(ctx context.Context, movement *models.StockMovement) error
+	History(ctx context.Context, q StockQuery, p PageQuery) (*Page[models.StockMovement], error)
+}
@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"golang.elasticsearch/models"
+)
+
+func stockedProduct(t *testing.T, products ProductRepository, qty float64) string {
+	t.Helper()
+	id, err := products.Create(context.Background(), &models.Product{Descriptions: "Widget", Qty: qty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func quantity(t *testing.T, products ProductRepository, id string) float64 {
+	t.Helper()
+	product, err := products.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product.Qty
+}
+
+func TestMemoryMove(t *testing.T) {
+	ctx := context.Background()
+	products := NewMemoryProductRepository()
+	stock := NewMemoryStockRepository(products)
+	id := stockedProduct(t, products, 0)
+
+	for _, m := range []models.StockMovement{
+		{Type: models.MovementReceipt, Quantity: 10},
+		{Type: models.MovementIssue, Quantity: 4},
+		{Type: models.MovementAdjustment, Quantity: -1},
+		{Type: models.MovementReturn, Quantity: 2},
+	} {
+		m.ProductID = id
+		if err := stock.Move(ctx, &m); err != nil {
+			t.Fatalf("%s of %v: %s", m.Type, m.Quantity, err)
+		}
+	}
+	if got := quantity(t, products, id); got != 7 {
+		t.Errorf("qty = %v, want 7", got)
+	}
+
+	page, err := stock.History(ctx, StockQuery{ProductID: id}, PageQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var balances []float64
+	for _, m := range page.Items {
+		balances = append(balances, m.Balance)
+	}
+	if want := []float64{7, 5, 6, 10}; !slices.Equal(balances, want) {
+		t.Errorf("balances newest first = %v, want %v", balances, want)
+	}
+}
+
+func TestMemoryMoveInsufficientStock(t *testing.T) {
+	ctx := context.Background()
+	products := NewMemoryProductRepository()
+	stock := NewMemoryStockRepository(products)
+	id := stockedProduct(t, products, 3)
+
+	tests := []models.StockMovement{
+		{ProductID: id, Type: models.MovementIssue, Quantity: 4},
+		{ProductID: id, Type: models.MovementAdjustment, Quantity: -3.5},
+	}
+	for _, m := range tests {
+		if err := stock.Move(ctx, &m); !errors.Is(err, ErrInsufficientStock) {
+			t.Errorf("%s of %v: err = %v, want %v", m.Type, m.Quantity, err, ErrInsufficientStock)
+		}
+	}
+	if got := quantity(t, products, id); got != 3 {
+		t.Errorf("qty = %v, want 3", got)
+	}
+	page, err := stock.History(ctx, StockQuery{ProductID: id}, PageQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 0 {
+		t.Errorf("refused movements were recorded: %+v", page.Items)
+	}
+
+	missing := models.StockMovement{ProductID: "missing", Type: models.MovementReceipt, Quantity: 1}
+	if err := stock.Move(ctx, &missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("movement of a missing product: err = %v", err)
+	}
+}
+
+func TestMemoryMoveConcurrent(t *testing.T) {
+	ctx := context.Background()
+	products := NewMemoryProductRepository()
+	stock := NewMemoryStockRepository(products)
+	id := stockedProduct(t, products, 20)
+
+	// More issues than there is stock for, racing each other
+	const issues = 30
+	errs := make([]error, issues)
+	var wg sync.WaitGroup
+	for i := range issues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = stock.Move(ctx, &models.StockMovement{ProductID: id, Type: models.MovementIssue, Quantity: 1})
+		}()
+	}
+	wg.Wait()
+
+	var issued, refused int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			issued++
+		case errors.Is(err, ErrInsufficientStock):
+			refused++
+		default:
+			t.Fatal(err)
+		}
+	}
+	if issued != 20 || refused != 10 {
+		t.Errorf("%d issued and %d refused, want 20 and 10", issued, refused)
+	}
+	if got := quantity(t, products, id); got != 0 {
+		t.Errorf("qty = %v, want 0", got)
+	}
+
+	// Every movement saw the balance left by the one before it
+	page, err := stock.History(ctx, StockQuery{ProductID: id}, PageQuery{Size: issues})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var balances []float64
+	for _, m := range page.Items {
+		balances = append(balances, m.Balance)
+	}
+	slices.Sort(balances)
+	for i, b := range balances {
+		if b != float64(i) {
+			t.Fatalf("balances %v are not 0 to 19 once each", balances)
+		}
+	}
+}
+
+// fakeES serves the document calls of one product and the movements index,
+// the way Elasticsearch does, failing the first conflicts conditional updates
+// as if another writer got there first.
+type fakeES struct {
+	mu        sync.Mutex
+	qty       float64
+	seqNo     int64
+	conflicts int
+	updates   int
+	// movementsDown fails every write to the movements index.
+	movementsDown bool
+	movements     []models.StockMovement
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	reply := func(status int, body interface{}) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/products/_doc/p1":
+		reply(200, map[string]interface{}{
+			"_id":           "p1",
+			"_seq_no":       f.seqNo,
+			"_primary_term": 1,
+			"found":         true,
+			"_source":       models.Product{Qty: f.qty},
+		})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/products/_update/p1":
+		f.updates++
+		if f.conflicts > 0 || r.URL.Query().Get("if_seq_no") != strconv.FormatInt(f.seqNo, 10) {
+			f.conflicts--
+			// Someone else's write moved the document on
+			f.seqNo++
+			reply(409, map[string]interface{}{"error": map[string]interface{}{"type": "version_conflict_engine_exception"}})
+			return
+		}
+		var body struct {
+			Doc struct {
+				Qty float64 `json:"qty"`
+			} `json:"doc"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.qty = body.Doc.Qty
+		f.seqNo++
+		reply(200, map[string]interface{}{"result": "updated"})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/stock_movements/_doc":
+		if f.movementsDown {
+			reply(503, map[string]interface{}{"error": "unavailable"})
+			return
+		}
+		var m models.StockMovement
+		json.NewDecoder(r.Body).Decode(&m)
+		f.movements = append(f.movements, m)
+		reply(201, map[string]interface{}{"_id": "m" + strconv.Itoa(len(f.movements))})
+
+	default:
+		reply(400, map[string]interface{}{"error": "unexpected " + r.Method + " " + r.URL.Path})
+	}
+}
+
+func newFakeStock(t *testing.T, es *fakeES) StockRepository {
+	t.Helper()
+	srv := httptest.NewServer(es)
+	t.Cleanup(srv.Close)
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewElasticStockRepository(client, "products", "stock_movements")
+}
+
+func TestElasticMoveRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		conflicts int
+		want      error
+		qty       float64
+		updates   int
+	}{
+		{"no conflict", 0, nil, 7, 1},
+		{"conflicts then through", maxWriteAttempts - 1, nil, 7, maxWriteAttempts},
+		{"always conflicting", maxWriteAttempts, ErrConflict, 10, maxWriteAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := &fakeES{qty: 10, conflicts: tt.conflicts}
+			stock := newFakeStock(t, es)
+
+			m := &models.StockMovement{ProductID: "p1", Type: models.MovementIssue, Quantity: 3, CreatedAt: time.Now().UTC()}
+			err := stock.Move(context.Background(), m)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if es.qty != tt.qty || es.updates != tt.updates {
+				t.Errorf("qty %v after %d updates, want %v after %d", es.qty, es.updates, tt.qty, tt.updates)
+			}
+			if tt.want == nil && (m.Balance != 7 || m.ID == "" || len(es.movements) != 1) {
+				t.Errorf("movement = %+v, %d recorded", m, len(es.movements))
+			}
+			if tt.want != nil && len(es.movements) != 0 {
+				t.Errorf("a failed movement was recorded: %+v", es.movements)
+			}
+		})
+	}
+}
+
+func TestElasticMoveInsufficientStock(t *testing.T) {
+	es := &fakeES{qty: 2}
+	stock := newFakeStock(t, es)
+
+	m := &models.StockMovement{ProductID: "p1", Type: models.MovementIssue, Quantity: 3}
+	if err := stock.Move(context.Background(), m); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("err = %v, want %v", err, ErrInsufficientStock)
+	}
+	if es.qty != 2 || es.updates != 0 || len(es.movements) != 0 {
+		t.Errorf("qty %v after %d updates and %d movements", es.qty, es.updates, len(es.movements))
+	}
+}
+
+func TestElasticMoveUndoneWithoutLedgerEntry(t *testing.T) {
+	es := &fakeES{qty: 10, movementsDown: true}
+	stock := newFakeStock(t, es)
+
+	m := &models.StockMovement{ProductID: "p1", Type: models.MovementIssue, Quantity: 3}
+	if err := stock.Move(context.Background(), m); err == nil {
+		t.Fatal("moved without a ledger entry")
+	}
+	// Taken to 7, then back
+	if es.qty != 10 || es.updates != 2 {
+		t.Errorf("qty %v after %d updates, want 10 after 2", es.qty, es.updates)
+	}
+}
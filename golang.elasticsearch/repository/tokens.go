@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"golang.elasticsearch/models"
+)
+
+// ErrTokenReused is returned when a refresh token that was already rotated,
+// or a single use token, is presented again, which means it has leaked.
+var ErrTokenReused = errors.New("token already used")
+
+// TokenStore keeps refresh tokens and the revocation list checked by
+// AuthMiddleware.
+type TokenStore interface {
+	SaveRefresh(ctx context.Context, token *models.RefreshToken) error
+	GetRefresh(ctx context.Context, id string) (*models.RefreshToken, error)
+	// UseRefresh marks the token used, returning ErrTokenReused if it already was.
+	UseRefresh(ctx context.Context, id string) error
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, ids ...string) (bool, error)
+	// UseOnce records the id of a single use token until it expires,
+	// returning ErrTokenReused if it already was.
+	UseOnce(ctx context.Context, id string, expiresAt time.Time) error
+	// Sessions returns the session ids of the user that still hold a live
+	// refresh token, with the time the last of them expires.
+	Sessions(ctx context.Context, userID string) (map[string]time.Time, error)
+	// Cleanup drops refresh tokens and revocations that have expired.
+	Cleanup(ctx context.Context, now time.Time) error
+}
+
+// Cleaner is implemented by the stores holding expiring records.
+type Cleaner interface {
+	Cleanup(ctx context.Context, now time.Time) error
+}
+
+// RunCleanup calls Cleanup on every store each interval until ctx is done.
+func RunCleanup(ctx context.Context, interval time.Duration, stores ...Cleaner) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, store := range stores {
+				if err := store.Cleanup(ctx, now.UTC()); err != nil {
+					log.Printf("Error cleaning up expired records: %s", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/storage"
+)
+
+// pngFile encodes a square picture of one colour.
+func pngFile(t *testing.T, size int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func (s *testServer) addProduct(token string) string {
+	s.t.Helper()
+	w := s.do("POST", "/addproduct", `{"category":"tools","descriptions":"Widget","qty":1,"sellprice":2}`, token)
+	expectStatus(s.t, w, http.StatusCreated)
+	var created struct {
+		ID string `json:"id"`
+	}
+	decode(s.t, w, &created)
+	return created.ID
+}
+
+func (s *testServer) uploadImage(productID string, data []byte, token string) (*models.ProductImages, int) {
+	s.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "picture.jpg")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	part.Write(data)
+	form.Close()
+
+	w := s.send("POST", "/api/products/"+productID+"/image", form.FormDataContentType(), strings.NewReader(body.String()), token)
+	if w.Code != http.StatusOK {
+		return nil, w.Code
+	}
+	var res struct {
+		Images models.ProductImages `json:"images"`
+	}
+	decode(s.t, w, &res)
+	return &res.Images, w.Code
+}
+
+func (s *testServer) blobExists(key string) bool {
+	s.t.Helper()
+	obj, err := s.blobs.Get(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	obj.Body.Close()
+	return true
+}
+
+func TestProductImage(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+	id := s.addProduct(admin.Token)
+
+	first, status := s.uploadImage(id, pngFile(t, 800, color.White), admin.Token)
+	if status != http.StatusOK {
+		t.Fatalf("upload status = %d, want 200", status)
+	}
+	for _, key := range first.Keys() {
+		if !strings.HasPrefix(key, "products/"+id+"/") {
+			t.Errorf("key %q is not under the product", key)
+		}
+		w := s.do("GET", "/media/"+key, "", "")
+		expectStatus(t, w, http.StatusOK)
+		// An opaque picture is stored as a JPEG whatever it came in
+		if ct := w.Header().Get("Content-Type"); ct != "image/jpeg" || !strings.HasSuffix(key, ".jpg") {
+			t.Errorf("%s served as %q, want image/jpeg", key, ct)
+		}
+	}
+
+	// Uploading the same picture again keeps its files
+	again, _ := s.uploadImage(id, pngFile(t, 800, color.White), admin.Token)
+	if *again != *first || !s.blobExists(first.Original) {
+		t.Errorf("re-upload stored %+v and dropped the current files %+v", again, first)
+	}
+
+	// A new picture replaces the files of the old one
+	second, _ := s.uploadImage(id, pngFile(t, 800, color.Transparent), admin.Token)
+	if second.Original == first.Original {
+		t.Fatal("a different picture got the same keys")
+	}
+	// Transparency survives as a PNG
+	if !strings.HasSuffix(second.Original, ".png") {
+		t.Errorf("transparent picture stored as %s, want a PNG", second.Original)
+	}
+	for _, key := range first.Keys() {
+		if s.blobExists(key) {
+			t.Errorf("old file %s was kept", key)
+		}
+	}
+
+	expectStatus(t, s.do("DELETE", "/api/products/"+id+"/image", "", admin.Token), http.StatusOK)
+	for _, key := range second.Keys() {
+		if s.blobExists(key) {
+			t.Errorf("file %s was kept after the delete", key)
+		}
+	}
+	expectStatus(t, s.do("DELETE", "/api/products/"+id+"/image", "", admin.Token), http.StatusNotFound)
+}
+
+func TestProductImageRejected(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+	id := s.addProduct(admin.Token)
+
+	if _, status := s.uploadImage(id, []byte("not a picture"), admin.Token); status != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d for text, want 415", status)
+	}
+	if _, status := s.uploadImage("missing", pngFile(t, 10, color.White), admin.Token); status != http.StatusNotFound {
+		t.Errorf("status = %d for an unknown product, want 404", status)
+	}
+	user := s.signup("alice")
+	if _, status := s.uploadImage(id, pngFile(t, 10, color.White), user.Token); status != http.StatusForbidden {
+		t.Errorf("status = %d for a non-admin, want 403", status)
+	}
+}
@@ -0,0 +1,145 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.elasticsearch/models"
+)
+
+type importReport struct {
+	Rows    int `json:"rows"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+	Errors  []struct {
+		Line int    `json:"line"`
+		Sku  string `json:"sku"`
+	} `json:"errors"`
+}
+
+func (s *testServer) importFile(path, contentType, file, token string) importReport {
+	s.t.Helper()
+	w := s.send("POST", path, contentType, strings.NewReader(file), token)
+	expectStatus(s.t, w, http.StatusOK)
+	var report importReport
+	decode(s.t, w, &report)
+	return report
+}
+
+func (s *testServer) productBySKU(sku string) models.Product {
+	s.t.Helper()
+	found, err := s.products.BySKU(context.Background(), []string{sku})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	product, ok := found[sku]
+	if !ok {
+		s.t.Fatalf("no product with SKU %s", sku)
+	}
+	return product
+}
+
+func (s *testServer) movements(productID, token string) []models.StockMovement {
+	s.t.Helper()
+	w := s.do("GET", "/api/products/"+productID+"/movements", "", token)
+	expectStatus(s.t, w, http.StatusOK)
+	var page struct {
+		Movements []models.StockMovement `json:"movements"`
+	}
+	decode(s.t, w, &page)
+	return page.Movements
+}
+
+func TestImportCSV(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+
+	file := "sku,category,descriptions,qty,costprice,sellprice\n" +
+		"W-1,tools,Widget,10,1,2.5\n" +
+		"W-2,tools,Gadget,0,1,3\n" +
+		"W-3,tools,Too cheap,1,5,2\n" +
+		"W-1,tools,Widget again,1,1,2\n"
+	report := s.importFile("/api/products/import", "text/csv", file, admin.Token)
+
+	if report.Rows != 4 || report.Created != 2 || report.Failed != 2 {
+		t.Fatalf("report = %+v, want 4 rows, 2 created, 2 failed", report)
+	}
+	lines := map[int]bool{}
+	for _, e := range report.Errors {
+		lines[e.Line] = true
+	}
+	if !lines[4] || !lines[5] {
+		t.Errorf("errors on lines %v, want 4 and 5", lines)
+	}
+
+	widget := s.productBySKU("W-1")
+	if widget.Qty != 10 {
+		t.Errorf("qty = %v, want 10", widget.Qty)
+	}
+	moves := s.movements(widget.ID, admin.Token)
+	if len(moves) != 1 || moves[0].Type != models.MovementReceipt || moves[0].Quantity != 10 {
+		t.Errorf("movements = %+v, want one opening receipt of 10", moves)
+	}
+	// No movement for an opening balance of zero
+	if moves := s.movements(s.productBySKU("W-2").ID, admin.Token); len(moves) != 0 {
+		t.Errorf("movements = %+v, want none", moves)
+	}
+}
+
+func TestImportUpdatesPresentColumns(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+	s.importFile("/api/products/import", "text/csv", "sku,category,descriptions,qty,costprice,sellprice,unit\nW-1,tools,Widget,10,1,2.5,pc\n", admin.Token)
+
+	report := s.importFile("/api/products/import?format=ndjson", "application/octet-stream",
+		`{"sku":"W-1","category":"tools","descriptions":"Widget","sellprice":4,"images":{"original":"x"}}`+"\n", admin.Token)
+	if report.Updated != 1 || report.Failed != 0 {
+		t.Fatalf("report = %+v, want 1 updated", report)
+	}
+
+	widget := s.productBySKU("W-1")
+	if widget.Sellprice != 4 || widget.Costprice != 1 || widget.Unit != "pc" || widget.Qty != 10 {
+		t.Errorf("product = %+v, want sellprice 4 and the other columns kept", widget)
+	}
+	if moves := s.movements(widget.ID, admin.Token); len(moves) != 1 {
+		t.Errorf("%d movements, an import without qty must not add one", len(moves))
+	}
+
+	// A stock count moves the balance by the difference
+	s.importFile("/api/products/import", "text/csv", "sku,category,descriptions,qty\nW-1,tools,Widget,7\n", admin.Token)
+	moves := s.movements(widget.ID, admin.Token)
+	if len(moves) != 2 || moves[0].Type != models.MovementAdjustment || moves[0].Quantity != -3 {
+		t.Errorf("movements = %+v, want an adjustment of -3 first", moves)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+
+	file := `{"sku":"W-1","category":"tools","descriptions":"Widget","qty":1}` + "\n" +
+		`{"sku":"W-2","category":"tools"}` + "\n"
+	report := s.importFile("/api/products/import?dryrun=true", "application/x-ndjson", file, admin.Token)
+	if report.Created != 1 || report.Failed != 1 {
+		t.Errorf("report = %+v, want 1 created and 1 failed", report)
+	}
+
+	found, err := s.products.BySKU(context.Background(), []string{"W-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Error("a dry run stored the product")
+	}
+}
+
+func TestImportRejectsUnknownFormat(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+
+	w := s.send("POST", "/api/products/import", "text/plain", strings.NewReader("sku\nW-1\n"), admin.Token)
+	expectStatus(t, w, http.StatusUnsupportedMediaType)
+}
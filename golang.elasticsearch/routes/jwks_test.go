@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.elasticsearch/utils"
+)
+
+func TestJWKS(t *testing.T) {
+	s := newTestServer(t)
+	sess := s.login(adminName, adminPassword)
+
+	w := s.do("GET", "/.well-known/jwks.json", "", "")
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=300" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	var set struct {
+		Keys []utils.JWK `json:"keys"`
+	}
+	decode(t, w, &set)
+	if len(set.Keys) != 1 {
+		t.Fatalf("keys = %+v, want one", set.Keys)
+	}
+	key := set.Keys[0]
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil || key.Kty != "OKP" || key.Crv != "Ed25519" {
+		t.Fatalf("key = %+v", key)
+	}
+
+	// A session token checks out against the published key of its kid
+	_, err = jwt.Parse(sess.Token, func(token *jwt.Token) (interface{}, error) {
+		if token.Header["kid"] != key.Kid {
+			t.Errorf("token kid %v is not published", token.Header["kid"])
+		}
+		return ed25519.PublicKey(x), nil
+	}, jwt.WithValidMethods([]string{key.Alg}), jwt.WithAudience(utils.AccessAudience))
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,224 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+// totpCode is the code of the time step offset steps away from now.
+func totpCode(t *testing.T, secret string, offset int) string {
+	t.Helper()
+	code, err := totp.GenerateCode(secret, time.Now().Add(time.Duration(offset)*30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// enrollMFA turns MFA on for the session user with a code of the previous
+// time step, leaving the current one unused. It returns the secret and the
+// recovery codes.
+func (s *testServer) enrollMFA(user session) (string, []string) {
+	s.t.Helper()
+	expectStatus(s.t, s.do("PATCH", "/api/me/mfa/activate", `{"TwoFactorEnabled":true}`, user.Token), http.StatusOK)
+
+	stored, err := s.users.Get(context.Background(), user.ID)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	secret := *stored.Pendingsecret
+
+	w := s.do("PATCH", "/api/me/mfa/verifytotp", `{"otp":"`+totpCode(s.t, secret, -1)+`"}`, user.Token)
+	expectStatus(s.t, w, http.StatusOK)
+	var body struct {
+		Recoverycodes []string `json:"recoverycodes"`
+	}
+	decode(s.t, w, &body)
+	return secret, body.Recoverycodes
+}
+
+// challenge logs in a user with MFA and returns the challenge token.
+func (s *testServer) challenge(username string) string {
+	s.t.Helper()
+	w := s.do("POST", "/auth/signin", `{"username":"`+username+`","password":"`+userPassword+`"}`, "")
+	expectStatus(s.t, w, http.StatusOK)
+	var body struct {
+		ChallengeToken string `json:"challenge_token"`
+	}
+	decode(s.t, w, &body)
+	if body.ChallengeToken == "" {
+		s.t.Fatalf("no MFA challenge in %s", w.Body.String())
+	}
+	return body.ChallengeToken
+}
+
+func (s *testServer) answer(challenge, field, code string) int {
+	w := s.do("POST", "/auth/mfa/verify", `{"challenge_token":"`+challenge+`","`+field+`":"`+code+`"}`, "")
+	return w.Code
+}
+
+func TestTOTPReplay(t *testing.T) {
+	s := newTestServer(t)
+	secret, _ := s.enrollMFA(s.signup("rey"))
+
+	code := totpCode(t, secret, 0)
+	if status := s.answer(s.challenge("rey"), "otp", code); status != http.StatusOK {
+		t.Fatalf("first use status = %d", status)
+	}
+	if status := s.answer(s.challenge("rey"), "otp", code); status != http.StatusUnauthorized {
+		t.Errorf("replayed code status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	// A code of an earlier step than the last one used is stale as well
+	if status := s.answer(s.challenge("rey"), "otp", totpCode(t, secret, -1)); status != http.StatusUnauthorized {
+		t.Errorf("stale code status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestRecoveryCodeUsedOnce(t *testing.T) {
+	s := newTestServer(t)
+	_, codes := s.enrollMFA(s.signup("rey"))
+	if len(codes) == 0 {
+		t.Fatal("no recovery codes issued")
+	}
+
+	const racers = 4
+	challenges := make([]string, racers)
+	for i := range challenges {
+		challenges[i] = s.challenge("rey")
+	}
+
+	var wg sync.WaitGroup
+	statuses := make([]int, racers)
+	for i := range challenges {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = s.answer(challenges[i], "recovery_code", codes[0])
+		}(i)
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			accepted++
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("recovery code accepted %d times, statuses %v", accepted, statuses)
+	}
+}
+
+func TestDisableMFARequiresProof(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+	s.enrollMFA(rey)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"no proof", `{"TwoFactorEnabled":false}`, http.StatusBadRequest},
+		{"wrong password", `{"TwoFactorEnabled":false,"password":"wrong"}`, http.StatusUnauthorized},
+		{"password", `{"TwoFactorEnabled":false,"password":"` + userPassword + `"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, s.do("PATCH", "/api/me/mfa/activate", tt.body, rey.Token), tt.status)
+		})
+	}
+
+	stored, err := s.users.Get(context.Background(), rey.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Secret != nil || len(stored.Recoverycodes) != 0 {
+		t.Error("MFA is still enabled")
+	}
+}
+
+func TestReplaceMFARequiresProof(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+	active, _ := s.enrollMFA(rey)
+
+	expectStatus(t, s.do("PATCH", "/api/me/mfa/activate", `{"TwoFactorEnabled":true}`, rey.Token), http.StatusBadRequest)
+	expectStatus(t, s.do("PATCH", "/api/me/mfa/activate", `{"TwoFactorEnabled":true,"password":"wrong"}`, rey.Token), http.StatusUnauthorized)
+	stored, err := s.users.Get(context.Background(), rey.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Pendingsecret != nil {
+		t.Fatal("a secret was staged without proof")
+	}
+
+	expectStatus(t, s.do("PATCH", "/api/me/mfa/activate", `{"TwoFactorEnabled":true,"password":"`+userPassword+`"}`, rey.Token), http.StatusOK)
+	if stored, err = s.users.Get(context.Background(), rey.ID); err != nil {
+		t.Fatal(err)
+	}
+	pending := *stored.Pendingsecret
+
+	// The active secret still works while the new one waits for its first code
+	w := s.do("PATCH", "/api/me/mfa/verifytotp", `{"otp":"`+totpCode(t, active, 0)+`"}`, rey.Token)
+	expectStatus(t, w, http.StatusOK)
+	if stored, err = s.users.Get(context.Background(), rey.ID); err != nil {
+		t.Fatal(err)
+	}
+	if *stored.Secret != active || stored.Pendingsecret == nil {
+		t.Fatal("a code of the active secret changed the enrollment")
+	}
+
+	w = s.do("PATCH", "/api/me/mfa/verifytotp", `{"otp":"`+totpCode(t, pending, 0)+`"}`, rey.Token)
+	expectStatus(t, w, http.StatusOK)
+	if stored, err = s.users.Get(context.Background(), rey.ID); err != nil {
+		t.Fatal(err)
+	}
+	if *stored.Secret != pending || stored.Pendingsecret != nil {
+		t.Error("the confirmed secret did not replace the active one")
+	}
+}
+
+func TestProfileHidesSecrets(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+	secret, _ := s.enrollMFA(rey)
+
+	w := s.do("GET", "/api/me", "", rey.Token)
+	expectStatus(t, w, http.StatusOK)
+	var profile map[string]interface{}
+	decode(t, w, &profile)
+	for _, field := range []string{"password", "secret", "qrcodeurl", "pendingsecret"} {
+		if _, ok := profile[field]; ok {
+			t.Errorf("profile carries %s", field)
+		}
+	}
+	if strings.Contains(w.Body.String(), secret) {
+		t.Error("profile carries the TOTP secret")
+	}
+	if profile["mfaenabled"] != true {
+		t.Errorf("mfaenabled = %v, want true", profile["mfaenabled"])
+	}
+}
+
+func TestChallengeUsedOnce(t *testing.T) {
+	s := newTestServer(t)
+	_, codes := s.enrollMFA(s.signup("rey"))
+	challenge := s.challenge("rey")
+
+	// A challenge is not a session
+	expectStatus(t, s.do("GET", "/api/me", "", challenge), http.StatusUnauthorized)
+
+	if status := s.answer(challenge, "recovery_code", codes[0]); status != http.StatusOK {
+		t.Fatalf("first use status = %d", status)
+	}
+	if status := s.answer(challenge, "recovery_code", codes[1]); status != http.StatusUnauthorized {
+		t.Errorf("replayed challenge status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
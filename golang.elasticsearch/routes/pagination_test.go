@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// productPage is one page of /products/list or /products/search.
+type productPage struct {
+	Products []struct {
+		ID           string `json:"id"`
+		Descriptions string `json:"descriptions"`
+	} `json:"products"`
+	Total      int64   `json:"total"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func (s *testServer) addProducts(token string, n int) {
+	s.t.Helper()
+	for i := 1; i <= n; i++ {
+		body := fmt.Sprintf(`{"category":"tools","descriptions":"Widget %02d","qty":1,"sellprice":%d}`, i, i)
+		expectStatus(s.t, s.do("POST", "/addproduct", body, token), http.StatusCreated)
+	}
+}
+
+// walk follows next_cursor from the first page of path and returns the ids
+// in the order they came.
+func (s *testServer) walk(path string, params url.Values) []string {
+	s.t.Helper()
+	var ids []string
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			s.t.Fatal("the cursor never reached the last page")
+		}
+		w := s.do("GET", path+"?"+params.Encode(), "", "")
+		expectStatus(s.t, w, http.StatusOK)
+		var page productPage
+		decode(s.t, w, &page)
+		for _, p := range page.Products {
+			ids = append(ids, p.ID)
+		}
+		if page.NextCursor == nil {
+			return ids
+		}
+		params.Set("cursor", *page.NextCursor)
+	}
+}
+
+func TestProductListCursor(t *testing.T) {
+	s := newTestServer(t)
+	s.addProducts(s.login(adminName, adminPassword).Token, 7)
+
+	ids := s.walk("/products/list", url.Values{"size": {"3"}})
+	if len(ids) != 7 {
+		t.Fatalf("walked %d products, want 7: %v", len(ids), ids)
+	}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			t.Errorf("product %s came twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestCatalogueSearchCursor(t *testing.T) {
+	s := newTestServer(t)
+	s.addProducts(s.login(adminName, adminPassword).Token, 5)
+
+	ids := s.walk("/products/search", url.Values{"size": {"2"}, "minprice": {"2"}})
+	if len(ids) != 4 {
+		t.Errorf("walked %d products priced 2 and up, want 4: %v", len(ids), ids)
+	}
+}
+
+func TestCursorMismatch(t *testing.T) {
+	s := newTestServer(t)
+	s.addProducts(s.login(adminName, adminPassword).Token, 3)
+
+	w := s.do("GET", "/products/search?size=1&minprice=1", "", "")
+	expectStatus(t, w, http.StatusOK)
+	var page productPage
+	decode(t, w, &page)
+	if page.NextCursor == nil {
+		t.Fatal("no next cursor on the first page")
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"garbage", "/products/search?size=1&cursor=garbage"},
+		{"other filters", "/products/search?size=1&minprice=2&cursor=" + url.QueryEscape(*page.NextCursor)},
+		{"other listing", "/products/list?size=1&cursor=" + url.QueryEscape(*page.NextCursor)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, s.do("GET", tt.path, "", ""), http.StatusBadRequest)
+		})
+	}
+}
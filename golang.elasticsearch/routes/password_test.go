@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+const newPassword = "changed456"
+
+func TestResetPassword(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+
+	expectStatus(t, s.do("POST", "/auth/password/forgot", `{"email":"rey@example.com"}`, ""), http.StatusOK)
+	token := s.mail.lastToken(t, "rey@example.com")
+	w := s.do("POST", "/auth/password/reset", `{"token":"`+token+`","password":"`+newPassword+`"}`, "")
+	expectStatus(t, w, http.StatusOK)
+
+	// Every session ends, and the link works once
+	expectStatus(t, s.do("GET", "/api/me", "", rey.Token), http.StatusUnauthorized)
+	w = s.do("POST", "/auth/password/reset", `{"token":"`+token+`","password":"other789"}`, "")
+	expectStatus(t, w, http.StatusBadRequest)
+	s.login("rey", newPassword)
+}
+
+func TestResetPasswordTokenUsedOnce(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("rey")
+	expectStatus(t, s.do("POST", "/auth/password/forgot", `{"email":"rey@example.com"}`, ""), http.StatusOK)
+	token := s.mail.lastToken(t, "rey@example.com")
+
+	passwords := []string{"racer1111", "racer2222", "racer3333", "racer4444"}
+	statuses := make([]int, len(passwords))
+	var wg sync.WaitGroup
+	for i, password := range passwords {
+		wg.Add(1)
+		go func(i int, password string) {
+			defer wg.Done()
+			w := s.do("POST", "/auth/password/reset", `{"token":"`+token+`","password":"`+password+`"}`, "")
+			statuses[i] = w.Code
+		}(i, password)
+	}
+	wg.Wait()
+
+	winner := -1
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			continue
+		}
+		if winner >= 0 {
+			t.Fatalf("the reset link was used twice, statuses %v", statuses)
+		}
+		winner = i
+	}
+	if winner < 0 {
+		t.Fatalf("no reset went through, statuses %v", statuses)
+	}
+	// The password is the one of the request that won
+	s.login("rey", passwords[winner])
+}
+
+func TestChangePassword(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+	other := s.login("rey", userPassword)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"no current password", `{"password":"` + newPassword + `"}`, http.StatusBadRequest},
+		{"wrong current password", `{"current_password":"wrong","password":"` + newPassword + `"}`, http.StatusUnauthorized},
+		{"current password", `{"current_password":"` + userPassword + `","password":"` + newPassword + `"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, s.do("PATCH", "/api/me/changepassword", tt.body, rey.Token), tt.status)
+		})
+	}
+
+	// The session that made the change stays, the others end
+	expectStatus(t, s.do("GET", "/api/me", "", rey.Token), http.StatusOK)
+	expectStatus(t, s.do("GET", "/api/me", "", other.Token), http.StatusUnauthorized)
+	w := s.do("POST", "/auth/refresh", `{"refresh_token":"`+other.RefreshToken+`"}`, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+	s.login("rey", newPassword)
+}
@@ -0,0 +1,142 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/utils"
+)
+
+func (s *testServer) refresh(token string) (session, int) {
+	s.t.Helper()
+	w := s.do("POST", "/auth/refresh", `{"refresh_token":"`+token+`"}`, "")
+	var sess session
+	if w.Code == http.StatusOK {
+		decode(s.t, w, &sess)
+	}
+	return sess, w.Code
+}
+
+func (s *testServer) storedRefresh(token string) *models.RefreshToken {
+	s.t.Helper()
+	stored, err := s.tokens.GetRefresh(context.Background(), utils.HashToken(token))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return stored
+}
+
+func TestRefreshRotation(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+
+	next, status := s.refresh(rey.RefreshToken)
+	if status != http.StatusOK {
+		t.Fatalf("refresh status = %d", status)
+	}
+	if next.RefreshToken == rey.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	expectStatus(t, s.do("GET", "/api/me", "", next.Token), http.StatusOK)
+
+	first, second := s.storedRefresh(rey.RefreshToken), s.storedRefresh(next.RefreshToken)
+	if second.Family != first.Family {
+		t.Errorf("family = %s, want %s", second.Family, first.Family)
+	}
+	if !second.FamilyStart.Equal(first.FamilyStart) || !second.ExpiresAt.Equal(first.ExpiresAt) {
+		t.Errorf("rotation moved the session end from %s to %s", first.ExpiresAt, second.ExpiresAt)
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+
+	next, status := s.refresh(rey.RefreshToken)
+	if status != http.StatusOK {
+		t.Fatalf("refresh status = %d", status)
+	}
+
+	// The rotated token comes back: whoever holds either copy is logged out
+	if _, status := s.refresh(rey.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("reused token status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if _, status := s.refresh(next.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("latest token status = %d after reuse, want %d", status, http.StatusUnauthorized)
+	}
+	expectStatus(t, s.do("GET", "/api/me", "", next.Token), http.StatusUnauthorized)
+
+	// Other sessions of the user are left alone
+	other := s.login("rey", userPassword)
+	expectStatus(t, s.do("GET", "/api/me", "", other.Token), http.StatusOK)
+}
+
+func TestRefreshSessionEnd(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+	ctx := context.Background()
+
+	// A session started almost a refresh lifetime ago keeps its end
+	started := time.Now().UTC().Add(-testLifetimes.Refresh + time.Minute)
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.tokens.SaveRefresh(ctx, &models.RefreshToken{
+		ID:          hash,
+		UserID:      rey.ID,
+		Family:      utils.NewTokenID(),
+		FamilyStart: started,
+		ExpiresAt:   started.Add(testLifetimes.Refresh),
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, status := s.refresh(token)
+	if status != http.StatusOK {
+		t.Fatalf("refresh status = %d", status)
+	}
+	stored := s.storedRefresh(next.RefreshToken)
+	if want := started.Add(testLifetimes.Refresh); !stored.ExpiresAt.Equal(want) {
+		t.Errorf("expires at %s, want the session end %s", stored.ExpiresAt, want)
+	}
+
+	claims, err := s.keys.VerifyJWT(next.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.ExpiresAt.After(stored.ExpiresAt.Add(time.Second)) {
+		t.Errorf("access token expires at %s, after the session end %s", claims.ExpiresAt, stored.ExpiresAt)
+	}
+}
+
+func TestRefreshChecksAccount(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		username string
+		fields   map[string]interface{}
+	}{
+		{"blocked", "rey", map[string]interface{}{"isblocked": true}},
+		{"locked out", "finn", map[string]interface{}{"isblocked": true, "lockeduntil": time.Now().UTC().Add(time.Hour)}},
+		{"not activated", "poe", map[string]interface{}{"isactivated": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := s.signup(tt.username)
+			if err := s.users.Update(ctx, user.ID, tt.fields); err != nil {
+				t.Fatal(err)
+			}
+			if _, status := s.refresh(user.RefreshToken); status != http.StatusForbidden {
+				t.Errorf("refresh status = %d, want %d", status, http.StatusForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"golang.elasticsearch/charts"
+	"golang.elasticsearch/mailer"
+	"golang.elasticsearch/middleware"
+	auth "golang.elasticsearch/middleware/auth"
+	media "golang.elasticsearch/middleware/media"
+	prods "golang.elasticsearch/middleware/prods"
+	users "golang.elasticsearch/middleware/users"
+	"golang.elasticsearch/models"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/storage"
+	"golang.elasticsearch/utils"
+	"golang.elasticsearch/validation"
+)
+
+// Dependencies holds the storage and signing keys used by every handler. main
+// wires the Elasticsearch implementations; tests can pass the in-memory ones.
+type Dependencies struct {
+	Users    repository.UserRepository
+	Products repository.ProductRepository
+	Sales    repository.SalesRepository
+	Stock    repository.StockRepository
+	Tokens   repository.TokenStore
+	Attempts repository.AttemptStore
+	Keys     *utils.KeyManager
+	Mailer   mailer.Mailer
+	// Blobs keeps uploaded files such as product images and profile pictures.
+	Blobs storage.BlobStore
+	// AppURL is the frontend address put in verification and reset links.
+	AppURL string
+	// CORSOrigins may call the API from a browser. "*" allows any origin,
+	// without credentials.
+	CORSOrigins []string
+	Lifetimes   auth.Lifetimes
+	// AssetsDir holds the static images, the product pictures placed by hand
+	// and the profile pictures uploaded before the blob store.
+	AssetsDir string
+}
+
+// New builds the Gin engine with the full route set.
+func New(deps Dependencies) *gin.Engine {
+	validation.Register()
+
+	router := gin.Default()
+	// Profile pictures are served by the users handler, not from assets/users
+	router.Static("/assets/images", filepath.Join(deps.AssetsDir, "images"))
+	router.Static("/assets/products", filepath.Join(deps.AssetsDir, "products"))
+
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler,
+		ginSwagger.InstanceName("swagger"),
+		ginSwagger.DefaultModelsExpandDepth(-1),
+	))
+
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:     deps.CORSOrigins,
+		AllowMethods:     []string{"GET", "POST", "OPTIONS", "PUT", "PATCH", "DELETE", "HEAD"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: !slices.Contains(deps.CORSOrigins, "*"),
+		MaxAge:           12 * time.Hour,
+	}))
+
+	logo := filepath.Join(deps.AssetsDir, "images", "logo.png")
+	authHandler := auth.NewHandler(deps.Users, deps.Tokens, deps.Attempts, deps.Keys, deps.Mailer, deps.AppURL, deps.Lifetimes)
+	userHandler := users.NewHandler(deps.Users, deps.Blobs, filepath.Join(deps.AssetsDir, "users"))
+	prodHandler := prods.NewHandler(deps.Products, deps.Sales, deps.Stock, charts.NewService(logo), deps.Blobs, logo)
+	mediaHandler := media.NewHandler(deps.Blobs)
+
+	authenticate := middleware.AuthMiddleware(deps.Users, deps.Tokens, deps.Keys)
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+	ownerOnly := middleware.RequireOwnerOrAdmin("id")
+
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+	router.GET("/media/*key", mediaHandler.Serve)
+	router.HEAD("/media/*key", mediaHandler.Serve)
+	router.GET("/users/:id/picture", userHandler.GetPicture)
+	router.POST("/auth/signin", authHandler.Login)
+	router.POST("/auth/signup", authHandler.Register)
+	router.POST("/auth/mfa/verify", authHandler.MfaLogin)
+	router.POST("/auth/refresh", authHandler.Refresh)
+	router.POST("/auth/verify", authHandler.VerifyEmail)
+	router.POST("/auth/verify/resend", authHandler.ResendVerification)
+	router.POST("/auth/password/forgot", authHandler.ForgotPassword)
+	router.POST("/auth/password/reset", authHandler.ResetPassword)
+	router.POST("/auth/logout", authenticate, authHandler.Logout)
+	router.POST("/addproduct", authenticate, adminOnly, prodHandler.AddProduct)
+	router.GET("/products/list", prodHandler.ListProducts)
+	router.GET("/products/search", prodHandler.CatalogueSearch)
+	router.GET("/products/suggest", prodHandler.SuggestProducts)
+	router.GET("/api/products/:id", prodHandler.GetProduct)
+	router.GET("/productreport", prodHandler.ProductPDFReport)
+	router.GET("/sales/barchart", prodHandler.GetSalesChart)
+	router.GET("/sales/piechart", prodHandler.GetLineChart)
+
+	authGuard := router.Group("/api")
+	authGuard.Use(authenticate)
+	{
+		authGuard.GET("/getallusers", adminOnly, userHandler.GetAllUsers)
+		authGuard.GET("/getuserbyid/:id", ownerOnly, userHandler.GetUserid)
+		authGuard.PATCH("/mfa/activate/:id", ownerOnly, authHandler.MfaActivate)
+		authGuard.PATCH("/mfa/verifytotp/:id", ownerOnly, authHandler.MfaVerifyotp)
+		authGuard.GET("/mfa/recoverycodes/:id", ownerOnly, authHandler.MfaRecoveryCount)
+		authGuard.POST("/mfa/recoverycodes/:id", ownerOnly, authHandler.MfaRecoveryRegenerate)
+		authGuard.PATCH("/changepassword/:id", ownerOnly, authHandler.ChangePassword)
+		authGuard.PATCH("/updateprofile/:id", ownerOnly, userHandler.UpdateProfile)
+		authGuard.PATCH("/uploadpicture/:id", ownerOnly, userHandler.UploadPicture)
+		authGuard.DELETE("/deleteuserbyid/:id", adminOnly, userHandler.DeleteUserid)
+		authGuard.GET("/products/alerts", prodHandler.GetStockAlerts)
+		authGuard.PUT("/products/:id", adminOnly, prodHandler.ReplaceProduct)
+		authGuard.PATCH("/products/:id", adminOnly, prodHandler.PatchProduct)
+		authGuard.DELETE("/products/:id", adminOnly, prodHandler.DeleteProduct)
+		authGuard.GET("/products/export", adminOnly, prodHandler.ExportProducts)
+		authGuard.POST("/products/import", adminOnly, prodHandler.ImportProducts)
+		authGuard.POST("/products/:id/image", adminOnly, prodHandler.UploadProductImage)
+		authGuard.DELETE("/products/:id/image", adminOnly, prodHandler.DeleteProductImage)
+		authGuard.GET("/products/:id/movements", prodHandler.GetStockMovements)
+		authGuard.POST("/products/:id/movements", adminOnly, prodHandler.PostStockMovement)
+		authGuard.POST("/sales", adminOnly, prodHandler.PostSale)
+		authGuard.GET("/sales/analytics", prodHandler.GetSalesAnalytics)
+	}
+
+	// Self-service routes acting on the authenticated user
+	meGuard := authGuard.Group("/me")
+	meGuard.Use(middleware.Self("id"))
+	{
+		meGuard.GET("", userHandler.GetUserid)
+		meGuard.PATCH("/mfa/activate", authHandler.MfaActivate)
+		meGuard.PATCH("/mfa/verifytotp", authHandler.MfaVerifyotp)
+		meGuard.GET("/mfa/recoverycodes", authHandler.MfaRecoveryCount)
+		meGuard.POST("/mfa/recoverycodes", authHandler.MfaRecoveryRegenerate)
+		meGuard.PATCH("/changepassword", authHandler.ChangePassword)
+		meGuard.PATCH("/updateprofile", userHandler.UpdateProfile)
+		meGuard.PATCH("/uploadpicture", userHandler.UploadPicture)
+	}
+
+	adminGuard := authGuard.Group("/admin")
+	adminGuard.Use(adminOnly)
+	{
+		adminGuard.PUT("/users/:id/roles/:role", userHandler.GrantRole)
+		adminGuard.DELETE("/users/:id/roles/:role", userHandler.RevokeRole)
+		adminGuard.POST("/users/:id/unlock", authHandler.UnlockUser)
+		adminGuard.POST("/ips/:ip/unlock", authHandler.UnlockIP)
+	}
+
+	return router
+}
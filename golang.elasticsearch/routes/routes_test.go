@@ -0,0 +1,273 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.elasticsearch/mailer"
+	auth "golang.elasticsearch/middleware/auth"
+	"golang.elasticsearch/repository"
+	"golang.elasticsearch/storage"
+	"golang.elasticsearch/utils"
+)
+
+const (
+	adminName     = "admin"
+	adminPassword = "admin1234"
+	userPassword  = "secret123"
+)
+
+var testLifetimes = auth.Lifetimes{
+	Access:        15 * time.Minute,
+	Refresh:       7 * 24 * time.Hour,
+	Challenge:     5 * time.Minute,
+	VerifyEmail:   24 * time.Hour,
+	PasswordReset: 30 * time.Minute,
+}
+
+// outbox keeps the mails sent instead of delivering them.
+type outbox struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mailer.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+var mailTokenPattern = regexp.MustCompile(`token=([^\s&]+)`)
+
+// lastToken returns the token in the link of the last mail sent to address.
+func (o *outbox) lastToken(t *testing.T, address string) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := len(o.sent) - 1; i >= 0; i-- {
+		if o.sent[i].To != address {
+			continue
+		}
+		if m := mailTokenPattern.FindStringSubmatch(o.sent[i].Body); m != nil {
+			return m[1]
+		}
+	}
+	t.Fatalf("no mail with a token sent to %s", address)
+	return ""
+}
+
+// testServer is the full route set over the in-memory repositories.
+type testServer struct {
+	t        *testing.T
+	router   *gin.Engine
+	users    repository.UserRepository
+	products repository.ProductRepository
+	tokens   repository.TokenStore
+	attempts repository.AttemptStore
+	keys     *utils.KeyManager
+	blobs    storage.BlobStore
+	mail     *outbox
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	keys, err := utils.NewEphemeralKeyManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{
+		t:        t,
+		users:    repository.NewMemoryUserRepository(),
+		products: repository.NewMemoryProductRepository(),
+		tokens:   repository.NewMemoryTokenStore(),
+		attempts: repository.NewMemoryAttemptStore(),
+		keys:     keys,
+		blobs:    storage.NewLocalStore(t.TempDir()),
+		mail:     &outbox{},
+	}
+	if err := repository.SeedAdmin(context.Background(), s.users, adminName, "admin@example.com", adminPassword, false); err != nil {
+		t.Fatal(err)
+	}
+
+	s.router = New(Dependencies{
+		Users:       s.users,
+		Products:    s.products,
+		Sales:       repository.NewMemorySalesRepository(),
+		Stock:       repository.NewMemoryStockRepository(s.products),
+		Tokens:      s.tokens,
+		Attempts:    s.attempts,
+		Keys:        s.keys,
+		Mailer:      s.mail,
+		Blobs:       s.blobs,
+		AppURL:      "http://app.test",
+		CORSOrigins: []string{"*"},
+		Lifetimes:   testLifetimes,
+		AssetsDir:   "../assets",
+	})
+	return s
+}
+
+// do sends a JSON request, authenticated when token is set.
+func (s *testServer) do(method, path, body, token string) *httptest.ResponseRecorder {
+	return s.send(method, path, "application/json", strings.NewReader(body), token)
+}
+
+func (s *testServer) send(method, path, contentType string, body *strings.Reader, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// session is the token pair a login or refresh hands out.
+type session struct {
+	ID           string `json:"id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *testServer) login(username, password string) session {
+	s.t.Helper()
+	w := s.do("POST", "/auth/signin", `{"username":"`+username+`","password":"`+password+`"}`, "")
+	expectStatus(s.t, w, http.StatusOK)
+	var sess session
+	decode(s.t, w, &sess)
+	return sess
+}
+
+// signup registers username, confirms its email and logs it in.
+func (s *testServer) signup(username string) session {
+	s.t.Helper()
+	email := username + "@example.com"
+	w := s.do("POST", "/auth/signup", `{"email":"`+email+`","username":"`+username+`","password":"`+userPassword+`"}`, "")
+	expectStatus(s.t, w, http.StatusCreated)
+	w = s.do("POST", "/auth/verify", `{"token":"`+s.mail.lastToken(s.t, email)+`"}`, "")
+	expectStatus(s.t, w, http.StatusOK)
+	return s.login(username, userPassword)
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %s", w.Body.String(), err)
+	}
+}
+
+func TestProductLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+
+	w := s.do("POST", "/addproduct", `{"sku":"W-1","category":"tools","descriptions":"Widget","qty":4,"costprice":1,"sellprice":2.5}`, admin.Token)
+	expectStatus(t, w, http.StatusCreated)
+	var created struct {
+		ID string `json:"id"`
+	}
+	decode(t, w, &created)
+	id := created.ID
+	if id == "" {
+		t.Fatalf("no product id in %s", w.Body.String())
+	}
+
+	w = s.do("PATCH", "/api/products/"+id, `{"sellprice":3}`, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.do("GET", "/api/products/"+id, "", "")
+	expectStatus(t, w, http.StatusOK)
+	var product struct {
+		Sellprice float64 `json:"sellprice"`
+		Qty       float64 `json:"qty"`
+	}
+	decode(t, w, &product)
+	if product.Sellprice != 3 || product.Qty != 4 {
+		t.Errorf("product = %+v, want sellprice 3 and qty 4", product)
+	}
+
+	w = s.do("DELETE", "/api/products/"+id, "", admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	w = s.do("GET", "/api/products/"+id, "", "")
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestProductValidation(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+
+	w := s.do("POST", "/addproduct", `{"category":"tools","descriptions":"Widget","costprice":5,"sellprice":2}`, admin.Token)
+	expectStatus(t, w, http.StatusBadRequest)
+	var body struct {
+		Errors []struct {
+			Field string `json:"field"`
+			Rule  string `json:"rule"`
+		} `json:"errors"`
+	}
+	decode(t, w, &body)
+	if len(body.Errors) != 1 || body.Errors[0].Field != "sellprice" || body.Errors[0].Rule != "gtefield" {
+		t.Errorf("errors = %+v, want one gtefield error on sellprice", body.Errors)
+	}
+}
+
+func TestRouteGuards(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+	rey := s.signup("rey")
+	finn := s.signup("finn")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"anonymous", "GET", "/api/me", "", http.StatusUnauthorized},
+		{"forged token", "GET", "/api/me", "not-a-jwt", http.StatusUnauthorized},
+		{"own profile", "GET", "/api/me", rey.Token, http.StatusOK},
+		{"own id", "GET", "/api/getuserbyid/" + rey.ID, rey.Token, http.StatusOK},
+		{"other user", "GET", "/api/getuserbyid/" + finn.ID, rey.Token, http.StatusForbidden},
+		{"admin reads any user", "GET", "/api/getuserbyid/" + finn.ID, admin.Token, http.StatusOK},
+		{"user lists users", "GET", "/api/getallusers", rey.Token, http.StatusForbidden},
+		{"admin lists users", "GET", "/api/getallusers", admin.Token, http.StatusOK},
+		{"user adds product", "POST", "/addproduct", rey.Token, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, `{}`, tt.token)
+			expectStatus(t, w, tt.status)
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	s := newTestServer(t)
+	rey := s.signup("rey")
+
+	expectStatus(t, s.do("POST", "/auth/logout", "", rey.Token), http.StatusOK)
+	expectStatus(t, s.do("GET", "/api/me", "", rey.Token), http.StatusUnauthorized)
+	w := s.do("POST", "/auth/refresh", `{"refresh_token":"`+rey.RefreshToken+`"}`, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login(adminName, adminPassword)
+	rey := s.signup("rey")
+
+	// Two failures are free, after the third even the right password waits
+	bad := `{"username":"rey","password":"wrong"}`
+	for i := 0; i < 3; i++ {
+		expectStatus(t, s.do("POST", "/auth/signin", bad, ""), http.StatusBadRequest)
+	}
+	w := s.do("POST", "/auth/signin", `{"username":"rey","password":"`+userPassword+`"}`, "")
+	expectStatus(t, w, http.StatusTooManyRequests)
+	if seconds, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || seconds < 1 {
+		t.Errorf("Retry-After = %q, want a number of seconds", w.Header().Get("Retry-After"))
+	}
+
+	// An administrator clears both counters
+	expectStatus(t, s.do("POST", "/api/admin/users/"+rey.ID+"/unlock", "", admin.Token), http.StatusOK)
+	expectStatus(t, s.do("POST", "/api/admin/ips/192.0.2.1/unlock", "", admin.Token), http.StatusOK)
+	s.login("rey", userPassword)
+}
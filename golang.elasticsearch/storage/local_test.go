@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	testBlobStore(t, NewLocalStore(t.TempDir()))
+}
+
+func TestLocalStoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStore(dir)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "products/12/original.jpg", []byte("jpeg"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	// No temporary file stays next to the blob
+	entries, err := os.ReadDir(filepath.Join(dir, "products", "12"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "original.jpg" {
+		t.Errorf("directory holds %v, want only original.jpg", entries)
+	}
+
+	// A directory is not a blob
+	if _, err := store.Get(ctx, "products/12"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v for a directory, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 stands in for an S3 server: it keeps the objects of one bucket in
+// memory and refuses requests whose Signature Version 4 does not check out.
+type fakeS3 struct {
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		bucket:    "media",
+		region:    "eu-west-1",
+		accessKey: "AKIDEXAMPLE",
+		secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		objects:   map[string]fakeObject{},
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeS3) config(endpoint string) S3Config {
+	return S3Config{
+		Endpoint:  endpoint,
+		Region:    f.region,
+		Bucket:    f.bucket,
+		AccessKey: f.accessKey,
+		SecretKey: f.secretKey,
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.verify(r, body); err != nil {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+err.Error()+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = fakeObject{data: body, contentType: r.Header.Get("Content-Type"), modTime: time.Now()}
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("ETag", `"`+sha256Hex(obj.data)[:32]+`"`)
+		w.Header().Set("Last-Modified", obj.modTime.UTC().Format(http.TimeFormat))
+		w.Write(obj.data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify recomputes the signature from the request as it came over the wire.
+func (f *fakeS3) verify(r *http.Request, body []byte) error {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return fmt.Errorf("not signed")
+	}
+	parts := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		parts[name] = value
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return fmt.Errorf("bad x-amz-date %q", amzDate)
+	}
+	scope := amzDate[:8] + "/" + f.region + "/s3/aws4_request"
+	if parts["Credential"] != f.accessKey+"/"+scope {
+		return fmt.Errorf("bad credential %q", parts["Credential"])
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+		return fmt.Errorf("payload hash does not match the body")
+	}
+
+	path, query, _ := strings.Cut(r.RequestURI, "?")
+	canonical := []string{r.Method, path, query}
+	for _, name := range strings.Split(parts["SignedHeaders"], ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonical = append(canonical, name+":"+strings.TrimSpace(value))
+	}
+	canonical = append(canonical, "", parts["SignedHeaders"], sha256Hex(body))
+
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(strings.Join(canonical, "\n")))
+	key := []byte("AWS4" + f.secretKey)
+	for _, part := range []string{amzDate[:8], f.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(hmacSHA256(key, toSign)); parts["Signature"] != want {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+func TestS3Store(t *testing.T) {
+	f, server := newFakeS3(t)
+	testBlobStore(t, NewS3Store(f.config(server.URL+"/")))
+}
+
+func TestS3StoreObjectKeys(t *testing.T) {
+	f, server := newFakeS3(t)
+	store := NewS3Store(f.config(server.URL))
+
+	key := "products/12/ab34 medium+v2.jpg"
+	if err := store.Put(context.Background(), key, []byte("jpeg"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[key]; !ok {
+		t.Errorf("stored keys %v, want %q", f.objects, key)
+	}
+}
+
+func TestS3StoreRefused(t *testing.T) {
+	f, server := newFakeS3(t)
+	cfg := f.config(server.URL)
+	cfg.SecretKey = "wrong"
+	store := NewS3Store(cfg)
+
+	err := store.Put(context.Background(), "products/1/a.jpg", []byte("jpeg"), "image/jpeg")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("err = %v, want the 403 of the server", err)
+	}
+	if _, err := store.Get(context.Background(), "products/1/a.jpg"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want the server error", err)
+	}
+}
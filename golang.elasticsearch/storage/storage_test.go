@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// testBlobStore runs the behaviour every BlobStore shares.
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+
+	t.Run("round trip", func(t *testing.T) {
+		key := "products/12/ab34 medium+v2.jpg"
+		if err := store.Put(ctx, key, []byte("first"), "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put(ctx, key, []byte("second"), "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+
+		obj, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer obj.Body.Close()
+		data, err := io.ReadAll(obj.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "second" || obj.Size != int64(len(data)) {
+			t.Errorf("read %q of size %d, want the last write", data, obj.Size)
+		}
+		if obj.ContentType != "image/jpeg" {
+			t.Errorf("content type = %q, want image/jpeg", obj.ContentType)
+		}
+		if obj.ETag == "" || obj.ModTime.IsZero() {
+			t.Errorf("etag %q and modification time %s, want both set", obj.ETag, obj.ModTime)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := store.Get(ctx, "products/none.jpg"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("err = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		key := "users/7/picture.png"
+		if err := store.Put(ctx, key, []byte("png"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("delete %d: %s", i+1, err)
+			}
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("err = %v after delete, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "/etc/passwd", "../secret", "products/../../x", "products//x", `products\x`, "products/./x"} {
+			if err := store.Put(ctx, key, []byte("x"), "text/plain"); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Put(%q) err = %v, want ErrInvalidKey", key, err)
+			}
+			if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Get(%q) err = %v, want ErrInvalidKey", key, err)
+			}
+			if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Delete(%q) err = %v, want ErrInvalidKey", key, err)
+			}
+		}
+	})
+}
@@ -2,58 +2,99 @@ package utils
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var secret = os.Getenv("JWT_SECRET")
-var jwtKey = []byte(secret)
+const issuer = "BARCLAYS BANK"
 
+// Audiences keep session tokens and MFA challenge tokens apart. Services
+// checking our tokens through the JWKS must require AccessAudience, so that
+// a challenge token, signed with the same key, is never taken for a session.
+const (
+	AccessAudience    = "api"
+	ChallengeAudience = "mfa_challenge"
+)
+
+// Token types, carried in the "typ" header.
+const (
+	accessType    = "at+jwt"
+	challengeType = "mfa-challenge+jwt"
+)
+
+// Claims are carried by every token. SessionID is shared by all tokens issued
+// for one login, so the whole session can be revoked at once.
 type Claims struct {
-	Username string `json:"username"`
+	Username  string   `json:"username"`
+	Roles     []string `json:"roles"`
+	SessionID string   `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(username string) (string, error) {
+// GenerateJWT issues a session token valid for lifetime. The subject is the
+// user document id.
+func (km *KeyManager) GenerateJWT(userID string, username string, roles []string, sessionID string, lifetime time.Duration) (string, error) {
 
-	// it will expire in 8 hours
-	expirationTime := time.Now().Add(8 * time.Hour)
+	expirationTime := time.Now().Add(lifetime)
 
 	claims := &Claims{
-		Username: username,
+		Username:  username,
+		Roles:     roles,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "BARCLAYS BANK",
-			Subject:   "user_authentication",
-			ID:        "1",
+			Issuer:    issuer,
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{AccessAudience},
+			ID:        NewTokenID(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return km.sign(claims, accessType)
+}
 
-	tokenString, err := token.SignedString(jwtKey)
+// GenerateChallengeJWT issues a short-lived token that can only be exchanged,
+// together with an OTP code, for a session token. Its id is meant to be used
+// once.
+func (km *KeyManager) GenerateChallengeJWT(userID string, lifetime time.Duration) (string, error) {
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    issuer,
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{ChallengeAudience},
+			ID:        NewTokenID(),
+		},
+	}
+
+	return km.sign(claims, challengeType)
+}
+
+// VerifyJWT validates a session token. Tokens for any other audience are
+// refused.
+func (km *KeyManager) VerifyJWT(tokenString string) (*Claims, error) {
+	return km.parseClaims(tokenString, AccessAudience, accessType)
+}
 
+// VerifyChallengeJWT validates a token issued by GenerateChallengeJWT.
+func (km *KeyManager) VerifyChallengeJWT(tokenString string) (*Claims, error) {
+	claims, err := km.parseClaims(tokenString, ChallengeAudience, challengeType)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("invalid challenge token: %w", err)
 	}
-
-	return tokenString, nil
+	return claims, nil
 }
 
-func VerifyJWT(tokenString string) (*Claims, error) {
+func (km *KeyManager) parseClaims(tokenString string, audience string, typ string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return jwtKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, km.keyFunc,
+		jwt.WithAudience(audience), jwt.WithIssuer(issuer), jwt.WithExpirationRequired())
 
 	if err != nil {
 		if err == jwt.ErrSignatureInvalid {
@@ -62,7 +103,7 @@ func VerifyJWT(tokenString string) (*Claims, error) {
 		return nil, err
 	}
 
-	if !token.Valid {
+	if !token.Valid || token.Header["typ"] != typ {
 		return nil, fmt.Errorf("invalid token")
 	}
 
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKeyManager(t *testing.T) *KeyManager {
+	t.Helper()
+	km, err := NewEphemeralKeyManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return km
+}
+
+func TestTokenAudiences(t *testing.T) {
+	km := newTestKeyManager(t)
+
+	access, err := km.GenerateJWT("u1", "rey@example.com", []string{"user"}, "s1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := km.GenerateChallengeJWT("u1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := km.VerifyJWT(access)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "u1" || claims.SessionID != "s1" || claims.Issuer != issuer {
+		t.Errorf("claims = %+v", claims)
+	}
+	if _, err := km.VerifyChallengeJWT(challenge); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := km.VerifyJWT(challenge); err == nil {
+		t.Error("a challenge token passed as a session token")
+	}
+	if _, err := km.VerifyChallengeJWT(access); err == nil {
+		t.Error("a session token passed as a challenge token")
+	}
+}
+
+// Other services only see the standard claims, so those alone must tell the
+// kinds of token apart.
+func TestTokenTypesInHeaders(t *testing.T) {
+	km := newTestKeyManager(t)
+	challenge, err := km.GenerateChallengeJWT("u1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(challenge, claims, km.keyFunc, jwt.WithAudience(AccessAudience))
+	if err == nil {
+		t.Error("a challenge token has the access audience")
+	}
+	if token.Header["typ"] != challengeType {
+		t.Errorf("typ = %v, want %s", token.Header["typ"], challengeType)
+	}
+}
+
+func TestTokenWithoutAudienceRefused(t *testing.T) {
+	km := newTestKeyManager(t)
+	// As issued before tokens carried an audience
+	old, err := km.sign(&Claims{
+		Username: "rey@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			Issuer:    issuer,
+			Subject:   "u1",
+		},
+	}, accessType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := km.VerifyJWT(old); err == nil {
+		t.Error("a token without an audience was accepted")
+	}
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyManager signs tokens with one active key and verifies them with any key
+// it knows, selected by the "kid" header.
+//
+// Keys are PEM files in a directory, the file name being the kid:
+// "<kid>.pem" holds a private key (RSA for RS256, Ed25519 for EdDSA) and
+// "<kid>.pub.pem" a public key kept only to verify tokens issued before a
+// rotation. To rotate, add the new private key, point JWT_SIGNING_KID at it
+// and keep the old file until its tokens have expired.
+type KeyManager struct {
+	signing string
+	keys    map[string]verificationKey
+	private map[string]crypto.Signer
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// LoadKeyManager reads every key in dir. When signingKid is empty the last
+// private key in name order signs new tokens.
+func LoadKeyManager(dir string, signingKid string) (*KeyManager, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	km := &KeyManager{
+		keys:    make(map[string]verificationKey),
+		private: make(map[string]crypto.Signer),
+	}
+	for _, file := range files {
+		kid := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".pem"), ".pub")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := km.add(kid, data); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if _, ok := km.private[kid]; ok && signingKid == "" {
+			km.signing = kid
+		}
+	}
+
+	if signingKid != "" {
+		km.signing = signingKid
+	}
+	if _, ok := km.private[km.signing]; !ok {
+		return nil, fmt.Errorf("no private key for signing kid %q in %s", km.signing, dir)
+	}
+	return km, nil
+}
+
+// NewEphemeralKeyManager generates an Ed25519 key that lives only as long as
+// the process. Tokens do not survive a restart, so it is meant for local use.
+func NewEphemeralKeyManager() (*KeyManager, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := "ephemeral-" + NewTokenID()[:8]
+	return &KeyManager{
+		signing: kid,
+		keys:    map[string]verificationKey{kid: {method: jwt.SigningMethodEdDSA, public: private.Public()}},
+		private: map[string]crypto.Signer{kid: private},
+	}, nil
+}
+
+func (km *KeyManager) add(kid string, data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		km.private[kid] = k
+		km.keys[kid] = verificationKey{method: jwt.SigningMethodRS256, public: k.Public()}
+	case ed25519.PrivateKey:
+		km.private[kid] = k
+		km.keys[kid] = verificationKey{method: jwt.SigningMethodEdDSA, public: k.Public()}
+	case *rsa.PublicKey:
+		km.keys[kid] = verificationKey{method: jwt.SigningMethodRS256, public: k}
+	case ed25519.PublicKey:
+		km.keys[kid] = verificationKey{method: jwt.SigningMethodEdDSA, public: k}
+	default:
+		return fmt.Errorf("unsupported key type %T, use RSA or Ed25519", key)
+	}
+	return nil
+}
+
+func (km *KeyManager) sign(claims *Claims, typ string) (string, error) {
+	key := km.keys[km.signing]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = km.signing
+	token.Header["typ"] = typ
+	return token.SignedString(km.private[km.signing])
+}
+
+func (km *KeyManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := km.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// JWK is one entry of the JSON Web Key Set.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS lists every verification key so other services can check our tokens.
+func (km *KeyManager) JWKS() []JWK {
+	kids := make([]string, 0, len(km.keys))
+	for kid := range km.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	b64 := base64.RawURLEncoding
+	set := make([]JWK, 0, len(kids))
+	for _, kid := range kids {
+		key := km.keys[kid]
+		jwk := JWK{Kid: kid, Alg: key.method.Alg(), Use: "sig"}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64.EncodeToString(pub.N.Bytes())
+			jwk.E = b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = b64.EncodeToString(pub)
+		}
+		set = append(set, jwk)
+	}
+	return set
+}
@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey stores the private key of kid in dir, or only its public key when
+// public is set, as a retired key is kept.
+func writeKey(t *testing.T, dir, kid string, key crypto.Signer, public bool) {
+	t.Helper()
+	var block *pem.Block
+	var err error
+	name := kid + ".pem"
+	if public {
+		name = kid + ".pub.pem"
+		block = &pem.Block{Type: "PUBLIC KEY"}
+		block.Bytes, err = x509.MarshalPKIXPublicKey(key.Public())
+	} else {
+		block = &pem.Block{Type: "PRIVATE KEY"}
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newRSA(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func loadKeys(t *testing.T, dir, signingKid string) *KeyManager {
+	t.Helper()
+	km, err := LoadKeyManager(dir, signingKid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return km
+}
+
+func accessToken(t *testing.T, km *KeyManager) string {
+	t.Helper()
+	token, err := km.GenerateJWT("u1", "rey@example.com", []string{"user"}, "s1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func header(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Header
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	old := newEd25519(t)
+	writeKey(t, dir, "2025-01", old, false)
+
+	km := loadKeys(t, dir, "")
+	before := accessToken(t, km)
+	if kid := header(t, before)["kid"]; kid != "2025-01" {
+		t.Fatalf("kid = %v, want 2025-01", kid)
+	}
+
+	// Rotate: a new private key signs, the old one is kept to verify only
+	writeKey(t, dir, "2026-01", newRSA(t), false)
+	if err := os.Remove(filepath.Join(dir, "2025-01.pem")); err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "2025-01", old, true)
+
+	km = loadKeys(t, dir, "")
+	after := accessToken(t, km)
+	h := header(t, after)
+	if h["kid"] != "2026-01" || h["alg"] != "RS256" {
+		t.Errorf("new tokens are signed with %v %v, want 2026-01 RS256", h["kid"], h["alg"])
+	}
+	for name, token := range map[string]string{"older kid": before, "new kid": after} {
+		if _, err := km.VerifyJWT(token); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+
+	// Once the old key is dropped its tokens are refused
+	if err := os.Remove(filepath.Join(dir, "2025-01.pub.pem")); err != nil {
+		t.Fatal(err)
+	}
+	km = loadKeys(t, dir, "")
+	if _, err := km.VerifyJWT(before); err == nil {
+		t.Error("a token of a dropped key was accepted")
+	}
+	if _, err := km.VerifyJWT(after); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadKeyManagerSigningKid(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "a", newEd25519(t), false)
+	writeKey(t, dir, "b", newEd25519(t), false)
+	writeKey(t, dir, "retired", newEd25519(t), true)
+
+	// Pinned to a key other than the last one
+	km := loadKeys(t, dir, "a")
+	if kid := header(t, accessToken(t, km))["kid"]; kid != "a" {
+		t.Errorf("kid = %v, want a", kid)
+	}
+
+	for _, kid := range []string{"retired", "missing"} {
+		if _, err := LoadKeyManager(dir, kid); err == nil {
+			t.Errorf("signing with %q accepted", kid)
+		}
+	}
+	if _, err := LoadKeyManager(t.TempDir(), ""); err == nil {
+		t.Error("a directory without keys was accepted")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyManager(dir, "a"); err == nil {
+		t.Error("a broken key file was accepted")
+	}
+}
+
+// A token naming one key in its header but signed by another must not pass.
+func TestKidMismatchRefused(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "ed", newEd25519(t), false)
+	writeKey(t, dir, "rsa", newRSA(t), false)
+	km := loadKeys(t, dir, "ed")
+
+	claims := &Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{AccessAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		Issuer:    issuer,
+		Subject:   "u1",
+	}}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = "rsa"
+	token.Header["typ"] = accessType
+	forged, err := token.SignedString(km.private["ed"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := km.VerifyJWT(forged); err == nil {
+		t.Error("a token signed under another kid was accepted")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := newRSA(t)
+	edKey := newEd25519(t)
+	writeKey(t, dir, "2026-01", rsaKey, false)
+	writeKey(t, dir, "2025-01", edKey, true)
+	km := loadKeys(t, dir, "")
+
+	set := km.JWKS()
+	if len(set) != 2 || set[0].Kid != "2025-01" || set[1].Kid != "2026-01" {
+		t.Fatalf("keys = %+v, want 2025-01 and 2026-01", set)
+	}
+
+	b64 := base64.RawURLEncoding
+	ed := set[0]
+	if ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != "EdDSA" || ed.Use != "sig" {
+		t.Errorf("Ed25519 key = %+v", ed)
+	}
+	if x, _ := b64.DecodeString(ed.X); !edKey.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+		t.Error("x is not the Ed25519 public key")
+	}
+
+	rs := set[1]
+	if rs.Kty != "RSA" || rs.Alg != "RS256" || rs.Use != "sig" || rs.X != "" {
+		t.Errorf("RSA key = %+v", rs)
+	}
+	n, _ := b64.DecodeString(rs.N)
+	e, _ := b64.DecodeString(rs.E)
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if !rsaKey.PublicKey.Equal(public) {
+		t.Error("n and e are not the RSA public key")
+	}
+
+	// Another service verifies our tokens with the published key alone
+	_, err := jwt.Parse(accessToken(t, km), func(*jwt.Token) (interface{}, error) { return public, nil },
+		jwt.WithValidMethods([]string{rs.Alg}), jwt.WithAudience(AccessAudience), jwt.WithIssuer(issuer))
+	if err != nil {
+		t.Error(err)
+	}
+}
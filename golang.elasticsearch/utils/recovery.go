@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// GenerateRecoveryCodes returns n one-time codes in plain text, to be shown
+// to the user once, and their bcrypt hashes, which are what gets stored.
+func GenerateRecoveryCodes(n int) ([]string, []string, error) {
+	plain := make([]string, 0, n)
+	hashed := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(buf)
+		code = code[:5] + "-" + code[5:]
+
+		hash, err := HashPassword(code)
+		if err != nil {
+			return nil, nil, err
+		}
+		plain = append(plain, code)
+		hashed = append(hashed, hash)
+	}
+	return plain, hashed, nil
+}
+
+// MatchRecoveryCode returns the index of the hash matching code, or -1.
+func MatchRecoveryCode(hashes []string, code string) int {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for i, hash := range hashes {
+		if ComparePassword(hash, []byte(code)) {
+			return i
+		}
+	}
+	return -1
+}
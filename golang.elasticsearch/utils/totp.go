@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const totpPeriod = 30
+
+// ValidateTOTP checks a code against the secret, allowing one period of clock
+// skew either way. It returns the time step the code belongs to; steps at or
+// below lastStep have been used already and are refused to stop replays.
+func ValidateTOTP(code string, secret string, lastStep int64) (int64, bool) {
+	now := time.Now().Unix() / totpPeriod
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+
+	for step := now - 1; step <= now+1; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), opts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,187 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one rejected field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// BindJSON decodes and validates the request body into obj. On failure it
+// writes a 400 response and returns false.
+func BindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		Abort(c, err)
+		return false
+	}
+	return true
+}
+
+// BindQuery is BindJSON for query string parameters.
+func BindQuery(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindQuery(obj); err != nil {
+		Abort(c, err)
+		return false
+	}
+	return true
+}
+
+// Struct validates a value built by the handler itself, such as a partial
+// update merged into the stored document. It writes a 400 response and
+// returns false when the value is invalid.
+func Struct(c *gin.Context, obj interface{}) bool {
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		Abort(c, err)
+		return false
+	}
+	return true
+}
+
+// Abort answers a bad request body with the validation envelope:
+//
+//	{"message": "<first problem>", "errors": [{"field", "rule", "message"}]}
+func Abort(c *gin.Context, err error) {
+	fields := Translate(err)
+	message := "Invalid request format"
+	if len(fields) > 0 {
+		message = fields[0].Message
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+		"message": message,
+		"errors":  fields,
+	})
+}
+
+// Translate turns binding errors into field level messages. Errors that are
+// not about a particular field, such as malformed JSON, give an empty list.
+func Translate(err error) []FieldError {
+	fields := []FieldError{}
+
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		for _, fe := range invalid {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: message(fe),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		fields = append(fields, FieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be %s", typeErr.Field, kind(typeErr.Type)),
+		})
+	}
+	return fields
+}
+
+// fieldPath drops the struct name from the namespace, leaving the JSON path.
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+func message(fe validator.FieldError) string {
+	field, param := fe.Field(), fe.Param()
+	numeric := isNumber(fe.Kind())
+
+	switch fe.Tag() {
+	case "required":
+		return field + " is required"
+	case "required_without":
+		return fmt.Sprintf("%s is required when %s is empty", field, strings.ToLower(param))
+	case "email":
+		return field + " must be a valid email address"
+	case "mobile":
+		return field + " must be a valid mobile number"
+	case "password":
+		return fmt.Sprintf("%s must be at least %d characters and contain letters and digits", field, MinPasswordLength)
+	case "isodate":
+		return field + " must be a date in YYYY-MM-DD format"
+	case "numeric":
+		return field + " must contain digits only"
+	case "len":
+		return fmt.Sprintf("%s must be exactly %s characters", field, param)
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must have at least %s items", field, param)
+		}
+		if numeric {
+			return fmt.Sprintf("%s must be at least %s", field, param)
+		}
+		return fmt.Sprintf("%s must be at least %s characters", field, param)
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must have at most %s items", field, param)
+		}
+		if numeric {
+			return fmt.Sprintf("%s must be at most %s", field, param)
+		}
+		return fmt.Sprintf("%s must be at most %s characters", field, param)
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, param)
+	case "gte":
+		return fmt.Sprintf("%s must be greater than or equal to %s", field, param)
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", field, param)
+	case "lte":
+		return fmt.Sprintf("%s must be less than or equal to %s", field, param)
+	case "gtefield":
+		return fmt.Sprintf("%s must be greater than or equal to %s", field, strings.ToLower(param))
+	case "ltefield":
+		return fmt.Sprintf("%s must be less than or equal to %s", field, strings.ToLower(param))
+	case "excluded_with":
+		return fmt.Sprintf("%s cannot be used together with %s", field, strings.Join(strings.Fields(strings.ToLower(param)), " or "))
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(strings.Fields(param), ", "))
+	}
+	return fmt.Sprintf("%s is not valid (%s)", field, fe.Tag())
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func kind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case isNumber(t.Kind()):
+		return "a number"
+	case t.Kind() == reflect.String:
+		return "a string"
+	case t.Kind() == reflect.Bool:
+		return "true or false"
+	case t.Kind() == reflect.Slice:
+		return "a list"
+	}
+	return "an object"
+}
@@ -0,0 +1,121 @@
+package validation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.elasticsearch/dto"
+)
+
+type response struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+// bindJSON runs BindJSON on body and decodes the answer, if any.
+func bindJSON(t *testing.T, body string, obj interface{}) (bool, int, response) {
+	t.Helper()
+	Register()
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	ok := BindJSON(c, obj)
+
+	var res response
+	if !ok {
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: %s", err, w.Body)
+		}
+	}
+	return ok, w.Code, res
+}
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		obj  interface{}
+		want response
+	}{
+		{
+			name: "every field reported",
+			body: `{"username":"rey","email":"rey","password":"short","mobile":"1"}`,
+			obj:  &dto.UserRegister{},
+			want: response{
+				Message: "email must be a valid email address",
+				Errors: []FieldError{
+					{"email", "email", "email must be a valid email address"},
+					{"mobile", "mobile", "mobile must be a valid mobile number"},
+					{"password", "password", "password must be at least 8 characters and contain letters and digits"},
+				},
+			},
+		},
+		{
+			name: "nested path",
+			body: `{"items":[{"product_id":"p1","qty":1},{"product_id":"","qty":0}],"salesdate":"2026-13-01"}`,
+			obj:  &dto.Sales{},
+			want: response{
+				Message: "salesdate must be a date in YYYY-MM-DD format",
+				Errors: []FieldError{
+					{"salesdate", "isodate", "salesdate must be a date in YYYY-MM-DD format"},
+					{"items[1].product_id", "required", "product_id is required"},
+					{"items[1].qty", "gt", "qty must be greater than 0"},
+				},
+			},
+		},
+		{
+			name: "struct rule",
+			body: `{"type":"issue","quantity":-1}`,
+			obj:  &dto.StockMovement{},
+			want: response{
+				Message: "quantity must be greater than 0",
+				Errors:  []FieldError{{"quantity", "gt", "quantity must be greater than 0"}},
+			},
+		},
+		{
+			name: "wrong type",
+			body: `{"type":"issue","quantity":"five"}`,
+			obj:  &dto.StockMovement{},
+			want: response{
+				Message: "quantity must be a number",
+				Errors:  []FieldError{{"quantity", "type", "quantity must be a number"}},
+			},
+		},
+		{
+			name: "malformed JSON",
+			body: `{"type":`,
+			obj:  &dto.StockMovement{},
+			want: response{Message: "Invalid request format", Errors: []FieldError{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, status, res := bindJSON(t, tt.body, tt.obj)
+			if ok || status != http.StatusBadRequest {
+				t.Fatalf("bound = %v, status %d", ok, status)
+			}
+			if !reflect.DeepEqual(res, tt.want) {
+				t.Errorf("response = %+v, want %+v", res, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindJSONValid(t *testing.T) {
+	var m dto.StockMovement
+	ok, _, _ := bindJSON(t, `{"type":"adjustment","quantity":-1.5,"note":"Damaged"}`, &m)
+	if !ok {
+		t.Fatal("a valid body was refused")
+	}
+	if m.Quantity != -1.5 || m.Note != "Damaged" {
+		t.Errorf("bound %+v", m)
+	}
+}
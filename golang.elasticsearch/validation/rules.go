@@ -0,0 +1,108 @@
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"golang.elasticsearch/dto"
+	"golang.elasticsearch/models"
+)
+
+var register sync.Once
+
+// Register adds the custom rules to the validator gin binds with, and makes
+// errors report the JSON name of a field. It is safe to call more than once.
+func Register() {
+	register.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+
+		_ = v.RegisterValidation("mobile", mobile)
+		_ = v.RegisterValidation("password", password)
+		_ = v.RegisterValidation("isodate", isodate)
+
+		v.RegisterStructValidation(stockMovement, dto.StockMovement{})
+		v.RegisterStructValidation(productSearch, dto.ProductSearch{})
+		v.RegisterStructValidation(mfaActivation, dto.MfaActivation{})
+	})
+}
+
+var mobilePattern = regexp.MustCompile(`^\+?[0-9][0-9 ()-]*[0-9]$`)
+
+// mobile accepts local and international numbers with 7 to 15 digits, written
+// with optional spaces, dashes and brackets.
+func mobile(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if !mobilePattern.MatchString(value) {
+		return false
+	}
+	digits := 0
+	for _, r := range value {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15
+}
+
+// MinPasswordLength is the shortest password accepted for new credentials.
+const MinPasswordLength = 8
+
+// password requires MinPasswordLength characters with at least one letter and
+// one digit.
+func password(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if len([]rune(value)) < MinPasswordLength {
+		return false
+	}
+	return strings.IndexFunc(value, unicode.IsLetter) >= 0 && strings.IndexFunc(value, unicode.IsDigit) >= 0
+}
+
+// isodate accepts a calendar date in YYYY-MM-DD form.
+func isodate(fl validator.FieldLevel) bool {
+	_, err := time.Parse(time.DateOnly, fl.Field().String())
+	return err == nil
+}
+
+// stockMovement only lets adjustments carry a negative quantity.
+func stockMovement(sl validator.StructLevel) {
+	m := sl.Current().Interface().(dto.StockMovement)
+	if m.Type != models.MovementAdjustment && m.Quantity < 0 {
+		sl.ReportError(m.Quantity, "quantity", "Quantity", "gt", "0")
+	}
+}
+
+// productSearch compares the price bounds only when both are set.
+func productSearch(sl validator.StructLevel) {
+	p := sl.Current().Interface().(dto.ProductSearch)
+	if p.MinPrice != nil && p.MaxPrice != nil && *p.MaxPrice < *p.MinPrice {
+		sl.ReportError(p.MaxPrice, "maxprice", "MaxPrice", "gtefield", "MinPrice")
+	}
+}
+
+// mfaActivation asks for an OTP code or the password when MFA is turned off.
+func mfaActivation(sl validator.StructLevel) {
+	m := sl.Current().Interface().(dto.MfaActivation)
+	if !m.TwoFactoEnabled && m.Otp == "" && m.Password == "" {
+		sl.ReportError(m.Otp, "otp", "Otp", "required_without", "Password")
+	}
+}
@@ -0,0 +1,129 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"golang.elasticsearch/dto"
+)
+
+type fieldRules struct {
+	Mobile   string `json:"mobile" binding:"omitempty,mobile"`
+	Password string `json:"password" binding:"omitempty,password"`
+	Date     string `json:"date" binding:"omitempty,isodate"`
+}
+
+// rules validates obj and returns the rule each rejected field broke.
+func rules(t *testing.T, obj interface{}) map[string]string {
+	t.Helper()
+	Register()
+	broken := map[string]string{}
+	for _, fe := range Translate(binding.Validator.ValidateStruct(obj)) {
+		broken[fe.Field] = fe.Rule
+	}
+	return broken
+}
+
+func TestFieldRules(t *testing.T) {
+	tests := []struct {
+		field string
+		value string
+		valid bool
+	}{
+		{"mobile", "09171234567", true},
+		{"mobile", "+63 917 123 4567", true},
+		{"mobile", "+63 (2) 8123-4567", true},
+		{"mobile", "(02) 8123-4567", false},
+		{"mobile", "123456", false},
+		{"mobile", "1234567890123456", false},
+		{"mobile", "0917-CALL-NOW", false},
+		{"mobile", "+63 917 123 4567 ", false},
+		{"mobile", "++639171234567", false},
+
+		{"password", "secret12", true},
+		{"password", "pässwört1", true},
+		{"password", "secret1", false},
+		{"password", "secretsecret", false},
+		{"password", "1234567890", false},
+
+		{"date", "2026-02-28", true},
+		{"date", "2024-02-29", true},
+		{"date", "2026-02-29", false},
+		{"date", "2026-2-28", false},
+		{"date", "28/02/2026", false},
+		{"date", "2026-02-28T00:00:00Z", false},
+	}
+	for _, tt := range tests {
+		var obj fieldRules
+		switch tt.field {
+		case "mobile":
+			obj.Mobile = tt.value
+		case "password":
+			obj.Password = tt.value
+		case "date":
+			obj.Date = tt.value
+		}
+		broken := rules(t, &obj)
+		if _, failed := broken[tt.field]; failed == tt.valid {
+			t.Errorf("%s %q: valid = %v, want %v", tt.field, tt.value, !failed, tt.valid)
+		}
+	}
+}
+
+func TestStockMovementRule(t *testing.T) {
+	tests := []struct {
+		movement dto.StockMovement
+		broken   string
+	}{
+		{dto.StockMovement{Type: "receipt", Quantity: 5}, ""},
+		{dto.StockMovement{Type: "adjustment", Quantity: -2}, ""},
+		{dto.StockMovement{Type: "issue", Quantity: -2}, "gt"},
+		{dto.StockMovement{Type: "return", Quantity: -0.5}, "gt"},
+		{dto.StockMovement{Type: "issue"}, "required"},
+	}
+	for _, tt := range tests {
+		broken := rules(t, &tt.movement)
+		if got := broken["quantity"]; got != tt.broken || len(broken) > 1 {
+			t.Errorf("%+v broke %v, want quantity %q", tt.movement, broken, tt.broken)
+		}
+	}
+}
+
+func TestProductSearchRule(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+	tests := []struct {
+		search dto.ProductSearch
+		broken bool
+	}{
+		{dto.ProductSearch{}, false},
+		{dto.ProductSearch{MinPrice: price(10)}, false},
+		{dto.ProductSearch{MaxPrice: price(10)}, false},
+		{dto.ProductSearch{MinPrice: price(10), MaxPrice: price(10)}, false},
+		{dto.ProductSearch{MinPrice: price(10), MaxPrice: price(9.99)}, true},
+	}
+	for _, tt := range tests {
+		broken := rules(t, &tt.search)
+		if (broken["maxprice"] == "gtefield") != tt.broken || len(broken) > 1 {
+			t.Errorf("min %v max %v broke %v", tt.search.MinPrice, tt.search.MaxPrice, broken)
+		}
+	}
+}
+
+func TestMfaActivationRule(t *testing.T) {
+	tests := []struct {
+		activation dto.MfaActivation
+		broken     string
+	}{
+		{dto.MfaActivation{TwoFactoEnabled: true}, ""},
+		{dto.MfaActivation{TwoFactoEnabled: false, Otp: "123456"}, ""},
+		{dto.MfaActivation{TwoFactoEnabled: false, Password: "secret12"}, ""},
+		{dto.MfaActivation{TwoFactoEnabled: false}, "required_without"},
+		{dto.MfaActivation{TwoFactoEnabled: false, Otp: "12345"}, "len"},
+	}
+	for _, tt := range tests {
+		broken := rules(t, &tt.activation)
+		if got := broken["otp"]; got != tt.broken || len(broken) > 1 {
+			t.Errorf("%+v broke %v, want otp %q", tt.activation, broken, tt.broken)
+		}
+	}
+}
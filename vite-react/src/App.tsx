@@ -12,6 +12,8 @@ import Profile from './components/Profile.tsx';
 import Productreport from './components/Productreport.tsx';
 import Salesbarchart from './components/Salesbarchart.tsx';
 import Salespiechart from './components/Salespiechart.tsx';
+import Verifyemail from './components/Verifyemail.tsx';
+import Resetpassword from './components/Resetpassword.tsx';
 import './App.css'
 
 function App() {
@@ -30,6 +32,8 @@ function App() {
           <Route path="/pdfreport" element={<Productreport />} />
           <Route path="/salesbarchart" element={<Salesbarchart />} />
           <Route path="/salespiechart" element={<Salespiechart />} />
+          <Route path="/verifyemail" element={<Verifyemail />} />
+          <Route path="/resetpassword" element={<Resetpassword />} />
 
         </Routes>
     </HashRouter>    
@@ -3,6 +3,7 @@ import { Link,useNavigate } from "react-router-dom";
 import Login from "./Login.tsx";
 import Register from "./Register.tsx";
 import { useState, useEffect } from "react";
+import axios from "axios";
 
 export default function Header() {
   const [username, setUsername] = useState<string>('');
@@ -24,11 +25,19 @@ export default function Header() {
     }
   },[username, userpic]);
 
-  const Logout = () => {
+  const Logout = async () => {
+    const token = sessionStorage.getItem('TOKEN');
+    if (token !== null) {
+      // revoke the session server-side, the local logout happens regardless
+      await axios.post("http://localhost:5000/auth/logout", null, {headers: {
+        Authorization: `Bearer ${token}`
+      }}).catch(() => {});
+    }
     sessionStorage.removeItem('USERID');
     sessionStorage.removeItem('USERNAME');
     sessionStorage.removeItem('USERPIC');
     sessionStorage.removeItem('TOKEN');
+    sessionStorage.removeItem('REFRESHTOKEN');
     navigate('/'); 
     location.reload();
   }
@@ -25,12 +25,8 @@ export default function Login() {
     await api.post("auth/signin", jsonData)
     .then((res: any) => {
             setMessage(res.data.message);
-            if (res.data.qrcodeurl !== null) {
-                window.sessionStorage.setItem('USERID',res.data.id);
-                window.sessionStorage.setItem('TOKEN',res.data.token);
-                window.sessionStorage.setItem('ROLE',res.data.roles);
-                const userpic: string = `http://localhost:5000/assets/users/${res.data.userpicture}`;
-                window.sessionStorage.setItem('USERPIC', userpic);
+            if (res.data.mfa_required) {
+                window.sessionStorage.setItem('CHALLENGE',res.data.challenge_token);
                 jQuery("#loginReset").trigger("click");
                 setIsdisabled(false);
                 jQuery("#mfaModal").trigger("click");
@@ -38,8 +34,9 @@ export default function Login() {
                 window.sessionStorage.setItem('USERID',res.data.id);
                 window.sessionStorage.setItem('USERNAME',res.data.username);
                 window.sessionStorage.setItem('TOKEN',res.data.token);                        
+                window.sessionStorage.setItem('REFRESHTOKEN',res.data.refresh_token);
                 window.sessionStorage.setItem('ROLE',res.data.roles);
-                const userpic: string = `http://localhost:5000/assets/users/${res.data.userpicture}`;
+                const userpic: string = `http://localhost:5000/users/${res.data.id}/picture?v=${encodeURIComponent(res.data.userpicture)}`;
                 window.sessionStorage.setItem('USERPIC',userpic);
                 setIsdisabled(false);
                 jQuery("#loginReset").trigger('"click')
@@ -96,6 +93,7 @@ export default function Login() {
         </form>
       </div>
       <div className="modal-footer">
+        <a href="#/resetpassword" onClick={closeLogin} data-bs-dismiss="modal" className="w-100 small">Forgot password?</a>
         <div className="w-100 text-danger">{message}</div>
       </div>
     </div>
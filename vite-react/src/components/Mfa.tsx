@@ -15,16 +15,19 @@ export default function Mfa() {
   const submitMfa = (event: any) => {
     event.preventDefault();
 
-    const userid = sessionStorage.getItem('USERID');
-    const token = sessionStorage.getItem('TOKEN');
+    const challenge = sessionStorage.getItem('CHALLENGE');
     setMessage('please wait..');
-    const jsonData =JSON.stringify({otp: otp });
-    api.patch(`api/mfa/verifytotp/${userid}`, jsonData, {headers: {
-      Authorization: `Bearer ${token}`
-  }})
+    const jsonData =JSON.stringify({challenge_token: challenge, otp: otp });
+    api.post("auth/mfa/verify", jsonData)
     .then((res: any) => {
           setMessage(res.data.message);
+            sessionStorage.removeItem('CHALLENGE');
+            sessionStorage.setItem('USERID', res.data.id);
             sessionStorage.setItem("USERNAME", res.data.username);
+            sessionStorage.setItem('TOKEN', res.data.token);
+            sessionStorage.setItem('REFRESHTOKEN', res.data.refresh_token);
+            sessionStorage.setItem('ROLE', res.data.roles);
+            sessionStorage.setItem('USERPIC', `http://localhost:5000/users/${res.data.id}/picture?v=${encodeURIComponent(res.data.userpicture)}`);
             window.setTimeout(() => {
               setMessage('');
               jQuery("#mfaReset").trigger('click');
@@ -47,6 +50,7 @@ export default function Mfa() {
     event.preventDefault();
     setMessage('');
     setOtp('');
+    sessionStorage.removeItem('CHALLENGE');
     sessionStorage.removeItem('USERID');
     sessionStorage.removeItem('USERNAME');
     sessionStorage.removeItem('USERPIC');
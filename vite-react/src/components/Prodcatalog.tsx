@@ -15,20 +15,29 @@ const toDecimal = (number: any) => {
   });
   return formatter.format(number);
 };
+// products per page
+const pageSize = 5;
+
 export default function Prodcatalog() {
     let [page, setPage] = useState<number>(1);
     let [prods, setProds] = useState<[]>([]);
     let [totpage, setTotpage] = useState<number>(0);
     let [totalrecords, setTotalrecords] = useState<number>(0);
+    // cursors[i] fetches page i + 1, the first page needs none
+    let [cursors, setCursors] = useState<string[]>(['']);
+    let [nextCursor, setNextCursor] = useState<string | null>(null);
     const [message, setMessage] = useState('');
 
-    const fetchCatalog = async (pg: any) => {
-      api.get(`/products/list/${pg}`)
+    const fetchCatalog = async (pg: number, cursor: string) => {
+      api.get('/products/list', { params: { size: pageSize, cursor: cursor || undefined } })
       .then((res: any) => {
+        setMessage('');
         setProds(res.data.products);
-        setTotpage(res.data.totpage);
-        setTotalrecords(res.data.totalrecords);
-        setPage(res.data.page);
+        setTotalrecords(res.data.total);
+        setTotpage(Math.ceil(res.data.total / pageSize));
+        setNextCursor(res.data.next_cursor);
+        setCursors(prev => [...prev.slice(0, pg - 1), cursor]);
+        setPage(pg);
       }, (error: any) => {
               setMessage(error.response.data.message);
               return;
@@ -36,40 +45,28 @@ export default function Prodcatalog() {
     }
 
     useEffect(() => {
-      fetchCatalog(page)
-    },[page]);
+      fetchCatalog(1, '')
+    },[]);
 
     const firstPage = (event: any) => {
         event.preventDefault();    
-        page = 1;
-        return fetchCatalog(page);
+        return fetchCatalog(1, '');
       }
     
       const nextPage = (event: any) => {
         event.preventDefault();    
-        if (page == totpage) {
-            setPage(totpage);
+        if (nextCursor === null) {
             return;
-        } else {
-          page++;
-          return fetchCatalog(page);  
         }
+        return fetchCatalog(page + 1, nextCursor);  
       }
     
       const prevPage = (event: any) => {
         event.preventDefault();    
         if (page === 1) {
-          setPage(1);
           return;
           }
-          page--;
-          return fetchCatalog(page);
-      }
-    
-      const lastPage = (event: any) => {
-        event.preventDefault();
-        page = totpage;
-        return fetchCatalog(page);
+          return fetchCatalog(page - 1, cursors[page - 2]);
       }
 
     return(
@@ -81,7 +78,7 @@ export default function Prodcatalog() {
                     return (
                       <div className='col-md-4'>
                       <div key={item['id']} className="card mx-3 mt-3">
-                          <img src={`http://localhost:5000/assets/products/${item['productpicture']}`} className="card-img-top product-size" alt=""/>
+                          <img src={item['images'] ? `http://localhost:5000/media/${item['images']['medium']}` : `http://localhost:5000/assets/products/${item['productpicture']}`} className="card-img-top product-size" alt=""/>
                           <div className="card-body">
                             <h5 className="card-title">Descriptions</h5>
                             <p className="card-text desc-h">{item['descriptions']}</p>
@@ -100,10 +97,9 @@ export default function Prodcatalog() {
         <div className='container'>
         <nav aria-label="Page navigation example">
         <ul className="pagination sm">
-          <li className="page-item"><Link onClick={lastPage} className="page-link sm" to="/#">Last</Link></li>
+          <li className="page-item"><Link onClick={firstPage} className="page-link sm" to="/#">First</Link></li>
           <li className="page-item"><Link onClick={prevPage} className="page-link sm" to="/#">Previous</Link></li>
           <li className="page-item"><Link onClick={nextPage} className="page-link sm" to="/#">Next</Link></li>
-          <li className="page-item"><Link onClick={firstPage} className="page-link sm" to="/#">First</Link></li>
           <li className="page-item page-link text-danger sm">Page&nbsp;{page} of&nbsp;{totpage}</li>
         </ul>
       </nav>
@@ -10,7 +10,7 @@ const api = axios.create({
 });
 
 interface Product {
-  id: number;
+  id: string;
   category: string;
   descriptions: string;
   qty: number;
@@ -25,11 +25,13 @@ interface Product {
 // Define the shape of your API response
 interface ApiResponse {
   products: Product[];
-  totpage: number;
-  totalrecords: number;
-  page: number;
+  total: number;
+  next_cursor: string | null;
 }
 
+// products per page
+const pageSize = 5;
+
 export default function Prodlist() {
   const toDecimal = (number: number) => {
     return new Intl.NumberFormat('en-US', {
@@ -41,53 +43,55 @@ export default function Prodlist() {
   const [page, setPage] = useState<number>(1);
   const [totpage, setTotpage] = useState<number>(0);
   const [totalrecs, setTotalrecs] = useState<number>(0);
+  // cursors[i] fetches page i + 1, the first page needs none
+  const [cursors, setCursors] = useState<string[]>(['']);
+  const [nextCursor, setNextCursor] = useState<string | null>(null);
   
   // Fix: Initialize as Product array instead of empty tuple []
   const [products, setProducts] = useState<Product[]>([]);
 
-  const fetchProducts = async (pg: number) => {
+  const fetchProducts = async (pg: number, cursor: string) => {
     try {
       // Fix: Cast the response to your expected API structure
-      const res = await api.get<ApiResponse>(`/products/list/${pg}`);
+      const res = await api.get<ApiResponse>('/products/list', {
+        params: { size: pageSize, cursor: cursor || undefined }
+      });
       
       // Update states based on response structure
       setProducts(res.data.products); 
-      setTotpage(res.data.totpage);
-      setTotalrecs(res.data.totalrecords);
-      setPage(res.data.page);
+      setTotalrecs(res.data.total);
+      setTotpage(Math.ceil(res.data.total / pageSize));
+      setNextCursor(res.data.next_cursor);
+      setCursors(prev => [...prev.slice(0, pg - 1), cursor]);
+      setPage(pg);
     } catch (error: any) {
       console.error(error.response?.data?.message || "An error occurred");
     }
   };
 
   useEffect(() => {
-    fetchProducts(page);
-  }, [page]);
+    fetchProducts(1, '');
+  }, []);
 
   const firstPage = (event: React.MouseEvent) => {
     event.preventDefault();
-    setPage(1);
+    fetchProducts(1, '');
   };
 
   const nextPage = (event: React.MouseEvent) => {
     event.preventDefault();
-    if (page < totpage) {
-      setPage(prev => prev + 1);
+    if (nextCursor !== null) {
+      fetchProducts(page + 1, nextCursor);
     }
   };
 
   const prevPage = (event: React.MouseEvent) => {
     event.preventDefault();
     if (page > 1) {
-      setPage(prev => prev - 1);
+      fetchProducts(page - 1, cursors[page - 2]);
     }
   };
 
-  const lastPage = (event: React.MouseEvent) => {
-    event.preventDefault();
-    setPage(totpage);
-  };
-
   return (
     <div className="container">
       <h1 className='text-warning embossed mt-3'>Products List</h1>
@@ -102,9 +106,9 @@ export default function Prodlist() {
           </tr>
         </thead>
         <tbody>
-          {products.map((item) => (
+          {products.map((item, index) => (
             <tr key={item.id}>
-              <td>{item.id}</td>
+              <td>{(page - 1) * pageSize + index + 1}</td>
               <td>{item.descriptions}</td>
               <td>{item.qty}</td>
               <td>{item.unit}</td>
@@ -119,7 +123,6 @@ export default function Prodlist() {
           <li className="page-item"><a onClick={firstPage} className="page-link sm" href="#">First</a></li>
           <li className="page-item"><a onClick={prevPage} className="page-link sm" href="#">Previous</a></li>
           <li className="page-item"><a onClick={nextPage} className="page-link sm" href="#">Next</a></li>
-          <li className="page-item"><a onClick={lastPage} className="page-link sm" href="#">Last</a></li>
           <li className="page-item page-link text-danger sm">Page {page} of {totpage}</li>
         </ul>
       </nav>
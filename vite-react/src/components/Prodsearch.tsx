@@ -1,5 +1,5 @@
 import axios from 'axios';
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 
 const api = axios.create({
   baseURL: "http://localhost:5000",
@@ -16,6 +16,9 @@ const toDecimal = (number: any) => {
 };
 
 
+// products per page
+const pageSize = 5;
+
 export default function Prodsearch() {
   let [message, setMessage] = useState('');
   let [prodsearch, setProdsearch] = useState<[]>([]);
@@ -23,42 +26,52 @@ export default function Prodsearch() {
   let [totpage, setTotpage] = useState<number>(0);
   let [totalrecords, setTotalrecords] = useState<number>(0);
   let [searchkey, setSearchkey] = useState<string>('');
-
-  const getProdsearch = async (event: any) => {
-      event.preventDefault();
-      setMessage("please wait .");
-      await api.get(`/products/search/${page}/${searchkey}`)
+  // the search the pages belong to, cursors do not carry over to another one
+  let [query, setQuery] = useState<string>('');
+  // cursors[i] fetches page i + 1, the first page needs none
+  let [cursors, setCursors] = useState<string[]>(['']);
+  let [nextCursor, setNextCursor] = useState<string | null>(null);
+  let [suggestions, setSuggestions] = useState<[]>([]);
+
+  useEffect(() => {
+    if (searchkey.trim().length < 2) {
+      setSuggestions([]);
+      return;
+    }
+    // wait for a pause in typing before asking for suggestions
+    const timer = setTimeout(() => {
+      api.get('/products/suggest', { params: { q: searchkey } })
       .then((res: any) => {
-          setProdsearch(res.data.products);
-          setTotpage(res.data.totpage);
-          setTotalrecords(res.data.totalrecords)
-          setPage(res.data.page);
-          setTimeout(() => {
-            setMessage('');
-          }, 1000);
-
-      }, (error: any) => {     
-        setMessage(error.response.data.message);
-        setProdsearch([]);
-        setTimeout(() => {
-            setMessage('');
-        }, 3000);
-          return;
-      });  
-  }
-
-  const getProdPage = async (page: number) => {
+          setSuggestions(res.data.suggestions);
+      }, () => {
+          setSuggestions([]);
+      });
+    }, 250);
+    return () => clearTimeout(timer);
+  }, [searchkey]);
+
+  const getProdPage = async (q: string, pg: number, cursor: string) => {
     setMessage("please wait .");
-    await api.get(`products/search/${page}/${searchkey}`)
+    await api.get('/products/search', { params: { q: q, size: pageSize, cursor: cursor || undefined } })
     .then((res: any) => {
         setProdsearch(res.data.products);
-        setTotpage(res.data.totpage);
-        setPage(res.data.page);
+        setTotalrecords(res.data.total);
+        setTotpage(Math.ceil(res.data.total / pageSize));
+        setNextCursor(res.data.next_cursor);
+        setCursors(prev => [...prev.slice(0, pg - 1), cursor]);
+        setQuery(q);
+        setPage(pg);
+        if (res.data.total === 0) {
+          setMessage('products not found.');
+          setTimeout(() => {
+            setMessage('');
+          }, 3000);
+          return;
+        }
         setTimeout(() => {
           setMessage('');
         }, 1000);
 
-
     }, (error: any) => {        
       setMessage(error.response.data.message);
       setProdsearch([]);
@@ -69,39 +82,31 @@ export default function Prodsearch() {
     });  
 }
 
+  const getProdsearch = async (event: any) => {
+      event.preventDefault();
+      return getProdPage(searchkey, 1, '');
+  }
+
   const firstPage = (event: any) => {
     event.preventDefault();    
-    page = 1;
-    return getProdPage(page);
+    return getProdPage(query, 1, '');
   }
 
   const nextPage = (event: any) => {
     event.preventDefault();    
-    if (page == totpage) {
-        page = 0;
-        setPage(totpage);
+    if (nextCursor === null) {
         return;
-    } else {
-      page++;
-      return getProdPage(page);  
     }
+    return getProdPage(query, page + 1, nextCursor);  
   }
 
   const prevPage = (event: any) => {
     event.preventDefault();    
     if (page === 1) {
-      setPage(1);
       return;
       }
-      page--;
-      return getProdPage(page);
+      return getProdPage(query, page - 1, cursors[page - 2]);
   }
-
-  const lastPage = (event: any) => {
-    event.preventDefault();
-    page = totpage;
-    return getProdPage(page);
-  }  
    
 return (
   <div className="container mb-10">
@@ -109,7 +114,12 @@ return (
 
       <form className="row g-3" onSubmit={getProdsearch} autoComplete='off'>
           <div className="col-auto">
-            <input type="text" required className="form-control-sm" value={searchkey} onChange={e => setSearchkey(e.target.value)} placeholder="enter Product keyword"/>
+            <input type="text" required className="form-control-sm" value={searchkey} onChange={e => setSearchkey(e.target.value)} placeholder="enter Product keyword" list="prodsuggest"/>
+            <datalist id="prodsuggest">
+              {suggestions.map((item) => (
+                <option key={item['id']} value={item['descriptions']}>{item['category']}</option>
+              ))}
+            </datalist>
             <div className='searcMsg text-warning'>{message}</div>
           </div>
           <div className="col-auto">
@@ -123,7 +133,7 @@ return (
               return (
               <div className='col-md-4'>
               <div key={item['id']} className="card mx-3 mt-3">
-                  <img src={`http://localhost:5000/assets/products/${item['productpicture']}`} className="card-img-top product-size" alt=""/>
+                  <img src={item['images'] ? `http://localhost:5000/media/${item['images']['medium']}` : `http://localhost:5000/assets/products/${item['productpicture']}`} className="card-img-top product-size" alt=""/>
                   <div className="card-body">
                     <h5 className="card-title">Descriptions</h5>
                     <p className="card-text desc-h">{item['descriptions']}</p>
@@ -142,10 +152,9 @@ return (
           <>
           <nav aria-label="Page navigation example">
             <ul className="pagination sm mt-3">
-              <li className="page-item"><a onClick={lastPage} className="page-link sm" href="/#">Last</a></li>
+              <li className="page-item"><a onClick={firstPage} className="page-link sm" href="/#">First</a></li>
               <li className="page-item"><a onClick={prevPage} className="page-link sm" href="/#">Previous</a></li>
               <li className="page-item"><a onClick={nextPage} className="page-link sm" href="/#">Next</a></li>
-              <li className="page-item"><a onClick={firstPage} className="page-link sm" href="/#">First</a></li>
               <li className="page-item page-link text-danger sm">Page&nbsp;{page} of&nbsp;{totpage}</li>
             </ul>
           </nav>
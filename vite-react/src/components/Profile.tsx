@@ -22,6 +22,7 @@ export default function Profile() {
     const [mobile, setMobile] = useState<string>('');
     const [userpicture, setUserpicture] = useState<string>('');
     const [token, setToken] = useState<string>('');
+    const [curpassword, setCurPassword ] = useState<string>('');
     const [newpassword, setNewPassword ] = useState<string>('');
     const [confnewpassword, setConfNewPassword ] = useState<string>('');    
     const [profileMsg, setProfileMsg] = useState<string>('');
@@ -40,18 +41,10 @@ export default function Profile() {
             setFname(res.data.firstname); 
             setEmail(res.data.email);
             setMobile(res.data.mobile);
-            const userpic: string = `http://localhost:5000/assets/users/${res.data.userpicture}`;
+            const userpic: string = `http://localhost:5000/users/${id}/picture?v=${encodeURIComponent(res.data.userpicture)}`;
             setUserpicture(userpic);
-            setQrcodeurl(res.data.qrcodeurl);     
-
-            if (res.data.qrcodeurl !== null) {
-                const qrcode: string = 'data:image/png;base64,' + res.data.qrcodeurl
-                setQrcodeurl(qrcode);
-                // jQuery('#googleAuth').attr('src', qrcode);
-
-            } else {
-                setQrcodeurl('http://127.0.0.1:5000/assets/images/qrcode.png');
-            }
+            // The QR code holds the TOTP secret, so it is only shown while enrolling
+            setQrcodeurl('http://127.0.0.1:5000/assets/images/qrcode.png');
 
         }, (error: any) => {
             if (error.response) {
@@ -124,7 +117,7 @@ export default function Profile() {
             .then((res: any) => {
                 setProfileMsg(res.data.message);
                 setTimeout(() => {
-                    const userpic: string = `http://localhost:5000/assets/users/${res.data.userpicpicture}`;
+                    const userpic: string = `http://localhost:5000/users/${userid}/picture?v=${encodeURIComponent(res.data.userpic)}`;
                     sessionStorage.removeItem('USERPIC');
                     sessionStorage.setItem('USERPIC',userpic)
                     
@@ -183,6 +176,7 @@ export default function Profile() {
                 let qrcode: any = 'data:image/png;base64,' + res.data.qrcodeurl
                 setQrcodeurl(qrcode);
                 setProfileMsg('');
+                setTimeout(confirmMFA, 500);
             },3000);
         }, (error: any) => {
             if (error.response) {
@@ -197,8 +191,42 @@ export default function Profile() {
         });
     }
 
+    const confirmMFA = () => {
+        const otp = window.prompt('Scan the QR code, then enter the 6-digit OTP code from your Authenticator.');
+        if (!otp) {
+            return;
+        }
+        const jsonData =JSON.stringify({otp: otp });
+        mfaapi.patch(`api/mfa/verifytotp/${userid}`, jsonData, {headers: {
+            Authorization: `Bearer ${token}`
+        }})
+        .then((res: any) => {
+            setProfileMsg(res.data.message);
+            window.alert('Your recovery codes, each can be used once instead of an OTP code:\n\n' + res.data.recoverycodes.join('\n'));
+            setTimeout(() => {
+                setProfileMsg('');
+            },3000);
+        }, (error: any) => {
+            if (error.response) {
+                setProfileMsg(error.response.data.message);
+            } else {
+                setProfileMsg(error.message);
+            }
+            setTimeout(() => {
+                setProfileMsg('');
+            },3000);
+            return;
+        });
+    }
+
     const disableMFA = () => {
-        const jsonData =JSON.stringify({TwoFactorEnabled: false });      
+        const proof = window.prompt('Enter the 6-digit OTP code from your Authenticator, or your password, to disable MFA.');
+        if (!proof) {
+            return;
+        }
+        const jsonData = /^[0-9]{6}$/.test(proof)
+            ? JSON.stringify({TwoFactorEnabled: false, otp: proof })
+            : JSON.stringify({TwoFactorEnabled: false, password: proof });
         mfaapi.patch(`api/mfa/activate/${userid}`, jsonData, {headers: {
             Authorization: `Bearer ${token}`
         }})
@@ -223,6 +251,13 @@ export default function Profile() {
 
     const changePassword = (event: any) => {
         event.preventDefault();
+        if (curpassword === '') {
+            setProfileMsg("Please enter your current Password.");
+            setTimeout(() => {
+                setProfileMsg('');
+            },3000);
+            return;
+        }
         if (newpassword === '') {
             setProfileMsg("Please enter new Pasword.");
             setTimeout(() => {
@@ -246,7 +281,7 @@ export default function Profile() {
             return;            
         }
 
-        const jsonData =JSON.stringify({password: newpassword });
+        const jsonData =JSON.stringify({current_password: curpassword, password: newpassword });
         mfaapi.patch(`api/changepassword/${userid}`, jsonData, {headers: {
             Authorization: `Bearer ${token}`
         }})
@@ -349,7 +384,8 @@ export default function Profile() {
                         </div>
                         { showpwd === true ? (
                             <>
-                              <input className="form-control text-dark border-primary mt-2" type="password" id="newPassword" value={newpassword} onChange={e => setNewPassword(e.target.value)} autoComplete="off" placeholder='enter new Password'/>
+                              <input className="form-control text-dark border-primary mt-2" type="password" id="curPassword" value={curpassword} onChange={e => setCurPassword(e.target.value)} autoComplete="current-password" placeholder='enter current Password'/>
+                              <input className="form-control text-dark border-primary mt-1" type="password" id="newPassword" value={newpassword} onChange={e => setNewPassword(e.target.value)} autoComplete="off" placeholder='enter new Password'/>
                               <input className="form-control text-dark border-primary mt-1" type="password" id="confNewPassword" value={confnewpassword} onChange={e => setConfNewPassword(e.target.value)} autoComplete="off" placeholder='confirm new Password'/>
                               <button onClick={changePassword} className='btn btn-primary mt-2' type="button">change password</button>
                             </>
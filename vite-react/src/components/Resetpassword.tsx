@@ -0,0 +1,55 @@
+import { useState } from "react"
+import { useSearchParams } from "react-router-dom";
+import axios from 'axios';
+
+const api = axios.create({
+   baseURL: "http://localhost:5000",
+   headers: {'Accept': 'application/json',
+             'Content-Type': 'application/json'}
+})
+
+// Without a token the page asks for the email address to send the reset link
+// to, with one it asks for the new password.
+export default function Resetpassword() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [email, setEmail] = useState<string>('');
+  const [password, setPassword] = useState<string>('');
+  const [message, setMessage] = useState<string>('');
+
+  const submitReset = (event: any) => {
+    event.preventDefault();
+    setMessage('please wait..');
+    const request = token
+      ? api.post("auth/password/reset", JSON.stringify({ token: token, password: password }))
+      : api.post("auth/password/forgot", JSON.stringify({ email: email }));
+    request.then((res: any) => {
+        setMessage(res.data.message);
+        setEmail('');
+        setPassword('');
+      }, (error: any) => {
+        if (error.response) {
+          setMessage(error.response.data.message);
+        } else {
+          setMessage(error.message);
+        }
+    });
+  }
+
+  return (
+    <div className="container mt-5">
+      <h2 className="embossed">{token ? 'Reset Password' : 'Forgot Password'}</h2>
+      <form onSubmit={submitReset} autoComplete="off" className="col-md-4">
+        <div className="mb-3">
+          {token
+            ? <input type="password" required value={password} onChange={e => setPassword(e.target.value)} className="form-control border-secondary border-emboss" placeholder="enter new Password"/>
+            : <input type="email" required value={email} onChange={e => setEmail(e.target.value)} className="form-control border-secondary border-emboss" placeholder="enter Email Address"/>}
+        </div>
+        <div className="mb-3">
+          <button type="submit" className="btn btn-violet text-white">submit</button>
+        </div>
+      </form>
+      <div className="w-100 text-danger">{message}</div>
+    </div>
+  )
+}
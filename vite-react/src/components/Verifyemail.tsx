@@ -0,0 +1,35 @@
+import { useEffect, useState } from "react"
+import { useSearchParams } from "react-router-dom";
+import axios from 'axios';
+
+const api = axios.create({
+   baseURL: "http://localhost:5000",
+   headers: {'Accept': 'application/json',
+             'Content-Type': 'application/json'}
+})
+
+export default function Verifyemail() {
+  const [searchParams] = useSearchParams();
+  const [message, setMessage] = useState<string>('please wait..');
+
+  useEffect(() => {
+    const jsonData = JSON.stringify({ token: searchParams.get('token') });
+    api.post("auth/verify", jsonData)
+    .then((res: any) => {
+        setMessage(res.data.message);
+      }, (error: any) => {
+        if (error.response) {
+          setMessage(error.response.data.message);
+        } else {
+          setMessage(error.message);
+        }
+    });
+  }, [searchParams]);
+
+  return (
+    <div className="container mt-5">
+      <h2 className="embossed">Email Verification</h2>
+      <div className="w-100 text-danger">{message}</div>
+    </div>
+  )
+}